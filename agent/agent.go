@@ -0,0 +1,252 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/eurozulu/pempal/logging"
+	"github.com/eurozulu/pempal/model"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Agent holds decrypted private keys in memory and signs with them on behalf of its clients.
+// Keys are only held until their timeout expires and are never written to disk.
+type Agent struct {
+	keys map[model.Fingerprint]*heldKey
+	lock sync.Mutex
+}
+
+type heldKey struct {
+	key     *model.PrivateKey
+	info    *KeyInfo
+	expires *time.Timer
+}
+
+// Serve listens on the unix socket at the given path, serving requests until the context is cancelled
+// or a client requests the agent to stop.
+// The socket directory must only be accessible to the current user and the socket is created only accessible to them.
+func (a *Agent) Serve(ctx context.Context, socketPath string) error {
+	dir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return err
+	}
+	if c, err := net.Dial("unix", socketPath); err == nil {
+		c.Close()
+		return fmt.Errorf("agent already running on %s", socketPath)
+	}
+	_ = os.Remove(socketPath)
+
+	l, err := listenSocket(socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	ctx, cnl := context.WithCancel(ctx)
+	defer cnl()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	logging.Info("agent listening on %s", socketPath)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				a.RemoveAll()
+				return nil
+			}
+			return err
+		}
+		go a.serveConn(conn, cnl)
+	}
+}
+
+func (a *Agent) serveConn(conn net.Conn, stop context.CancelFunc) {
+	defer conn.Close()
+	scn := bufio.NewScanner(conn)
+	scn.Buffer(nil, 1024*1024)
+	enc := json.NewEncoder(conn)
+	for scn.Scan() {
+		var req request
+		var resp *response
+		if err := json.Unmarshal(scn.Bytes(), &req); err != nil {
+			resp = &response{Error: fmt.Sprintf("invalid request  %v", err)}
+		} else {
+			resp = a.handle(&req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			logging.Warning("agent failed to respond  %v", err)
+			return
+		}
+		if req.Op == opStop {
+			stop()
+			return
+		}
+	}
+}
+
+func (a *Agent) handle(req *request) *response {
+	switch req.Op {
+	case opAdd:
+		info, err := a.Add(req.Key, req.IDs, req.Timeout)
+		if err != nil {
+			return &response{Error: err.Error()}
+		}
+		return &response{Keys: []*KeyInfo{info}}
+	case opList:
+		return &response{Keys: a.List()}
+	case opRemove:
+		if req.Fingerprint == "" {
+			a.RemoveAll()
+			return &response{}
+		}
+		if err := a.Remove(req.Fingerprint); err != nil {
+			return &response{Error: err.Error()}
+		}
+		return &response{}
+	case opSign:
+		sig, err := a.sign(req)
+		if err != nil {
+			return &response{Error: err.Error()}
+		}
+		return &response{Signature: sig}
+	case opStop:
+		return &response{}
+	default:
+		return &response{Error: fmt.Sprintf("unknown agent operation %q", req.Op)}
+	}
+}
+
+// Add adds the given PKCS#8 der key to the agent, holding it for the given timeout.
+// Adding a key already held replaces its timeout and ids.
+func (a *Agent) Add(der []byte, ids []string, timeout time.Duration) (*KeyInfo, error) {
+	prk := &model.PrivateKey{}
+	if err := prk.UnmarshalBinary(der); err != nil {
+		return nil, err
+	}
+	puk, err := prk.Public().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	fp := prk.Fingerprint()
+	hk := &heldKey{
+		key: prk,
+		info: &KeyInfo{
+			Fingerprint: fp.String(),
+			PublicKey:   puk,
+			IDs:         ids,
+		},
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.keys == nil {
+		a.keys = map[model.Fingerprint]*heldKey{}
+	}
+	if existing, ok := a.keys[fp]; ok && existing.expires != nil {
+		existing.expires.Stop()
+	}
+	a.keys[fp] = hk
+	if timeout > 0 {
+		hk.info.Expires = time.Now().Add(timeout)
+		hk.expires = time.AfterFunc(timeout, func() {
+			a.expire(fp, hk)
+		})
+	}
+	return hk.info, nil
+}
+
+// expire removes the held key, unless it has since been replaced by adding the key again.
+// A replaced keys timer may fire before it is stopped, so must not remove the key replacing it.
+func (a *Agent) expire(fp model.Fingerprint, hk *heldKey) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.keys[fp] != hk {
+		return
+	}
+	logging.Info("agent key %s expired", fp)
+	delete(a.keys, fp)
+}
+
+// List lists the keys held by the agent
+func (a *Agent) List() []*KeyInfo {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	infos := make([]*KeyInfo, 0, len(a.keys))
+	for _, hk := range a.keys {
+		infos = append(infos, hk.info)
+	}
+	slices.SortFunc(infos, func(a, b *KeyInfo) int {
+		return a.Expires.Compare(b.Expires)
+	})
+	return infos
+}
+
+// Remove removes the key with the given fingerprint, or id, from the agent.
+func (a *Agent) Remove(fingerprint string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	fp, hk := a.find(fingerprint)
+	if hk == nil {
+		return fmt.Errorf("key %s is not held by the agent", fingerprint)
+	}
+	if hk.expires != nil {
+		hk.expires.Stop()
+	}
+	delete(a.keys, fp)
+	return nil
+}
+
+// RemoveAll removes all the keys from the agent
+func (a *Agent) RemoveAll() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, hk := range a.keys {
+		if hk.expires != nil {
+			hk.expires.Stop()
+		}
+	}
+	a.keys = nil
+}
+
+// sign signs the digest in the request, using the key of the request fingerprint.
+func (a *Agent) sign(req *request) ([]byte, error) {
+	a.lock.Lock()
+	_, hk := a.find(req.Fingerprint)
+	a.lock.Unlock()
+	if hk == nil {
+		return nil, fmt.Errorf("key %s is not held by the agent", req.Fingerprint)
+	}
+	var opts crypto.SignerOpts = crypto.Hash(req.Hash)
+	if req.PSS {
+		opts = &rsa.PSSOptions{SaltLength: req.PSSSaltLength, Hash: crypto.Hash(req.Hash)}
+	}
+	if len(req.Digest) == 0 {
+		return nil, errors.New("no digest to sign")
+	}
+	return hk.key.Signer().Sign(rand.Reader, req.Digest, opts)
+}
+
+// find finds the held key by its fingerprint, public key fingerprint or one of its ids.
+// must be called with the lock held.
+func (a *Agent) find(id string) (model.Fingerprint, *heldKey) {
+	for fp, hk := range a.keys {
+		if fp.Equals(id) || hk.key.Public().Fingerprint().Equals(id) || slices.Contains(hk.info.IDs, id) {
+			return fp, hk
+		}
+	}
+	return model.Fingerprint{}, nil
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestAgentKeyExpiry(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Agent{}
+	info, err := a.Add(der, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp, old := a.find(info.Fingerprint)
+
+	// the replaced keys timer firing, before Add stops it, leaves the replacing key held
+	if _, err := a.Add(der, []string{"me"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	a.expire(fp, old)
+	if keys := a.List(); len(keys) != 1 || len(keys[0].IDs) != 1 {
+		t.Fatalf("expected the replacing key to be held, found %d keys", len(keys))
+	}
+
+	if _, err := a.Add(der, nil, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; len(a.List()) > 0; i++ {
+		if i > 50 {
+			t.Fatal("expected key to expire")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Client sends requests to the agent listening on its socket path
+type Client string
+
+// DefaultClient returns the client of the agent at the default socket path, or an empty client if no agent is running.
+func DefaultClient() Client {
+	c := Client(SocketPath())
+	if !c.IsRunning() {
+		return ""
+	}
+	return c
+}
+
+// IsRunning checks if an agent is listening on the clients socket
+func (c Client) IsRunning() bool {
+	if c == "" {
+		return false
+	}
+	if _, err := os.Stat(string(c)); err != nil {
+		return false
+	}
+	conn, err := net.DialTimeout("unix", string(c), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Add adds the given key to the agent, holding it for the timeout.
+// ids are additional identities the key may be found by.
+func (c Client) Add(prk *model.PrivateKey, ids []string, timeout time.Duration) (*KeyInfo, error) {
+	der, err := prk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.send(&request{Op: opAdd, Key: der, IDs: ids, Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Keys) == 0 {
+		return nil, errors.New("agent did not return the added key")
+	}
+	return resp.Keys[0], nil
+}
+
+// List lists the keys held by the agent
+func (c Client) List() ([]*KeyInfo, error) {
+	resp, err := c.send(&request{Op: opList})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// Find finds the key held by the agent with the given fingerprint, public key fingerprint or id.
+// Returns nil if the key is not held.
+func (c Client) Find(id string) (*model.PrivateKey, error) {
+	infos, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		puk := &model.PublicKey{}
+		if err := puk.UnmarshalBinary(info.PublicKey); err != nil {
+			return nil, err
+		}
+		if info.Fingerprint == id || puk.Fingerprint().Equals(id) || slices.Contains(info.IDs, id) {
+			return model.NewPrivateKey(&agentKey{client: c, info: info, public: puk.Public()}), nil
+		}
+	}
+	return nil, nil
+}
+
+// Remove removes the key with the given fingerprint from the agent.  An empty fingerprint removes all keys.
+func (c Client) Remove(fingerprint string) error {
+	_, err := c.send(&request{Op: opRemove, Fingerprint: fingerprint})
+	return err
+}
+
+// Stop stops the agent
+func (c Client) Stop() error {
+	_, err := c.send(&request{Op: opStop})
+	return err
+}
+
+// send sends the request to the agent, once the socket directory is checked to be only accessible to the current user.
+func (c Client) send(req *request) (*response, error) {
+	if err := checkSocketDir(filepath.Dir(string(c))); err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", string(c), time.Second*5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to agent  %v", err)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return nil, fmt.Errorf("failed to read agent response  %v", err)
+	}
+	var resp response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("invalid agent response  %v", err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// agentKey is a private key held by the agent.  Signing is proxied to the agent.
+type agentKey struct {
+	client Client
+	info   *KeyInfo
+	public crypto.PublicKey
+}
+
+func (k agentKey) Public() crypto.PublicKey {
+	return k.public
+}
+
+func (k agentKey) Fingerprint() model.Fingerprint {
+	fp, _ := model.ParseFingerPrint(k.info.Fingerprint)
+	return fp
+}
+
+func (k agentKey) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	req := &request{
+		Op:          opSign,
+		Fingerprint: k.info.Fingerprint,
+		Digest:      digest,
+		Hash:        uint(opts.HashFunc()),
+	}
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		req.PSS = true
+		req.PSSSaltLength = pss.SaltLength
+	}
+	resp, err := k.client.send(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
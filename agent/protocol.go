@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ENV_PP_AGENT_SOCK is the environment variable holding the path of the agent socket
+const ENV_PP_AGENT_SOCK = "PP_AGENT_SOCK"
+
+// agent operations
+const (
+	opAdd    = "add"
+	opList   = "list"
+	opRemove = "remove"
+	opSign   = "sign"
+	opStop   = "stop"
+)
+
+// request is a single request sent to the agent, as a json line.
+type request struct {
+	Op string `json:"op"`
+
+	// Key is the PKCS#8 der of a key being added
+	Key []byte `json:"key,omitempty"`
+	// IDs are additional identities of a key being added, such as the fingerprint of its encrypted pem
+	IDs []string `json:"ids,omitempty"`
+	// Timeout is the duration a key being added is held for.  Zero holds the key until the agent stops
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// Fingerprint identifies the key to sign with or remove
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Digest is the digest to sign, hashed with Hash
+	Digest        []byte `json:"digest,omitempty"`
+	Hash          uint   `json:"hash,omitempty"`
+	PSS           bool   `json:"pss,omitempty"`
+	PSSSaltLength int    `json:"pss-salt-length,omitempty"`
+}
+
+// response is the agents response to a single request
+type response struct {
+	Error     string     `json:"error,omitempty"`
+	Keys      []*KeyInfo `json:"keys,omitempty"`
+	Signature []byte     `json:"signature,omitempty"`
+}
+
+// KeyInfo describes a key held by the agent
+type KeyInfo struct {
+	// Fingerprint is the fingerprint of the private key
+	Fingerprint string `json:"fingerprint"`
+	// PublicKey is the PKIX der of the keys public key
+	PublicKey []byte    `json:"public-key"`
+	IDs       []string  `json:"ids,omitempty"`
+	Expires   time.Time `json:"expires,omitempty"`
+}
+
+// SocketPath returns the path of the agent socket.
+// The PP_AGENT_SOCK environment variable is used if set, otherwise a socket in the users XDG_RUNTIME_DIR
+// or, when that is not set, a user specific temp directory.
+func SocketPath() string {
+	if s, ok := os.LookupEnv(ENV_PP_AGENT_SOCK); ok && s != "" {
+		return s
+	}
+	if s, ok := os.LookupEnv("XDG_RUNTIME_DIR"); ok && s != "" {
+		return filepath.Join(s, "pempal", "agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("pempal-%d", os.Getuid()), "agent.sock")
+}
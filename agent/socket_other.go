@@ -0,0 +1,18 @@
+//go:build !unix
+
+package agent
+
+import (
+	"errors"
+	"net"
+)
+
+var errNotSupported = errors.New("the key agent is only supported on unix systems")
+
+func checkSocketDir(dir string) error {
+	return errNotSupported
+}
+
+func listenSocket(path string) (net.Listener, error) {
+	return nil, errNotSupported
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// checkSocketDir checks the directory of the socket is a directory, not a link, owned by and only accessible to the current user.
+func checkSocketDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("agent socket directory %s is not a directory", dir)
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("agent socket directory %s is not owned by the current user", dir)
+	}
+	if fi.Mode().Perm() != 0700 {
+		return fmt.Errorf("agent socket directory %s must only be accessible to its owner, with a mode of 0700, found %#o", dir, fi.Mode().Perm())
+	}
+	return nil
+}
+
+// listenSocket listens on the unix socket at the given path, creating the socket only accessible to the current user.
+func listenSocket(path string) (net.Listener, error) {
+	mask := syscall.Umask(0177)
+	defer syscall.Umask(mask)
+	return net.Listen("unix", path)
+}
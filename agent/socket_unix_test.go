@@ -0,0 +1,75 @@
+//go:build unix
+
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckSocketDir(t *testing.T) {
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private")
+	if err := os.Mkdir(private, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSocketDir(private); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	shared := filepath.Join(dir, "shared")
+	if err := os.Mkdir(shared, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(shared, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSocketDir(shared); err == nil {
+		t.Errorf("expected directory accessible to others to fail")
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(private, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSocketDir(link); err == nil {
+		t.Errorf("expected linked directory to fail")
+	}
+	if _, err := Client(filepath.Join(shared, "agent.sock")).List(); err == nil {
+		t.Errorf("expected client to refuse socket in a shared directory")
+	}
+}
+
+func TestServeSocketMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent", "agent.sock")
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+	done := make(chan error)
+	go func() {
+		done <- (&Agent{}).Serve(ctx, path)
+	}()
+	c := Client(path)
+	for i := 0; !c.IsRunning(); i++ {
+		if i > 50 {
+			t.Fatal("agent did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected socket mode 0600, found %#o", fi.Mode().Perm())
+	}
+	if _, err := c.List(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
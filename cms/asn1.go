@@ -0,0 +1,316 @@
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Pem types of CMS content.  PKCS7 is the type used by older tools, such as for .p7b files.
+const (
+	PemTypeCMS   = "CMS"
+	PemTypePKCS7 = "PKCS7"
+)
+
+var (
+	OIDData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	OIDSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	OIDEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA1WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidRSAPSS          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	oidMGF1            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+	oidECPublicKey     = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidECDSAWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+	oidRSAESOAEP = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 7}
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	// oidDESEDE3CBC is the default of older tools, supported only for decryption
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+
+	oidAES128Wrap = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 5}
+	oidAES192Wrap = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 25}
+	oidAES256Wrap = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 45}
+
+	// ECDH key agreement schemes of RFC 5753
+	oidDHSinglePassSHA1KDF   = asn1.ObjectIdentifier{1, 3, 133, 16, 840, 63, 0, 2}
+	oidDHSinglePassSHA256KDF = asn1.ObjectIdentifier{1, 3, 132, 1, 11, 1}
+	oidDHSinglePassSHA384KDF = asn1.ObjectIdentifier{1, 3, 132, 1, 11, 2}
+	oidDHSinglePassSHA512KDF = asn1.ObjectIdentifier{1, 3, 132, 1, 11, 3}
+)
+
+// contentInfo is the outer structure of all CMS content, RFC 5652 section 3.
+// Content is the [0] EXPLICIT content, its Bytes being the der of the inner content.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+// encapsulatedContentInfo holds the signed content.  EContent is absent when the content is detached.
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type pssParameters struct {
+	Hash         pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:0"`
+	MGF          pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:1"`
+	SaltLength   int                      `asn1:"optional,explicit,tag:2,default:20"`
+	TrailerField int                      `asn1:"optional,explicit,tag:3,default:1"`
+}
+
+type envelopedData struct {
+	Version              int
+	OriginatorInfo       asn1.RawValue   `asn1:"optional,tag:0"`
+	RecipientInfos       []asn1.RawValue `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+	UnprotectedAttrs     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// encryptedContentInfo holds the encrypted content as an implicit [0] OCTET STRING
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type keyTransRecipientInfo struct {
+	Version                int
+	RID                    asn1.RawValue
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+// keyAgreeRecipientInfo is the implicit [1] key agreement recipient.
+// Originator and UKM are [0] and [1] EXPLICIT, their Bytes being the der of their inner values.
+type keyAgreeRecipientInfo struct {
+	Version                int
+	Originator             asn1.RawValue `asn1:"tag:0"`
+	UKM                    asn1.RawValue `asn1:"optional,tag:1"`
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	RecipientEncryptedKeys []recipientEncryptedKey
+}
+
+type originatorPublicKey struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type recipientEncryptedKey struct {
+	RID          asn1.RawValue
+	EncryptedKey []byte
+}
+
+type oaepParameters struct {
+	Hash    pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:0"`
+	MGF     pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:1"`
+	PSource pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:2"`
+}
+
+// eccCMSSharedInfo is the shared info of the key derivation in ECDH key agreement, RFC 5753 section 7.2
+type eccCMSSharedInfo struct {
+	KeyInfo     pkix.AlgorithmIdentifier
+	EntityUInfo []byte `asn1:"optional,explicit,tag:0"`
+	SuppPubInfo []byte `asn1:"explicit,tag:2"`
+}
+
+// contextSpecific wraps the given content in a constructed, context specific tag
+func contextSpecific(tag int, content []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: tag, IsCompound: true, Bytes: content}
+}
+
+// elements splits the concatenated der elements of a SET or SEQUENCE content
+func elements(data []byte) ([]asn1.RawValue, error) {
+	var elems []asn1.RawValue
+	for len(data) > 0 {
+		var rv asn1.RawValue
+		rest, err := asn1.Unmarshal(data, &rv)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, rv)
+		data = rest
+	}
+	return elems, nil
+}
+
+// parseIdentifier parses a signer or recipient identifier,
+// either an IssuerAndSerialNumber or the implicit [0] subject key identifier.
+func parseIdentifier(rv asn1.RawValue) (issuer []byte, serial *big.Int, ski []byte, err error) {
+	switch {
+	case rv.Class == asn1.ClassUniversal && rv.Tag == asn1.TagSequence:
+		var ias issuerAndSerialNumber
+		if _, err := asn1.Unmarshal(rv.FullBytes, &ias); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid identifier  %v", err)
+		}
+		return ias.Issuer.FullBytes, ias.SerialNumber, nil, nil
+	case rv.Class == asn1.ClassContextSpecific && rv.Tag == 0:
+		return nil, nil, rv.Bytes, nil
+	default:
+		return nil, nil, nil, errors.New("invalid identifier")
+	}
+}
+
+// findCertificate finds the certificate with the given subject key id, or when nil, the issuer and serial number.
+func findCertificate(certs []*x509.Certificate, issuer []byte, serial *big.Int, ski []byte) *x509.Certificate {
+	for _, c := range certs {
+		if ski != nil {
+			if bytes.Equal(c.SubjectKeyId, ski) {
+				return c
+			}
+			continue
+		}
+		if serial != nil && bytes.Equal(c.RawIssuer, issuer) && c.SerialNumber.Cmp(serial) == 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+func digestAlgorithmOID(h crypto.Hash) (asn1.ObjectIdentifier, error) {
+	switch h {
+	case crypto.SHA1:
+		return oidSHA1, nil
+	case crypto.SHA256:
+		return oidSHA256, nil
+	case crypto.SHA384:
+		return oidSHA384, nil
+	case crypto.SHA512:
+		return oidSHA512, nil
+	default:
+		return nil, fmt.Errorf("digest algorithm %s is not supported", h)
+	}
+}
+
+func digestAlgorithmHash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA1):
+		return crypto.SHA1, nil
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("digest algorithm %s is not supported", oid)
+	}
+}
+
+// signatureAlgorithm gives the digest and signature algorithm identifiers for signing with the given algorithm.
+// RSA PKCS#1 v1.5 uses the rsaEncryption identifier, as most CMS implementations do.
+func signatureAlgorithm(sa x509.SignatureAlgorithm) (crypto.Hash, pkix.AlgorithmIdentifier, error) {
+	switch sa {
+	case x509.SHA256WithRSA:
+		return crypto.SHA256, pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}, nil
+	case x509.SHA384WithRSA:
+		return crypto.SHA384, pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}, nil
+	case x509.SHA512WithRSA:
+		return crypto.SHA512, pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}, nil
+	case x509.SHA256WithRSAPSS:
+		return pssAlgorithm(crypto.SHA256)
+	case x509.SHA384WithRSAPSS:
+		return pssAlgorithm(crypto.SHA384)
+	case x509.SHA512WithRSAPSS:
+		return pssAlgorithm(crypto.SHA512)
+	case x509.ECDSAWithSHA256:
+		return crypto.SHA256, pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}, nil
+	case x509.ECDSAWithSHA384:
+		return crypto.SHA384, pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA384}, nil
+	case x509.ECDSAWithSHA512:
+		return crypto.SHA512, pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA512}, nil
+	case x509.PureEd25519:
+		// RFC 8419, the message digest of Ed25519 signers is SHA-512
+		return crypto.SHA512, pkix.AlgorithmIdentifier{Algorithm: oidEd25519}, nil
+	default:
+		return 0, pkix.AlgorithmIdentifier{}, fmt.Errorf("signature algorithm %s is not supported by cms", sa)
+	}
+}
+
+func pssAlgorithm(h crypto.Hash) (crypto.Hash, pkix.AlgorithmIdentifier, error) {
+	hashOID, err := digestAlgorithmOID(h)
+	if err != nil {
+		return 0, pkix.AlgorithmIdentifier{}, err
+	}
+	hashAlg := pkix.AlgorithmIdentifier{Algorithm: hashOID, Parameters: asn1.NullRawValue}
+	hashDER, err := asn1.Marshal(hashAlg)
+	if err != nil {
+		return 0, pkix.AlgorithmIdentifier{}, err
+	}
+	params, err := asn1.Marshal(pssParameters{
+		Hash:         hashAlg,
+		MGF:          pkix.AlgorithmIdentifier{Algorithm: oidMGF1, Parameters: asn1.RawValue{FullBytes: hashDER}},
+		SaltLength:   h.Size(),
+		TrailerField: 1,
+	})
+	if err != nil {
+		return 0, pkix.AlgorithmIdentifier{}, err
+	}
+	return h, pkix.AlgorithmIdentifier{Algorithm: oidRSAPSS, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+// pssHash reads the hash from the parameters of an RSA PSS signature algorithm
+func pssHash(alg pkix.AlgorithmIdentifier) (crypto.Hash, error) {
+	var params pssParameters
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return 0, errors.New("invalid rsa pss parameters")
+	}
+	if len(params.Hash.Algorithm) == 0 {
+		// SHA-1 is the default hash of RFC 4055
+		return crypto.SHA1, nil
+	}
+	return digestAlgorithmHash(params.Hash.Algorithm)
+}
@@ -0,0 +1,120 @@
+package cms
+
+import (
+	"bytes"
+	"errors"
+)
+
+// berToDER converts the BER encoding, used by streaming tools such as 'openssl cms -stream', to DER.
+// Indefinite lengths are made definite and constructed octet strings are joined into a single primitive octet string.
+// Other BER encodings, such as non minimal lengths, are re-encoded, assuming the content is otherwise DER.
+func berToDER(data []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	for len(data) > 0 {
+		id, content, rest, err := berElement(data)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(id)
+		buf.Write(derLength(len(content)))
+		buf.Write(content)
+		data = rest
+	}
+	return buf.Bytes(), nil
+}
+
+// berElement reads the first element of the data, returning its identifier and der content, and the data following it.
+func berElement(data []byte) (id, content, rest []byte, err error) {
+	if len(data) < 2 {
+		return nil, nil, nil, errors.New("invalid ber, truncated element")
+	}
+	i := 1
+	if data[0]&0x1f == 0x1f {
+		for i < len(data) && data[i]&0x80 != 0 {
+			i++
+		}
+		i++
+	}
+	if i >= len(data) {
+		return nil, nil, nil, errors.New("invalid ber, truncated tag")
+	}
+	id = data[:i]
+	constructed := data[0]&0x20 != 0
+
+	l := int(data[i])
+	i++
+	indefinite := l == 0x80
+	if l > 0x80 {
+		n := l & 0x7f
+		if n > 4 || i+n > len(data) {
+			return nil, nil, nil, errors.New("invalid ber length")
+		}
+		l = 0
+		for _, b := range data[i : i+n] {
+			l = l<<8 | int(b)
+		}
+		i += n
+	}
+	if indefinite && !constructed {
+		return nil, nil, nil, errors.New("invalid ber, indefinite length of a primitive element")
+	}
+	if !indefinite {
+		if l < 0 || i+l > len(data) {
+			return nil, nil, nil, errors.New("invalid ber, truncated content")
+		}
+		if !constructed {
+			return id, data[i : i+l], data[i+l:], nil
+		}
+	}
+
+	body := data[i:]
+	if !indefinite {
+		body = data[i : i+l]
+	}
+	// octet strings are joined, other constructed elements have each of their elements converted
+	octets := len(id) == 1 && data[0] == 0x24
+	buf := bytes.NewBuffer(nil)
+	for {
+		if indefinite {
+			if len(body) < 2 {
+				return nil, nil, nil, errors.New("invalid ber, missing end of content")
+			}
+			if body[0] == 0 && body[1] == 0 {
+				body = body[2:]
+				break
+			}
+		} else if len(body) == 0 {
+			break
+		}
+		cid, cc, crest, err := berElement(body)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if octets {
+			buf.Write(cc)
+		} else {
+			buf.Write(cid)
+			buf.Write(derLength(len(cc)))
+			buf.Write(cc)
+		}
+		body = crest
+	}
+	if octets {
+		id = []byte{0x04}
+	}
+	if indefinite {
+		return id, buf.Bytes(), body, nil
+	}
+	return id, buf.Bytes(), data[i+l:], nil
+}
+
+func derLength(l int) []byte {
+	if l < 0x80 {
+		return []byte{byte(l)}
+	}
+	var b []byte
+	for ; l > 0; l >>= 8 {
+		b = append([]byte{byte(l)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
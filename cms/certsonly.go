@@ -0,0 +1,39 @@
+package cms
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+)
+
+// EncodeCertificates encodes the certificates and CRLs as degenerate signed data, with no content or signers.
+// This is the PKCS#7 certs-only bundle of .p7b and .p7c files.
+func EncodeCertificates(certs []*x509.Certificate, crls []*x509.RevocationList) ([]byte, error) {
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: OIDData},
+		SignerInfos:      []signerInfo{},
+	}
+	if len(certs) > 0 {
+		sd.Certificates = certificateSet(certs)
+	}
+	if len(crls) > 0 {
+		var raws [][]byte
+		for _, crl := range crls {
+			raws = append(raws, crl.Raw)
+		}
+		sd.CRLs = contextSpecific(1, bytes.Join(raws, nil))
+	}
+	der, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{ContentType: OIDSignedData, Content: contextSpecific(0, der)})
+}
+
+// IsCertificatesOnly checks if the signed data is degenerate, having no signers, only certificates and CRLs.
+func (sd SignedData) IsCertificatesOnly() bool {
+	return len(sd.Signers) == 0
+}
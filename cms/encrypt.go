@@ -0,0 +1,208 @@
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Encrypt encrypts the content read from the given reader, returning the der of a CMS ContentInfo holding the enveloped data.
+// The content is encrypted with AES-256-CBC, using a random key, which is encrypted for each of the recipients.
+// RSA recipients use RSA-OAEP with SHA-256, ECDSA recipients use ECDH key agreement with an ephemeral key.
+func Encrypt(content io.Reader, recipients ...*x509.Certificate) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients given")
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	cek := make([]byte, 32)
+	if _, err := rand.Read(cek); err != nil {
+		return nil, err
+	}
+	encAlg, encrypted, err := encryptContent(cek, data)
+	if err != nil {
+		return nil, err
+	}
+
+	version := 0
+	var infos [][]byte
+	for _, c := range recipients {
+		var info []byte
+		switch puk := c.PublicKey.(type) {
+		case *rsa.PublicKey:
+			info, err = keyTransRecipient(c, puk, cek)
+		case *ecdsa.PublicKey:
+			info, err = keyAgreeRecipient(c, puk, cek)
+			version = 2
+		default:
+			err = fmt.Errorf("%T keys can not be encrypted to", c.PublicKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("recipient %s  %v", c.Subject, err)
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, bytes.Compare)
+	ed := envelopedData{
+		Version: version,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                OIDData,
+			ContentEncryptionAlgorithm: encAlg,
+			EncryptedContent:           asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: encrypted},
+		},
+	}
+	for _, info := range infos {
+		ed.RecipientInfos = append(ed.RecipientInfos, asn1.RawValue{FullBytes: info})
+	}
+	der, err := asn1.Marshal(ed)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{ContentType: OIDEnvelopedData, Content: contextSpecific(0, der)})
+}
+
+func encryptContent(key, data []byte) (pkix.AlgorithmIdentifier, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	params, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	pad := aes.BlockSize - len(data)%aes.BlockSize
+	encrypted := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+	return pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: params}}, encrypted, nil
+}
+
+func keyTransRecipient(c *x509.Certificate, puk *rsa.PublicKey, cek []byte) ([]byte, error) {
+	ek, err := rsa.EncryptOAEP(crypto.SHA256.New(), rand.Reader, puk, cek, nil)
+	if err != nil {
+		return nil, err
+	}
+	rid, err := recipientIdentifier(c)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := oaepAlgorithm(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(keyTransRecipientInfo{
+		Version:                0,
+		RID:                    rid,
+		KeyEncryptionAlgorithm: alg,
+		EncryptedKey:           ek,
+	})
+}
+
+// keyAgreeRecipient encrypts the key with an AES key wrap, using a key agreed with an ephemeral ECDH key.
+func keyAgreeRecipient(c *x509.Certificate, puk *ecdsa.PublicKey, cek []byte) ([]byte, error) {
+	var kdf asn1.ObjectIdentifier
+	switch puk.Curve {
+	case elliptic.P256():
+		kdf = oidDHSinglePassSHA256KDF
+	case elliptic.P384():
+		kdf = oidDHSinglePassSHA384KDF
+	case elliptic.P521():
+		kdf = oidDHSinglePassSHA512KDF
+	default:
+		return nil, fmt.Errorf("curve %s is not supported", puk.Curve.Params().Name)
+	}
+	recipientKey, err := puk.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := recipientKey.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ephemeral.ECDH(recipientKey)
+	if err != nil {
+		return nil, err
+	}
+	wrapAlg, err := asn1.Marshal(pkix.AlgorithmIdentifier{Algorithm: oidAES256Wrap})
+	if err != nil {
+		return nil, err
+	}
+	alg := pkix.AlgorithmIdentifier{Algorithm: kdf, Parameters: asn1.RawValue{FullBytes: wrapAlg}}
+	kek, err := keyAgreementKEK(alg, secret, nil)
+	if err != nil {
+		return nil, err
+	}
+	ek, err := aesKeyWrap(kek, cek)
+	if err != nil {
+		return nil, err
+	}
+	originator, err := originatorKey(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	rid, err := recipientIdentifier(c)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.MarshalWithParams(keyAgreeRecipientInfo{
+		Version:                3,
+		Originator:             contextSpecific(0, originator),
+		KeyEncryptionAlgorithm: alg,
+		RecipientEncryptedKeys: []recipientEncryptedKey{{RID: rid, EncryptedKey: ek}},
+	}, "tag:1")
+}
+
+// originatorKey encodes the ephemeral public key as the implicit [1] OriginatorPublicKey
+func originatorKey(key *ecdh.PrivateKey) ([]byte, error) {
+	return asn1.MarshalWithParams(originatorPublicKey{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidECPublicKey},
+		PublicKey: asn1.BitString{Bytes: key.PublicKey().Bytes(), BitLength: len(key.PublicKey().Bytes()) * 8},
+	}, "tag:1")
+}
+
+// recipientIdentifier identifies the recipient by its issuer and serial number
+func recipientIdentifier(c *x509.Certificate) (asn1.RawValue, error) {
+	der, err := asn1.Marshal(issuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: c.RawIssuer},
+		SerialNumber: c.SerialNumber,
+	})
+	return asn1.RawValue{FullBytes: der}, err
+}
+
+func oaepAlgorithm(h crypto.Hash) (pkix.AlgorithmIdentifier, error) {
+	hashOID, err := digestAlgorithmOID(h)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	hashAlg := pkix.AlgorithmIdentifier{Algorithm: hashOID, Parameters: asn1.NullRawValue}
+	hashDER, err := asn1.Marshal(hashAlg)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	params, err := asn1.Marshal(oaepParameters{
+		Hash: hashAlg,
+		MGF:  pkix.AlgorithmIdentifier{Algorithm: oidMGF1, Parameters: asn1.RawValue{FullBytes: hashDER}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidRSAESOAEP, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
@@ -0,0 +1,352 @@
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// EnvelopedData is CMS enveloped data, as defined in RFC 5652 section 6.
+// The content is encrypted with a content encryption key, which is encrypted for each recipient.
+type EnvelopedData struct {
+	ContentType asn1.ObjectIdentifier
+	Recipients  []*RecipientInfo
+
+	contentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	encryptedContent           []byte
+}
+
+// RecipientInfo is the content encryption key, encrypted for a single recipient.
+// The recipient certificate is identified by either its Issuer and SerialNumber, or its SubjectKeyId.
+type RecipientInfo struct {
+	Issuer                 []byte
+	SerialNumber           *big.Int
+	SubjectKeyId           []byte
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+
+	// originatorKey is the ephemeral public key of key agreement recipients
+	originatorKey []byte
+	ukm           []byte
+}
+
+// ParseEnvelopedData parses the der, or ber, of a CMS ContentInfo holding enveloped data.
+// Recipients other than key transport and key agreement recipients are ignored.
+func ParseEnvelopedData(der []byte) (*EnvelopedData, error) {
+	ct, content, err := ParseContentInfo(der)
+	if err != nil {
+		return nil, err
+	}
+	if !ct.Equal(OIDEnvelopedData) {
+		return nil, fmt.Errorf("cms content type %s is not enveloped data", ct)
+	}
+	var raw envelopedData
+	if _, err := asn1.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("invalid cms enveloped data  %v", err)
+	}
+	ed := &EnvelopedData{
+		ContentType:                raw.EncryptedContentInfo.ContentType,
+		contentEncryptionAlgorithm: raw.EncryptedContentInfo.ContentEncryptionAlgorithm,
+	}
+	if ed.encryptedContent, err = encryptedContent(raw.EncryptedContentInfo.EncryptedContent); err != nil {
+		return nil, err
+	}
+	for _, rv := range raw.RecipientInfos {
+		var ris []*RecipientInfo
+		switch {
+		case rv.Class == asn1.ClassUniversal && rv.Tag == asn1.TagSequence:
+			ris, err = parseKeyTransRecipient(rv.FullBytes)
+		case rv.Class == asn1.ClassContextSpecific && rv.Tag == 1:
+			ris, err = parseKeyAgreeRecipient(rv.FullBytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ed.Recipients = append(ed.Recipients, ris...)
+	}
+	if len(ed.Recipients) == 0 {
+		return nil, errors.New("enveloped data has no supported recipients")
+	}
+	return ed, nil
+}
+
+// ReadEnvelopedData parses enveloped data from either a CMS or PKCS7 pem, or der.
+func ReadEnvelopedData(data []byte) (*EnvelopedData, error) {
+	if blk, _ := pem.Decode(data); blk != nil {
+		if !IsCMSPem(blk) {
+			return nil, fmt.Errorf("pem %s is not cms", blk.Type)
+		}
+		data = blk.Bytes
+	}
+	return ParseEnvelopedData(data)
+}
+
+// Decrypt decrypts the content using the given recipient and its private key.
+func (ed EnvelopedData) Decrypt(ri *RecipientInfo, key crypto.PrivateKey) ([]byte, error) {
+	cek, err := ri.decryptKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return decryptContent(ed.contentEncryptionAlgorithm, cek, ed.encryptedContent)
+}
+
+// FindCertificate finds the recipients certificate in the given certificates
+func (ri RecipientInfo) FindCertificate(certs []*x509.Certificate) *x509.Certificate {
+	return findCertificate(certs, ri.Issuer, ri.SerialNumber, ri.SubjectKeyId)
+}
+
+func (ri RecipientInfo) decryptKey(key crypto.PrivateKey) ([]byte, error) {
+	alg := ri.KeyEncryptionAlgorithm
+	switch {
+	case alg.Algorithm.Equal(oidRSAEncryption), alg.Algorithm.Equal(oidRSAESOAEP):
+		dk, ok := key.(crypto.Decrypter)
+		if !ok {
+			return nil, fmt.Errorf("%T key can not decrypt %s", key, alg.Algorithm)
+		}
+		if _, ok := dk.Public().(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("%T key can not decrypt %s", key, alg.Algorithm)
+		}
+		var opts crypto.DecrypterOpts
+		if alg.Algorithm.Equal(oidRSAESOAEP) {
+			oaep, err := oaepOptions(alg)
+			if err != nil {
+				return nil, err
+			}
+			opts = oaep
+		}
+		return dk.Decrypt(rand.Reader, ri.EncryptedKey, opts)
+
+	case alg.Algorithm.Equal(oidDHSinglePassSHA1KDF), alg.Algorithm.Equal(oidDHSinglePassSHA256KDF),
+		alg.Algorithm.Equal(oidDHSinglePassSHA384KDF), alg.Algorithm.Equal(oidDHSinglePassSHA512KDF):
+		k, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%T key can not decrypt %s", key, alg.Algorithm)
+		}
+		prk, err := k.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		puk, err := prk.Curve().NewPublicKey(ri.originatorKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid originator key  %v", err)
+		}
+		secret, err := prk.ECDH(puk)
+		if err != nil {
+			return nil, err
+		}
+		kek, err := keyAgreementKEK(alg, secret, ri.ukm)
+		if err != nil {
+			return nil, err
+		}
+		return aesKeyUnwrap(kek, ri.EncryptedKey)
+	default:
+		return nil, fmt.Errorf("key encryption algorithm %s is not supported", alg.Algorithm)
+	}
+}
+
+// keyAgreementKEK derives the key encryption key of an ECDH key agreement from the shared secret.
+// The parameters of the key agreement algorithm identify the key wrap algorithm.
+func keyAgreementKEK(alg pkix.AlgorithmIdentifier, secret, ukm []byte) ([]byte, error) {
+	var h crypto.Hash
+	switch {
+	case alg.Algorithm.Equal(oidDHSinglePassSHA1KDF):
+		h = crypto.SHA1
+	case alg.Algorithm.Equal(oidDHSinglePassSHA256KDF):
+		h = crypto.SHA256
+	case alg.Algorithm.Equal(oidDHSinglePassSHA384KDF):
+		h = crypto.SHA384
+	case alg.Algorithm.Equal(oidDHSinglePassSHA512KDF):
+		h = crypto.SHA512
+	default:
+		return nil, fmt.Errorf("key agreement algorithm %s is not supported", alg.Algorithm)
+	}
+	var wrapAlg pkix.AlgorithmIdentifier
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &wrapAlg); err != nil {
+		return nil, fmt.Errorf("invalid key wrap algorithm  %v", err)
+	}
+	var keyLen int
+	switch {
+	case wrapAlg.Algorithm.Equal(oidAES128Wrap):
+		keyLen = 16
+	case wrapAlg.Algorithm.Equal(oidAES192Wrap):
+		keyLen = 24
+	case wrapAlg.Algorithm.Equal(oidAES256Wrap):
+		keyLen = 32
+	default:
+		return nil, fmt.Errorf("key wrap algorithm %s is not supported", wrapAlg.Algorithm)
+	}
+	suppPubInfo := make([]byte, 4)
+	binary.BigEndian.PutUint32(suppPubInfo, uint32(keyLen*8))
+	sharedInfo, err := asn1.Marshal(eccCMSSharedInfo{
+		KeyInfo:     wrapAlg,
+		EntityUInfo: ukm,
+		SuppPubInfo: suppPubInfo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return x963KDF(h, secret, sharedInfo, keyLen), nil
+}
+
+func oaepOptions(alg pkix.AlgorithmIdentifier) (*rsa.OAEPOptions, error) {
+	// SHA-1 is the default hash of RFC 4055
+	opts := &rsa.OAEPOptions{Hash: crypto.SHA1, MGFHash: crypto.SHA1}
+	if len(alg.Parameters.FullBytes) == 0 || bytes.Equal(alg.Parameters.FullBytes, asn1.NullBytes) {
+		return opts, nil
+	}
+	var params oaepParameters
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.New("invalid rsa oaep parameters")
+	}
+	var err error
+	if len(params.Hash.Algorithm) > 0 {
+		if opts.Hash, err = digestAlgorithmHash(params.Hash.Algorithm); err != nil {
+			return nil, err
+		}
+	}
+	if len(params.MGF.Algorithm) > 0 {
+		var mgfHash pkix.AlgorithmIdentifier
+		if _, err := asn1.Unmarshal(params.MGF.Parameters.FullBytes, &mgfHash); err != nil {
+			return nil, errors.New("invalid rsa oaep mask generation parameters")
+		}
+		if opts.MGFHash, err = digestAlgorithmHash(mgfHash.Algorithm); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+func parseKeyTransRecipient(der []byte) ([]*RecipientInfo, error) {
+	var ktri keyTransRecipientInfo
+	if _, err := asn1.Unmarshal(der, &ktri); err != nil {
+		return nil, fmt.Errorf("invalid key transport recipient  %v", err)
+	}
+	ri := &RecipientInfo{
+		KeyEncryptionAlgorithm: ktri.KeyEncryptionAlgorithm,
+		EncryptedKey:           ktri.EncryptedKey,
+	}
+	var err error
+	if ri.Issuer, ri.SerialNumber, ri.SubjectKeyId, err = parseIdentifier(ktri.RID); err != nil {
+		return nil, fmt.Errorf("invalid recipient identifier  %v", err)
+	}
+	return []*RecipientInfo{ri}, nil
+}
+
+// parseKeyAgreeRecipient parses a key agreement recipient into a RecipientInfo for each of its recipient keys.
+// Only originators using an ephemeral public key are supported.
+func parseKeyAgreeRecipient(der []byte) ([]*RecipientInfo, error) {
+	var kari keyAgreeRecipientInfo
+	if _, err := asn1.UnmarshalWithParams(der, &kari, "tag:1"); err != nil {
+		return nil, fmt.Errorf("invalid key agreement recipient  %v", err)
+	}
+	var opk originatorPublicKey
+	if _, err := asn1.UnmarshalWithParams(kari.Originator.Bytes, &opk, "tag:1"); err != nil {
+		return nil, errors.New("key agreement originator must be a public key")
+	}
+	var ukm []byte
+	if len(kari.UKM.Bytes) > 0 {
+		if _, err := asn1.Unmarshal(kari.UKM.Bytes, &ukm); err != nil {
+			return nil, fmt.Errorf("invalid key agreement ukm  %v", err)
+		}
+	}
+	var ris []*RecipientInfo
+	for _, rek := range kari.RecipientEncryptedKeys {
+		ri := &RecipientInfo{
+			KeyEncryptionAlgorithm: kari.KeyEncryptionAlgorithm,
+			EncryptedKey:           rek.EncryptedKey,
+			originatorKey:          opk.PublicKey.RightAlign(),
+			ukm:                    ukm,
+		}
+		rid := rek.RID
+		if rid.Class == asn1.ClassContextSpecific && rid.Tag == 0 {
+			// rKeyId is an implicit RecipientKeyIdentifier, beginning with the subject key id
+			var ski []byte
+			if _, err := asn1.Unmarshal(rid.Bytes, &ski); err != nil {
+				return nil, fmt.Errorf("invalid recipient key identifier  %v", err)
+			}
+			ri.SubjectKeyId = ski
+		} else {
+			var err error
+			if ri.Issuer, ri.SerialNumber, ri.SubjectKeyId, err = parseIdentifier(rid); err != nil {
+				return nil, fmt.Errorf("invalid recipient identifier  %v", err)
+			}
+		}
+		ris = append(ris, ri)
+	}
+	return ris, nil
+}
+
+// encryptedContent reads the implicit [0] OCTET STRING, which may be constructed from several octet strings.
+func encryptedContent(rv asn1.RawValue) ([]byte, error) {
+	if len(rv.FullBytes) == 0 {
+		return nil, errors.New("encrypted content is detached")
+	}
+	if !rv.IsCompound {
+		return rv.Bytes, nil
+	}
+	elems, err := elements(rv.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted content  %v", err)
+	}
+	buf := bytes.NewBuffer(nil)
+	for _, e := range elems {
+		buf.Write(e.Bytes)
+	}
+	return buf.Bytes(), nil
+}
+
+// decryptContent decrypts AES or, for older content, triple DES CBC encrypted content
+func decryptContent(alg pkix.AlgorithmIdentifier, key, data []byte) ([]byte, error) {
+	var keyLen int
+	newCipher := aes.NewCipher
+	switch {
+	case alg.Algorithm.Equal(oidAES128CBC):
+		keyLen = 16
+	case alg.Algorithm.Equal(oidAES192CBC):
+		keyLen = 24
+	case alg.Algorithm.Equal(oidAES256CBC):
+		keyLen = 32
+	case alg.Algorithm.Equal(oidDESEDE3CBC):
+		keyLen = 24
+		newCipher = des.NewTripleDESCipher
+	default:
+		return nil, fmt.Errorf("content encryption algorithm %s is not supported", alg.Algorithm)
+	}
+	if len(key) != keyLen {
+		return nil, errors.New("content encryption key has the wrong length")
+	}
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	size := block.BlockSize()
+	var iv []byte
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &iv); err != nil || len(iv) != size {
+		return nil, errors.New("invalid content encryption iv")
+	}
+	if len(data) == 0 || len(data)%size != 0 {
+		return nil, errors.New("encrypted content is not a multiple of the block size")
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > size || !bytes.Equal(plain[len(plain)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, errors.New("decryption failed, invalid padding")
+	}
+	return plain[:len(plain)-pad], nil
+}
@@ -0,0 +1,82 @@
+package cms
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// keyWrapIV is the default initial value of RFC 3394
+var keyWrapIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// aesKeyWrap wraps the key with the key encryption key, as defined in RFC 3394
+func aesKeyWrap(kek, key []byte) ([]byte, error) {
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, errors.New("key to wrap must be a multiple of 8 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(key) / 8
+	a := append([]byte{}, keyWrapIV...)
+	r := append([]byte{}, key...)
+	b := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(b, a)
+			copy(b[8:], r[i*8:(i+1)*8])
+			block.Encrypt(b, b)
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(b[:8])^t)
+			copy(r[i*8:], b[8:])
+		}
+	}
+	return append(a, r...), nil
+}
+
+// aesKeyUnwrap unwraps a key wrapped with aesKeyWrap
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, errors.New("wrapped key must be a multiple of 8 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(wrapped)/8 - 1
+	a := append([]byte{}, wrapped[:8]...)
+	r := append([]byte{}, wrapped[8:]...)
+	b := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n - 1; i >= 0; i-- {
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(b, binary.BigEndian.Uint64(a)^t)
+			copy(b[8:], r[i*8:(i+1)*8])
+			block.Decrypt(b, b)
+			copy(a, b[:8])
+			copy(r[i*8:], b[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(a, keyWrapIV) != 1 {
+		return nil, errors.New("key unwrap failed integrity check")
+	}
+	return r, nil
+}
+
+// x963KDF derives a key of the given length from the shared secret, as defined in ANSI X9.63
+func x963KDF(h crypto.Hash, secret, sharedInfo []byte, length int) []byte {
+	var key []byte
+	counter := make([]byte, 4)
+	for i := uint32(1); len(key) < length; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		hh := h.New()
+		hh.Write(secret)
+		hh.Write(counter)
+		hh.Write(sharedInfo)
+		key = hh.Sum(key)
+	}
+	return key[:length]
+}
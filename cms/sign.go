@@ -0,0 +1,151 @@
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"slices"
+	"time"
+)
+
+// Signer is a certificate and the key to sign with
+type Signer struct {
+	Certificate        *x509.Certificate
+	Key                crypto.Signer
+	SignatureAlgorithm x509.SignatureAlgorithm
+}
+
+// Sign signs the content read from the given reader, returning the der of a CMS ContentInfo holding the signed data.
+// When detached, the content is not included in the signed data.
+// The signers certificate, followed by the given certificates, are included in the signed data.
+// The content-type, signing-time and message-digest are signed as attributes.
+func Sign(content io.Reader, signer Signer, detached bool, certs ...*x509.Certificate) ([]byte, error) {
+	if signer.Certificate == nil || signer.Key == nil {
+		return nil, errors.New("signer certificate and key are required")
+	}
+	h, sigAlg, err := signatureAlgorithm(signer.SignatureAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	digestOID, err := digestAlgorithmOID(h)
+	if err != nil {
+		return nil, err
+	}
+	hh := h.New()
+	var attached []byte
+	if detached {
+		_, err = io.Copy(hh, content)
+	} else {
+		if attached, err = io.ReadAll(content); err == nil {
+			hh.Write(attached)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := signedAttributes(OIDData, hh.Sum(nil), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	signedAttrs, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: attrs})
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signAttributes(signer, h, signedAttrs)
+	if err != nil {
+		return nil, err
+	}
+	sid, err := asn1.Marshal(issuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: signer.Certificate.RawIssuer},
+		SerialNumber: signer.Certificate.SerialNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: digestOID}},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: OIDData},
+		Certificates:     certificateSet(append([]*x509.Certificate{signer.Certificate}, certs...)),
+		SignerInfos: []signerInfo{{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: digestOID},
+			SignedAttrs:        contextSpecific(0, attrs),
+			SignatureAlgorithm: sigAlg,
+			Signature:          sig,
+		}},
+	}
+	if !detached {
+		octets, err := asn1.Marshal(attached)
+		if err != nil {
+			return nil, err
+		}
+		sd.EncapContentInfo.EContent = contextSpecific(0, octets)
+	}
+	der, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{ContentType: OIDSignedData, Content: contextSpecific(0, der)})
+}
+
+// signedAttributes encodes the content of the signed attributes SET, in the DER order of their encodings.
+func signedAttributes(contentType asn1.ObjectIdentifier, digest []byte, signingTime time.Time) ([]byte, error) {
+	values := []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidAttributeContentType, contentType},
+		{oidAttributeSigningTime, signingTime.UTC()},
+		{oidAttributeMessageDigest, digest},
+	}
+	var encoded [][]byte
+	for _, v := range values {
+		value, err := asn1.Marshal(v.value)
+		if err != nil {
+			return nil, err
+		}
+		attr, err := asn1.Marshal(attribute{Type: v.oid, Values: []asn1.RawValue{{FullBytes: value}}})
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, attr)
+	}
+	slices.SortFunc(encoded, bytes.Compare)
+	return bytes.Join(encoded, nil), nil
+}
+
+func signAttributes(signer Signer, h crypto.Hash, signedAttrs []byte) ([]byte, error) {
+	if signer.SignatureAlgorithm == x509.PureEd25519 {
+		return signer.Key.Sign(rand.Reader, signedAttrs, crypto.Hash(0))
+	}
+	hh := h.New()
+	hh.Write(signedAttrs)
+	var opts crypto.SignerOpts = h
+	switch signer.SignatureAlgorithm {
+	case x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS:
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h}
+	}
+	return signer.Key.Sign(rand.Reader, hh.Sum(nil), opts)
+}
+
+// certificateSet encodes the given certificates as the implicit [0] CertificateSet, ignoring duplicates.
+func certificateSet(certs []*x509.Certificate) asn1.RawValue {
+	var raws [][]byte
+	for _, c := range certs {
+		if c == nil || slices.ContainsFunc(raws, func(r []byte) bool { return bytes.Equal(r, c.Raw) }) {
+			continue
+		}
+		raws = append(raws, c.Raw)
+	}
+	return contextSpecific(0, bytes.Join(raws, nil))
+}
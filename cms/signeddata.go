@@ -0,0 +1,326 @@
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// SignedData is CMS signed data, as defined in RFC 5652 section 5.
+type SignedData struct {
+	ContentType asn1.ObjectIdentifier
+	// Content is the signed content, nil when the content is detached.
+	Content      []byte
+	Certificates []*x509.Certificate
+	CRLs         []*x509.RevocationList
+	Signers      []*SignerInfo
+}
+
+// SignerInfo is a single signature of the signed data.
+// The signer certificate is identified by either its Issuer and SerialNumber, or its SubjectKeyId.
+type SignerInfo struct {
+	Issuer          []byte
+	SerialNumber    *big.Int
+	SubjectKeyId    []byte
+	DigestAlgorithm crypto.Hash
+	SigningTime     time.Time
+	Signature       []byte
+
+	signatureAlgorithm pkix.AlgorithmIdentifier
+	// signedAttrs is the der SET of the signed attributes, as they were signed
+	signedAttrs   []byte
+	contentType   asn1.ObjectIdentifier
+	messageDigest []byte
+}
+
+// IsDetached checks if the signed content is held outside the signed data
+func (sd SignedData) IsDetached() bool {
+	return sd.Content == nil
+}
+
+// ParseContentInfo parses the der, or ber, of a CMS ContentInfo, returning its content type and the der of its content.
+func ParseContentInfo(der []byte) (asn1.ObjectIdentifier, []byte, error) {
+	der, err := berToDER(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cms content  %v", err)
+	}
+	var ci contentInfo
+	rest, err := asn1.Unmarshal(der, &ci)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cms content  %v", err)
+	}
+	if len(rest) > 0 {
+		return nil, nil, errors.New("invalid cms content, trailing data")
+	}
+	return ci.ContentType, ci.Content.Bytes, nil
+}
+
+// ParseSignedData parses the der, or ber, of a CMS ContentInfo holding signed data.
+func ParseSignedData(der []byte) (*SignedData, error) {
+	ct, content, err := ParseContentInfo(der)
+	if err != nil {
+		return nil, err
+	}
+	if !ct.Equal(OIDSignedData) {
+		return nil, fmt.Errorf("cms content type %s is not signed data", ct)
+	}
+	var raw signedData
+	if _, err := asn1.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("invalid cms signed data  %v", err)
+	}
+	sd := &SignedData{ContentType: raw.EncapContentInfo.EContentType}
+	if len(raw.EncapContentInfo.EContent.Bytes) > 0 {
+		var octets []byte
+		if _, err := asn1.Unmarshal(raw.EncapContentInfo.EContent.Bytes, &octets); err != nil {
+			return nil, fmt.Errorf("invalid cms signed content  %v", err)
+		}
+		sd.Content = octets
+		if sd.Content == nil {
+			sd.Content = []byte{}
+		}
+	}
+	if sd.Certificates, err = parseCertificates(raw.Certificates.Bytes); err != nil {
+		return nil, err
+	}
+	if sd.CRLs, err = parseCRLs(raw.CRLs.Bytes); err != nil {
+		return nil, err
+	}
+	for _, rsi := range raw.SignerInfos {
+		si, err := newSignerInfo(rsi)
+		if err != nil {
+			return nil, err
+		}
+		sd.Signers = append(sd.Signers, si)
+	}
+	return sd, nil
+}
+
+// Verify verifies the signatures of the signed data, returning the certificate of each signer.
+// When content is nil, the attached content is verified.
+// Signer certificates are found in the signed data, or the given certificates.
+// Only the signatures are verified, the signer certificates are not validated.
+func (sd SignedData) Verify(content io.Reader, certs ...*x509.Certificate) ([]*x509.Certificate, error) {
+	if len(sd.Signers) == 0 {
+		return nil, errors.New("signed data has no signers")
+	}
+	if content == nil {
+		if sd.IsDetached() {
+			return nil, errors.New("signed content is detached and must be given")
+		}
+		content = bytes.NewReader(sd.Content)
+	}
+	hashes := map[crypto.Hash]hash.Hash{}
+	var writers []io.Writer
+	for _, si := range sd.Signers {
+		if _, ok := hashes[si.DigestAlgorithm]; !ok {
+			hashes[si.DigestAlgorithm] = si.DigestAlgorithm.New()
+			writers = append(writers, hashes[si.DigestAlgorithm])
+		}
+	}
+	// signers without signed attributes sign the content itself
+	message := bytes.NewBuffer(nil)
+	for _, si := range sd.Signers {
+		if si.signedAttrs == nil {
+			writers = append(writers, message)
+			break
+		}
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), content); err != nil {
+		return nil, err
+	}
+
+	candidates := append(append([]*x509.Certificate{}, sd.Certificates...), certs...)
+	signers := make([]*x509.Certificate, len(sd.Signers))
+	for i, si := range sd.Signers {
+		cert := si.FindCertificate(candidates)
+		if cert == nil {
+			return nil, fmt.Errorf("certificate of signer %d not found", i+1)
+		}
+		digest := hashes[si.DigestAlgorithm].Sum(nil)
+		if err := si.verify(sd.ContentType, cert.PublicKey, digest, message.Bytes()); err != nil {
+			return nil, fmt.Errorf("signature of %s is invalid  %v", cert.Subject, err)
+		}
+		signers[i] = cert
+	}
+	return signers, nil
+}
+
+// FindCertificate finds the signers certificate in the given certificates
+func (si SignerInfo) FindCertificate(certs []*x509.Certificate) *x509.Certificate {
+	return findCertificate(certs, si.Issuer, si.SerialNumber, si.SubjectKeyId)
+}
+
+func (si SignerInfo) verify(contentType asn1.ObjectIdentifier, puk crypto.PublicKey, digest, content []byte) error {
+	message := content
+	if si.signedAttrs != nil {
+		if !si.contentType.Equal(contentType) {
+			return errors.New("content-type attribute does not match the content")
+		}
+		if !bytes.Equal(si.messageDigest, digest) {
+			return errors.New("message digest does not match the content")
+		}
+		message = si.signedAttrs
+		h := si.DigestAlgorithm.New()
+		h.Write(message)
+		digest = h.Sum(nil)
+	} else if !contentType.Equal(OIDData) {
+		return errors.New("signed attributes are required for content other than data")
+	}
+
+	alg := si.signatureAlgorithm.Algorithm
+	switch k := puk.(type) {
+	case *rsa.PublicKey:
+		switch {
+		case alg.Equal(oidRSAPSS):
+			h, err := pssHash(si.signatureAlgorithm)
+			if err != nil {
+				return err
+			}
+			if h != si.DigestAlgorithm {
+				return errors.New("rsa pss hash does not match the digest algorithm")
+			}
+			return rsa.VerifyPSS(k, h, digest, si.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		case alg.Equal(oidRSAEncryption), alg.Equal(oidSHA1WithRSA), alg.Equal(oidSHA256WithRSA),
+			alg.Equal(oidSHA384WithRSA), alg.Equal(oidSHA512WithRSA):
+			return rsa.VerifyPKCS1v15(k, si.DigestAlgorithm, digest, si.Signature)
+		}
+	case *ecdsa.PublicKey:
+		if alg.Equal(oidECPublicKey) || alg.Equal(oidECDSAWithSHA1) || alg.Equal(oidECDSAWithSHA256) ||
+			alg.Equal(oidECDSAWithSHA384) || alg.Equal(oidECDSAWithSHA512) {
+			if !ecdsa.VerifyASN1(k, digest, si.Signature) {
+				return errors.New("ecdsa verification failure")
+			}
+			return nil
+		}
+	case ed25519.PublicKey:
+		if alg.Equal(oidEd25519) {
+			if !ed25519.Verify(k, message, si.Signature) {
+				return errors.New("ed25519 verification failure")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("signature algorithm %s can not be used with a %T key", alg, puk)
+}
+
+func newSignerInfo(rsi signerInfo) (*SignerInfo, error) {
+	h, err := digestAlgorithmHash(rsi.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	si := &SignerInfo{
+		DigestAlgorithm:    h,
+		Signature:          rsi.Signature,
+		signatureAlgorithm: rsi.SignatureAlgorithm,
+	}
+	if si.Issuer, si.SerialNumber, si.SubjectKeyId, err = parseIdentifier(rsi.SID); err != nil {
+		return nil, fmt.Errorf("invalid signer identifier  %v", err)
+	}
+
+	if len(rsi.SignedAttrs.FullBytes) == 0 {
+		return si, nil
+	}
+	// The signature is of the attributes encoded as a SET, rather than the implicit [0] tag
+	si.signedAttrs, err = asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: rsi.SignedAttrs.Bytes})
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := elements(rsi.SignedAttrs.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signed attributes  %v", err)
+	}
+	for _, rv := range attrs {
+		var attr attribute
+		if _, err := asn1.Unmarshal(rv.FullBytes, &attr); err != nil {
+			return nil, fmt.Errorf("invalid signed attribute  %v", err)
+		}
+		if len(attr.Values) != 1 {
+			continue
+		}
+		value := attr.Values[0].FullBytes
+		switch {
+		case attr.Type.Equal(oidAttributeContentType):
+			_, err = asn1.Unmarshal(value, &si.contentType)
+		case attr.Type.Equal(oidAttributeMessageDigest):
+			_, err = asn1.Unmarshal(value, &si.messageDigest)
+		case attr.Type.Equal(oidAttributeSigningTime):
+			_, err = asn1.Unmarshal(value, &si.SigningTime)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid signed attribute %s  %v", attr.Type, err)
+		}
+	}
+	if si.contentType == nil || si.messageDigest == nil {
+		return nil, errors.New("signed attributes are missing the content-type or message-digest")
+	}
+	return si, nil
+}
+
+// parseCertificates parses the certificates of a CertificateSet, ignoring other certificate types.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	elems, err := elements(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cms certificates  %v", err)
+	}
+	var certs []*x509.Certificate
+	for _, rv := range elems {
+		if rv.Class != asn1.ClassUniversal || rv.Tag != asn1.TagSequence {
+			continue
+		}
+		c, err := x509.ParseCertificate(rv.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	return certs, nil
+}
+
+// parseCRLs parses the CRLs of a RevocationInfoChoices, ignoring other revocation types.
+func parseCRLs(data []byte) ([]*x509.RevocationList, error) {
+	elems, err := elements(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cms crls  %v", err)
+	}
+	var crls []*x509.RevocationList
+	for _, rv := range elems {
+		if rv.Class != asn1.ClassUniversal || rv.Tag != asn1.TagSequence {
+			continue
+		}
+		crl, err := x509.ParseRevocationList(rv.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		crls = append(crls, crl)
+	}
+	return crls, nil
+}
+
+// ReadSignedData parses signed data from either a CMS or PKCS7 pem, or der.
+func ReadSignedData(data []byte) (*SignedData, error) {
+	if blk, _ := pem.Decode(data); blk != nil {
+		if !IsCMSPem(blk) {
+			return nil, fmt.Errorf("pem %s is not cms", blk.Type)
+		}
+		data = blk.Bytes
+	}
+	return ParseSignedData(data)
+}
+
+// IsCMSPem checks if the given pem is CMS content
+func IsCMSPem(blk *pem.Block) bool {
+	return strings.EqualFold(blk.Type, PemTypeCMS) || strings.EqualFold(blk.Type, PemTypePKCS7)
+}
@@ -0,0 +1,246 @@
+package cms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signed data made with 'openssl cms -sign -binary' of testContent, by a P-256 signer and an RSA signer using PSS with SHA-384.
+// The stream signature is ber, using indefinite lengths and a constructed octet string of the content.
+const (
+	testContent = "the content being signed\n"
+
+	testSignedAttached = `MIIDSgYJKoZIhvcNAQcCoIIDOzCCAzcCAQExDTALBglghkgBZQMEAgEwKAYJKoZIhvcNAQcBoBsE
+GXRoZSBjb250ZW50IGJlaW5nIHNpZ25lZAqgggGAMIIBfDCCASKgAwIBAgIBFTAKBggqhkjOPQQD
+AjAcMRowGAYDVQQDDBFwZW1wYWwgY21zIHNpZ25lcjAgFw0yNjEwMTkwNDI3MDZaGA8yMTI2MDky
+NTA0MjcwNlowHDEaMBgGA1UEAwwRcGVtcGFsIGNtcyBzaWduZXIwWTATBgcqhkjOPQIBBggqhkjO
+PQMBBwNCAAR1cV2rRgtesFhUkA0s1ETcaYVJPU82/Nz0TOJ6rKcS4iqq2A5TFDLKJSjZdf4bf+6S
+I7l801bZjuLjbb+35/0Ro1MwUTAdBgNVHQ4EFgQUVDRSzod1W7w/uZC23rOjMQmrp2QwHwYDVR0j
+BBgwFoAUVDRSzod1W7w/uZC23rOjMQmrp2QwDwYDVR0TAQH/BAUwAwEB/zAKBggqhkjOPQQDAgNI
+ADBFAiEAhB9eO4do++vToBuFvGYu4+JSUznQDIxa35nHFImZVkQCIEB9r3Lsgpv6wIUiEC2xnSTu
+pP50sgbT+TBkXHPmATy6MYIBczCCAW8CAQEwITAcMRowGAYDVQQDDBFwZW1wYWwgY21zIHNpZ25l
+cgIBFTALBglghkgBZQMEAgGggeQwGAYJKoZIhvcNAQkDMQsGCSqGSIb3DQEHATAcBgkqhkiG9w0B
+CQUxDxcNMjYxMDE5MDQyNzA2WjAvBgkqhkiG9w0BCQQxIgQgn0/LoTK7qYQjYGRb1V5uiRQpMa2m
+SXZxTV3DF0FBioAweQYJKoZIhvcNAQkPMWwwajALBglghkgBZQMEASowCwYJYIZIAWUDBAEWMAsG
+CWCGSAFlAwQBAjAKBggqhkiG9w0DBzAOBggqhkiG9w0DAgICAIAwDQYIKoZIhvcNAwICAUAwBwYF
+Kw4DAgcwDQYIKoZIhvcNAwICASgwCgYIKoZIzj0EAwIERzBFAiEA8Ir0zaOLtFEIuXDARXmGjMK9
+8+v80R39BcriTeOkhQECIA5hGBGtV5DZ7NC9/+So1Q+uhafKP3ytykTHzOEKgHP+`
+	testSignedDetached = `MIIDLQYJKoZIhvcNAQcCoIIDHjCCAxoCAQExDTALBglghkgBZQMEAgEwCwYJKoZIhvcNAQcBoIIB
+gDCCAXwwggEioAMCAQICARUwCgYIKoZIzj0EAwIwHDEaMBgGA1UEAwwRcGVtcGFsIGNtcyBzaWdu
+ZXIwIBcNMjYxMDE5MDQyNzA2WhgPMjEyNjA5MjUwNDI3MDZaMBwxGjAYBgNVBAMMEXBlbXBhbCBj
+bXMgc2lnbmVyMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEdXFdq0YLXrBYVJANLNRE3GmFST1P
+Nvzc9EzieqynEuIqqtgOUxQyyiUo2XX+G3/ukiO5fNNW2Y7i422/t+f9EaNTMFEwHQYDVR0OBBYE
+FFQ0Us6HdVu8P7mQtt6zozEJq6dkMB8GA1UdIwQYMBaAFFQ0Us6HdVu8P7mQtt6zozEJq6dkMA8G
+A1UdEwEB/wQFMAMBAf8wCgYIKoZIzj0EAwIDSAAwRQIhAIQfXjuHaPvr06AbhbxmLuPiUlM50AyM
+Wt+ZxxSJmVZEAiBAfa9y7IKb+sCFIhAtsZ0k7qT+dLIG0/kwZFxz5gE8ujGCAXMwggFvAgEBMCEw
+HDEaMBgGA1UEAwwRcGVtcGFsIGNtcyBzaWduZXICARUwCwYJYIZIAWUDBAIBoIHkMBgGCSqGSIb3
+DQEJAzELBgkqhkiG9w0BBwEwHAYJKoZIhvcNAQkFMQ8XDTI2MTAxOTA0MjcwNlowLwYJKoZIhvcN
+AQkEMSIEIJ9Py6Eyu6mEI2BkW9VebokUKTGtpkl2cU1dwxdBQYqAMHkGCSqGSIb3DQEJDzFsMGow
+CwYJYIZIAWUDBAEqMAsGCWCGSAFlAwQBFjALBglghkgBZQMEAQIwCgYIKoZIhvcNAwcwDgYIKoZI
+hvcNAwICAgCAMA0GCCqGSIb3DQMCAgFAMAcGBSsOAwIHMA0GCCqGSIb3DQMCAgEoMAoGCCqGSM49
+BAMCBEcwRQIgacShMgHq8hXRJuRiT0UjdLk9XPUptGcflHnTl5qTVZ4CIQCpwfFID2aZwhvZSz1c
+YS8BJNkCkDKSA+QpMVjUE96MiQ==`
+	testSignedStream = `MIAGCSqGSIb3DQEHAqCAMIACAQExDTALBglghkgBZQMEAgEwgAYJKoZIhvcNAQcBoIAkgAQZdGhl
+IGNvbnRlbnQgYmVpbmcgc2lnbmVkCgAAAAAAAKCCAYAwggF8MIIBIqADAgECAgEVMAoGCCqGSM49
+BAMCMBwxGjAYBgNVBAMMEXBlbXBhbCBjbXMgc2lnbmVyMCAXDTI2MTAxOTA0MjcwNloYDzIxMjYw
+OTI1MDQyNzA2WjAcMRowGAYDVQQDDBFwZW1wYWwgY21zIHNpZ25lcjBZMBMGByqGSM49AgEGCCqG
+SM49AwEHA0IABHVxXatGC16wWFSQDSzURNxphUk9Tzb83PRM4nqspxLiKqrYDlMUMsolKNl1/ht/
+7pIjuXzTVtmO4uNtv7fn/RGjUzBRMB0GA1UdDgQWBBRUNFLOh3VbvD+5kLbes6MxCaunZDAfBgNV
+HSMEGDAWgBRUNFLOh3VbvD+5kLbes6MxCaunZDAPBgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMC
+A0gAMEUCIQCEH147h2j769OgG4W8Zi7j4lJTOdAMjFrfmccUiZlWRAIgQH2vcuyCm/rAhSIQLbGd
+JO6k/nSyBtP5MGRcc+YBPLoxggFzMIIBbwIBATAhMBwxGjAYBgNVBAMMEXBlbXBhbCBjbXMgc2ln
+bmVyAgEVMAsGCWCGSAFlAwQCAaCB5DAYBgkqhkiG9w0BCQMxCwYJKoZIhvcNAQcBMBwGCSqGSIb3
+DQEJBTEPFw0yNjEwMTkwNDI3MDZaMC8GCSqGSIb3DQEJBDEiBCCfT8uhMruphCNgZFvVXm6JFCkx
+raZJdnFNXcMXQUGKgDB5BgkqhkiG9w0BCQ8xbDBqMAsGCWCGSAFlAwQBKjALBglghkgBZQMEARYw
+CwYJYIZIAWUDBAECMAoGCCqGSIb3DQMHMA4GCCqGSIb3DQMCAgIAgDANBggqhkiG9w0DAgIBQDAH
+BgUrDgMCBzANBggqhkiG9w0DAgIBKDAKBggqhkjOPQQDAgRHMEUCIHbhELfeofKeGl2Sofj4QxcF
+tbWu80+sBw8G4Lx0Th8UAiEA4YMmRHVKsqIxzN6ML+uj8TmhLCG5u73drpCST3vvDMAAAAAAAAA=`
+	testSignedPSS = `MIIF5QYJKoZIhvcNAQcCoIIF1jCCBdICAQExDTALBglghkgBZQMEAgIwKAYJKoZIhvcNAQcBoBsE
+GXRoZSBjb250ZW50IGJlaW5nIHNpZ25lZAqgggMUMIIDEDCCAfigAwIBAgIBFjANBgkqhkiG9w0B
+AQsFADAgMR4wHAYDVQQDDBVwZW1wYWwgY21zIHJzYSBzaWduZXIwIBcNMjYxMDE5MDQyNzExWhgP
+MjEyNjA5MjUwNDI3MTFaMCAxHjAcBgNVBAMMFXBlbXBhbCBjbXMgcnNhIHNpZ25lcjCCASIwDQYJ
+KoZIhvcNAQEBBQADggEPADCCAQoCggEBAK3OH7VpGja4Ve/60huWaDpRcM5Td9QJV2Cogss3c1EO
+ePQmc6N32GJFLPwEHlTnM3w8zOIhZL4WI2L5mUHTR1Qbm4McyBzWqwHDq0O6BMWpCY0/JC+tLA2f
+1ARSx1reLCYAlrs3i6LL1TO+d2XP94u4gNr/iQodMXoFflVDaJ5hl6Jmy2Nl3EQOUBVkfOnRyUc/
+wV26cw7DYHavSINHchgQ4xzJCeEN66JhZFsGkYodqc7+JGUZBUep/74feAGmirSkmq4rXk3G1m1v
+Zcv9iAIip7HfqUEaZ4qkUTWv32cE/S5OTiUTxbM+6E4CMkYMfXiwFhDNUTSwP8g0N0ZKzWUCAwEA
+AaNTMFEwHQYDVR0OBBYEFJfAOxBjxp/YtlY5tDu1fT3C8UzIMB8GA1UdIwQYMBaAFJfAOxBjxp/Y
+tlY5tDu1fT3C8UzIMA8GA1UdEwEB/wQFMAMBAf8wDQYJKoZIhvcNAQELBQADggEBAJQEUYD2kpik
+qdepLIp9I4XRTEPXhla7LPQ/T38AH2hH+eu480stB7gOH6+rgLLCSJOyUkdDsrTOhAufVg/7JzGG
+mDkewWfYoKCAoi853dWcNFLssci2hkTnHwq3N3lXfRwfRzOqnKSceZ5OEfI4Mo8btvmYJF+Geeet
+mLyLRGADvQx5o0MDWcxm6eRG64Kj/R4EG6D8jUwF0Tnl//wVgij0XTR59IAma/VeYNYoYJ8KOFkW
+8pmdFh5yC0pak59w51Vv1r79B0KWpoob2t0+RQLsoYnc5ja0kDBtGZ0xOCsHqN5jErhSnzf1/2lM
+0CRF6xEzwtZRE+5zfvvmjEGheJExggJ6MIICdgIBATAlMCAxHjAcBgNVBAMMFXBlbXBhbCBjbXMg
+cnNhIHNpZ25lcgIBFjALBglghkgBZQMEAgKggfQwGAYJKoZIhvcNAQkDMQsGCSqGSIb3DQEHATAc
+BgkqhkiG9w0BCQUxDxcNMjYxMDE5MDQyNzExWjA/BgkqhkiG9w0BCQQxMgQwCeYE+Iu8DMorV4hM
+jTP6/Vs7NCbvGUcetp/BDlupAOBBlHCy/07WvLJo1FVkLrrgMHkGCSqGSIb3DQEJDzFsMGowCwYJ
+YIZIAWUDBAEqMAsGCWCGSAFlAwQBFjALBglghkgBZQMEAQIwCgYIKoZIhvcNAwcwDgYIKoZIhvcN
+AwICAgCAMA0GCCqGSIb3DQMCAgFAMAcGBSsOAwIHMA0GCCqGSIb3DQMCAgEoMEIGCSqGSIb3DQEB
+CjA1oA8wDQYJYIZIAWUDBAICBQChHDAaBgkqhkiG9w0BAQgwDQYJYIZIAWUDBAICBQCiBAICAM4E
+ggEAasSRZhbi2f1L4A6qFmyliC3qdo12csFY784agNuKh5bWIhqitZTrepvqCu2T1y+lUbXDahYX
+38ftjwWYE49PHItd9MJw3HRMrQmJWEBdADYczLFGv3BNspuqu24tldG23JCN8BQyNzRpLvqXzJdf
+fnNFugbpDHXnrGF9yfV4SVQCXmSwDdGHVPF8/D5MehXX0hYCOBCdpIfdyJ7iO18WjrWAzkwAdY43
+a/oQacYvWB76xjtGbsNfTS4dM2P7+uH2oScXpicVuDNM1hjUfVliewjrM1MpEB1/Co1JkhEcabfe
+qG6oaqqo6dDQq5km3C4tQEd0HcB6RPzoqliiWR0ieg==`
+)
+
+func TestVerifyOpenSSL(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		der      string
+		detached bool
+		signer   string
+	}{
+		{"attached", testSignedAttached, false, "pempal cms signer"},
+		{"detached", testSignedDetached, true, "pempal cms signer"},
+		{"stream", testSignedStream, false, "pempal cms signer"},
+		{"pss", testSignedPSS, false, "pempal cms rsa signer"},
+	} {
+		der, err := base64.StdEncoding.DecodeString(tc.der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sd, err := ParseSignedData(der)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if sd.IsDetached() != tc.detached {
+			t.Fatalf("%s: expected detached %v", tc.name, tc.detached)
+		}
+		var content io.Reader
+		if tc.detached {
+			content = strings.NewReader(testContent)
+		} else if string(sd.Content) != testContent {
+			t.Fatalf("%s: unexpected content %q", tc.name, sd.Content)
+		}
+		certs, err := sd.Verify(content)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(certs) != 1 || certs[0].Subject.CommonName != tc.signer {
+			t.Errorf("%s: expected signer %s", tc.name, tc.signer)
+		}
+		if _, err := sd.Verify(strings.NewReader("other content")); err == nil {
+			t.Errorf("%s: expected other content to fail verification", tc.name)
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	content := []byte("the content being signed\n")
+	for _, sa := range []x509.SignatureAlgorithm{
+		x509.SHA256WithRSA, x509.SHA384WithRSAPSS, x509.ECDSAWithSHA256, x509.PureEd25519,
+	} {
+		for _, detached := range []bool{false, true} {
+			signer := testSigner(t, sa)
+			der, err := Sign(bytes.NewReader(content), signer, detached)
+			if err != nil {
+				t.Fatalf("%s: %v", sa, err)
+			}
+			sd, err := ParseSignedData(der)
+			if err != nil {
+				t.Fatalf("%s: %v", sa, err)
+			}
+			if sd.IsDetached() != detached {
+				t.Fatalf("%s: expected detached %v", sa, detached)
+			}
+			if !detached && !bytes.Equal(sd.Content, content) {
+				t.Fatalf("%s: unexpected content %q", sa, sd.Content)
+			}
+			if len(sd.Certificates) != 1 || !sd.Certificates[0].Equal(signer.Certificate) {
+				t.Fatalf("%s: expected signer certificate", sa)
+			}
+			var r *bytes.Reader
+			if detached {
+				r = bytes.NewReader(content)
+			}
+			certs, err := verify(sd, r)
+			if err != nil {
+				t.Fatalf("%s: %v", sa, err)
+			}
+			if len(certs) != 1 || !certs[0].Equal(signer.Certificate) {
+				t.Fatalf("%s: expected signer certificate to be returned", sa)
+			}
+			if _, err := sd.Verify(bytes.NewReader([]byte("other content"))); err == nil {
+				t.Fatalf("%s: expected other content to fail verification", sa)
+			}
+		}
+	}
+}
+
+func verify(sd *SignedData, r *bytes.Reader) ([]*x509.Certificate, error) {
+	if r == nil {
+		return sd.Verify(nil)
+	}
+	return sd.Verify(r)
+}
+
+func TestEncodeCertificates(t *testing.T) {
+	certs := []*x509.Certificate{
+		testSigner(t, x509.ECDSAWithSHA256).Certificate,
+		testSigner(t, x509.PureEd25519).Certificate,
+	}
+	der, err := EncodeCertificates(certs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := ParseSignedData(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sd.IsCertificatesOnly() {
+		t.Fatal("expected certificates only signed data")
+	}
+	if len(sd.Certificates) != len(certs) {
+		t.Fatalf("expected %d certificates, found %d", len(certs), len(sd.Certificates))
+	}
+	for i, c := range certs {
+		if !sd.Certificates[i].Equal(c) {
+			t.Fatalf("unexpected certificate %d", i)
+		}
+	}
+}
+
+func testSigner(t *testing.T, sa x509.SignatureAlgorithm) Signer {
+	var key crypto.Signer
+	var err error
+	switch sa {
+	case x509.SHA256WithRSA, x509.SHA384WithRSAPSS:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case x509.ECDSAWithSHA256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Signer{Certificate: cert, Key: key, SignatureAlgorithm: sa}
+}
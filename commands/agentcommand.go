@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/eurozulu/pempal/agent"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/repositories"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// AgentCommand runs the key agent, which holds decrypted private keys in memory and signs with them.
+// Once a key is added to the agent, its passphrase is no longer required to sign with it,
+// until the key times out or the agent is stopped.
+// The agent listens on the socket in PP_AGENT_SOCK, or in XDG_RUNTIME_DIR or a user specific temp directory when not set.
+// The directory of the socket must be owned by, and only accessible to, the current user.
+// @Command(agent)
+type AgentCommand struct {
+	// Timeout is the duration keys are held by the agent, e.g. 30m or 8h.  Defaults to 1h.
+	// Zero holds keys until the agent stops.
+	// @Flag(timeout, t)
+	Timeout string
+
+	// Socket is the path of the agents socket, overriding the default socket path.
+	// @Flag(socket, s)
+	Socket string
+}
+
+// RunAgent runs the key agent in the foreground, until it is interrupted or stopped.
+// @Action
+func (cmd AgentCommand) RunAgent() error {
+	ctx, cnl := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cnl()
+	path := cmd.socketPath()
+	fmt.Printf("%s=%s; export %s\n", agent.ENV_PP_AGENT_SOCK, path, agent.ENV_PP_AGENT_SOCK)
+	return (&agent.Agent{}).Serve(ctx, path)
+}
+
+// AddKeys adds the keys in the given path(s) to the agent.  When no path is given, the key path is used.
+// Encrypted keys are decrypted as they are added, prompting for their passphrase when required.
+// @Action(add, a)
+func (cmd AgentCommand) AddKeys(paths ...string) (string, error) {
+	c, err := cmd.client()
+	if err != nil {
+		return "", err
+	}
+	path := config.KeyPath()
+	if len(paths) > 0 {
+		path = strings.Join(paths, string(os.PathListSeparator))
+	}
+	timeout := time.Hour
+	if cmd.Timeout != "" {
+		if timeout, err = time.ParseDuration(cmd.Timeout); err != nil {
+			return "", fmt.Errorf("invalid timeout %q  %v", cmd.Timeout, err)
+		}
+	}
+	added, err := repositories.Keys(path).AddToAgent(c, timeout)
+	if err != nil {
+		return "", err
+	}
+	return formatAgentKeys(added)
+}
+
+// ListKeys lists the keys held by the agent
+// @Action(list, l)
+func (cmd AgentCommand) ListKeys() (string, error) {
+	c, err := cmd.client()
+	if err != nil {
+		return "", err
+	}
+	keys, err := c.List()
+	if err != nil {
+		return "", err
+	}
+	return formatAgentKeys(keys)
+}
+
+// RemoveKeys removes the keys of the given fingerprints from the agent.  When no fingerprint is given, all keys are removed.
+// @Action(remove, rm)
+func (cmd AgentCommand) RemoveKeys(fingerprints ...string) error {
+	c, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	if len(fingerprints) == 0 {
+		return c.Remove("")
+	}
+	for _, fp := range fingerprints {
+		if err := c.Remove(fp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopAgent stops the running agent, discarding all of its keys.
+// @Action(stop)
+func (cmd AgentCommand) StopAgent() error {
+	c, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	return c.Stop()
+}
+
+func (cmd AgentCommand) socketPath() string {
+	if cmd.Socket != "" {
+		return cmd.Socket
+	}
+	return agent.SocketPath()
+}
+
+func (cmd AgentCommand) client() (agent.Client, error) {
+	c := agent.Client(cmd.socketPath())
+	if !c.IsRunning() {
+		return "", fmt.Errorf("no agent running on %s", c)
+	}
+	return c, nil
+}
+
+func formatAgentKeys(keys []*agent.KeyInfo) (string, error) {
+	buf := bytes.NewBuffer(nil)
+	for _, k := range keys {
+		puk := &model.PublicKey{}
+		if err := puk.UnmarshalBinary(k.PublicKey); err != nil {
+			return "", err
+		}
+		expires := "never"
+		if !k.Expires.IsZero() {
+			expires = k.Expires.Format(time.RFC3339)
+		}
+		fmt.Fprintf(buf, "%s\t%s\texpires %s\n", k.Fingerprint, puk.PublicKeyAlgorithm(), expires)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"encoding/pem"
+	"fmt"
+	"github.com/eurozulu/pempal/factories"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
+	"os"
+)
+
+// CombineCommand recreates a private key from the shares made by the split command.
+// @Command(combine)
+type CombineCommand struct {
+	// Save, when set, saves the recreated key into the key path, rather than outputting it.
+	// @Flag(save, s)
+	Save bool
+
+	// Encrypt, when set, encrypts the recreated key with a new passphrase.
+	// @Flag(encrypt, e)
+	Encrypt bool
+}
+
+// CombineShares recreates the key from the share files given.
+// Each file may contain one or more shares, as pem blocks.  At least the threshold number of shares must be given.
+// @Action
+func (cmd CombineCommand) CombineShares(path string, paths ...string) (string, error) {
+	var shares []*model.KeyShare
+	for _, p := range append([]string{path}, paths...) {
+		s, err := readKeyShares(p)
+		if err != nil {
+			return "", err
+		}
+		shares = append(shares, s...)
+	}
+	prk, err := model.CombineKeyShares(shares)
+	if err != nil {
+		return "", err
+	}
+	if cmd.Encrypt {
+		pass, err := passphrases.DefaultProvider.Passphrase(fmt.Sprintf("key %s", prk.Public().Fingerprint()), true)
+		if err != nil {
+			return "", err
+		}
+		prk.SetPassphrase(pass)
+	}
+	if cmd.Save {
+		if err := factories.SaveResource(prk); err != nil {
+			return "", err
+		}
+		return prk.String(), nil
+	}
+	data, err := prk.MarshalText()
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func readKeyShares(path string) ([]*model.KeyShare, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var shares []*model.KeyShare
+	for blk, rest := pem.Decode(data); blk != nil; blk, rest = pem.Decode(rest) {
+		if blk.Type != model.PemTypeKeyShare {
+			continue
+		}
+		s, err := model.NewKeyShareFromPem(blk)
+		if err != nil {
+			return nil, fmt.Errorf("invalid share in %s  %v", path, err)
+		}
+		shares = append(shares, s)
+	}
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("no key shares found in %s", path)
+	}
+	return shares, nil
+}
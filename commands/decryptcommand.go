@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/eurozulu/pempal/cms"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/repositories"
+	"github.com/eurozulu/pempal/tools"
+	"io"
+	"os"
+)
+
+// DecryptCommand decrypts CMS enveloped data, made by the encrypt command or other CMS tools.
+// The private key of one of the recipients must be in the key path.
+// @Command(decrypt)
+type DecryptCommand struct {
+	// Out when set, writes the decrypted content to the given file path, rather than outputting it.
+	// @Flag(out, o)
+	Out string
+
+	// Force when set, allows an existing file to be overwritten when using -out.
+	// @Flag(force, f)
+	Force bool
+}
+
+// Decrypt decrypts the given file.  Use '-' to decrypt stdin.
+// @Action
+func (cmd DecryptCommand) Decrypt(path string) (string, error) {
+	if cmd.Out != "" && !cmd.Force && tools.IsPathExists(cmd.Out) {
+		return "", fmt.Errorf("%s already exists. Use -force to overwrite it", cmd.Out)
+	}
+	in, err := openInput(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return "", err
+	}
+	ed, err := cms.ReadEnvelopedData(data)
+	if err != nil {
+		return "", err
+	}
+	content, err := decryptEnvelopedData(ed)
+	if err != nil {
+		return "", err
+	}
+	if cmd.Out == "" {
+		return string(content), nil
+	}
+	if err := os.WriteFile(cmd.Out, content, 0600); err != nil {
+		return "", err
+	}
+	return cmd.Out, nil
+}
+
+// decryptEnvelopedData decrypts with the key of the first recipient whose certificate and private key are known.
+func decryptEnvelopedData(ed *cms.EnvelopedData) ([]byte, error) {
+	certs := repositories.Certificates(config.SearchPath())
+	keyz := repositories.Keys(config.KeyPath())
+	for _, ri := range ed.Recipients {
+		found := certs.FindAll(func(c *model.Certificate) bool {
+			return ri.FindCertificate([]*x509.Certificate{(*x509.Certificate)(c)}) != nil
+		})
+		for _, c := range found {
+			prk, err := keyz.ByPublicKey(model.NewPublicKey(c.PublicKey))
+			if err != nil {
+				continue
+			}
+			content, err := ed.Decrypt(ri, prk.Private())
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt with key of %s  %v", c.Subject, err)
+			}
+			return content, nil
+		}
+	}
+	return nil, fmt.Errorf("no private key found for any of the %d recipients", len(ed.Recipients))
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/eurozulu/pempal/cms"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/tools"
+	"os"
+	"strings"
+)
+
+// EncryptCommand encrypts a file, or stdin, to the holders of one or more certificates, as CMS enveloped data.
+// Only the holders of the private keys of those certificates can decrypt it.
+// @Command(encrypt)
+type EncryptCommand struct {
+	// Recipients is a comma delimited list of the certificates to encrypt to,
+	// by their common name, email address or fingerprint.
+	// When more than one current certificate matches, the one expiring last is used.
+	// @Flag(recipients, r)
+	Recipients string
+
+	// Out when set, writes the encrypted data to the given file path, rather than outputting it.
+	// @Flag(out, o)
+	Out string
+
+	// Force when set, allows an existing file to be overwritten when using -out.
+	// @Flag(force, f)
+	Force bool
+}
+
+// Encrypt encrypts the given file.  Use '-' to encrypt stdin.
+// @Action
+func (cmd EncryptCommand) Encrypt(path string) (string, error) {
+	if cmd.Recipients == "" {
+		return "", fmt.Errorf("no recipients given. Use -recipients to name the certificates to encrypt to")
+	}
+	var recipients []*x509.Certificate
+	for _, r := range strings.Split(cmd.Recipients, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		cert, err := resolveRecipient(r)
+		if err != nil {
+			return "", err
+		}
+		recipients = append(recipients, (*x509.Certificate)(cert))
+	}
+	if cmd.Out != "" && !cmd.Force && tools.IsPathExists(cmd.Out) {
+		return "", fmt.Errorf("%s already exists. Use -force to overwrite it", cmd.Out)
+	}
+
+	in, err := openInput(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	der, err := cms.Encrypt(in, recipients...)
+	if err != nil {
+		return "", err
+	}
+	text := pem.EncodeToMemory(&pem.Block{Type: cms.PemTypeCMS, Bytes: der})
+	if cmd.Out == "" {
+		return string(text), nil
+	}
+	if err := os.WriteFile(cmd.Out, text, 0644); err != nil {
+		return "", err
+	}
+	return cmd.Out, nil
+}
+
+// resolveRecipient finds the current certificate, of the given user, which may be used for encryption.
+// Certificates with a key usage which excludes key encipherment or key agreement are ignored.
+func resolveRecipient(user string) (*model.Certificate, error) {
+	certs := userCertificates(user)
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no current certificate found for recipient %q", user)
+	}
+	for _, c := range certs {
+		if c.KeyUsage == 0 || c.KeyUsage&(x509.KeyUsageKeyEncipherment|x509.KeyUsageKeyAgreement) != 0 {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("no certificate of recipient %q may be used for encryption", user)
+}
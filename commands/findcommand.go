@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	"context"
+	"encoding/pem"
 	"fmt"
 	"github.com/eurozulu/pempal/model"
 	"github.com/eurozulu/pempal/resourcefiles"
@@ -24,6 +25,12 @@ type FindCommand struct {
 	// Where specifies a simple expression to filter the results
 	// @Flag(where, w)
 	Where string
+
+	// Key when set, only finds resources with a public key matching the given fingerprint.
+	// Keys, certificates and requests are matched on their public key, including keys in
+	// OpenSSH authorized_keys, JWK and DER files.
+	// @Flag(key, k)
+	Key string
 }
 
 // ViewResources lists any pem reslurces found in the given path(s)
@@ -64,7 +71,28 @@ func (cmd FindCommand) ViewResources(path string, paths ...string) (string, erro
 }
 
 func (cmd FindCommand) buildFilter() resourcefiles.PemFileFilter {
-	return nil
+	if cmd.Key == "" {
+		return nil
+	}
+	return func(file *model.PemFile) *model.PemFile {
+		var blocks []*pem.Block
+		for _, blk := range file.Blocks {
+			resz := (&model.PemFile{Path: file.Path, Blocks: []*pem.Block{blk}}).Resources()
+			if len(resz) == 0 {
+				continue
+			}
+			puk, err := model.PublicKeyOf(resz[0])
+			if err != nil || !puk.Fingerprint().Match(strings.ToLower(cmd.Key)) {
+				continue
+			}
+			blocks = append(blocks, blk)
+		}
+		if len(blocks) == 0 {
+			return nil
+		}
+		file.Blocks = blocks
+		return file
+	}
 }
 
 func addToTotals(pf *model.PemFile, counts map[string]int) {
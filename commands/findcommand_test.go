@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/resourcefiles"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFindKeyMatchesPublicKeyEncodings(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pem files are found relative to the working directory
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	puk := model.NewPublicKey(key.Public())
+	authorized, err := puk.MarshalAuthorizedKey("me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := model.NewJWK(puk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwkData, err := json.Marshal(jwk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := puk.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherDer, err := model.NewPublicKey(other.Public()).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"key.pub":   authorized,
+		"key.jwk":   jwkData,
+		"key.der":   der,
+		"key.pem":   pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		"other.pem": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: otherDer}),
+	}
+	for path, data := range files {
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := FindCommand{Key: strings.ToUpper(puk.Fingerprint().String())}
+	found := map[string]bool{}
+	for pf := range resourcefiles.PemFiles(".").Find(context.Background(), cmd.buildFilter()) {
+		found[pf.Path] = true
+	}
+	for _, path := range []string{"key.pub", "key.jwk", "key.der", "key.pem"} {
+		if !found[path] {
+			t.Errorf("expected %s to match the key", path)
+		}
+	}
+	if found["other.pem"] {
+		t.Errorf("expected other.pem not to match the key")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/eurozulu/pempal/config"
 	"github.com/eurozulu/pempal/factories"
+	"github.com/eurozulu/pempal/model"
 	"github.com/eurozulu/pempal/repositories"
 	"github.com/eurozulu/pempal/templates"
 	"github.com/eurozulu/pempal/tools"
@@ -25,6 +26,36 @@ type MakeCommand struct {
 	// Save when set will save the new resource into the PKI repository.
 	// @Flag(save, s)
 	Save bool
+
+	// CrossSign when set should be the fingerprint (or unique partial fingerprint) of an existing CA certificate.
+	// The subject, public key and extensions of that certificate are re-issued under the issuer given in the templates or flags.
+	// The issuer is resolved by name in the same manner as a new certificate.
+	// @Flag(cross-sign, x)
+	CrossSign string
+
+	// Manifest when set should be the path to a manifest file, describing multiple resources to make.
+	// Each resource in the manifest is made, in dependency order, and saved.
+	// When names are given, only the manifest resources with those names, and their dependencies, are made.
+	// @Flag(manifest, m)
+	Manifest string
+
+	// Plan when set, merges and validates the templates, resolving the key, issuer and signature algorithm,
+	// and displays what would be made and where it would be saved.  Nothing is signed or written.
+	// @Flag(plan, p)
+	Plan bool
+
+	// Out when set, writes the new resource(s) to the given file path, rather than saving into the PKI repository.
+	// The path may contain macros, which are expanded using the properties of the merged template.
+	// e.g. -out "private/{{ .subject.common-name }}"
+	// In addition to the template properties, each resource has a '.fingerprint' and '.resource-type' property.
+	// When a key is made with a certificate or CSR, each is written to its own file, separated by their file extension.
+	// Encrypted keys are written with a '.pub' file of their public key, so they are found without decrypting them.
+	// @Flag(out, o)
+	Out string
+
+	// Force when set, allows existing files to be overwritten when using -out.
+	// @Flag(force, f)
+	Force bool
 }
 
 // Create generates a new resource witht he resulting template from merging the given named templates
@@ -32,19 +63,31 @@ type MakeCommand struct {
 // returns either the PEM encoded resource or, when Save is set, the fingerprint of the new resource
 // @Action
 func (cmd MakeCommand) Create(args ...string) (string, error) {
+	if cmd.Manifest != "" {
+		return cmd.createManifest(args...)
+	}
 	argFlags, argz, err := ArgFlagsToTemplate(args)
 	if err != nil {
 		return "", err
 	}
-	temps, err := templateRepo.ExpandedByName(argz...)
-	if err != nil {
-		return "", err
+	var temps []templates.Template
+	if len(argz) > 0 || cmd.CrossSign == "" {
+		temps, err = templateRepo.ExpandedByName(argz...)
+		if err != nil {
+			return "", err
+		}
+	}
+	if cmd.CrossSign != "" {
+		temps, err = crossSignTemplates(cmd.CrossSign, temps)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	if argFlags.String() != "" {
 		temps = append(temps, argFlags)
 	}
-	if cmd.Key != "" {
+	if cmd.Key != "" && cmd.CrossSign == "" {
 		puk, err := resolveKey(cmd.Key)
 		if err != nil {
 			return "", err
@@ -59,11 +102,33 @@ func (cmd MakeCommand) Create(args ...string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if cmd.Plan {
+		p, err := factories.PlanMake(t)
+		if err != nil {
+			return "", err
+		}
+		if cmd.Out != "" {
+			if err := p.SetOutputPath(cmd.Out, t); err != nil {
+				return "", err
+			}
+		}
+		return p.String(), nil
+	}
 	resz, err := factories.Make(t)
 	if err != nil {
 		return "", err
 	}
 
+	if cmd.Out != "" {
+		paths, err := factories.OutputPaths(cmd.Out, t, resz...)
+		if err != nil {
+			return "", err
+		}
+		if err := factories.WriteResources(paths, cmd.Force, resz...); err != nil {
+			return "", err
+		}
+		return strings.Join(paths, "\n"), nil
+	}
 	if cmd.Save {
 		if err := factories.SaveResource(resz...); err != nil {
 			return "", err
@@ -93,3 +158,78 @@ func resolveKey(fingerprint string) ([]byte, error) {
 	}
 	return keyz[0].Public().MarshalText()
 }
+
+func (cmd MakeCommand) createManifest(names ...string) (string, error) {
+	m, err := factories.LoadManifest(cmd.Manifest)
+	if err != nil {
+		return "", err
+	}
+	if cmd.Plan {
+		return planManifest(m, names...)
+	}
+	results, err := m.Make(cmd.Force, names...)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, r := range results {
+		for _, res := range r.Resources {
+			lines = append(lines, strings.Join([]string{r.Name, res.String()}, "\t"))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// planManifest lists the manifest resources in the order they would be made.
+// As resources may depend on others in the manifest, which do not yet exist, only the order and templates are shown.
+func planManifest(m *factories.Manifest, names ...string) (string, error) {
+	ordered, err := m.Ordered(names...)
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.NewBuffer(nil)
+	for i, mr := range ordered {
+		fmt.Fprintf(buf, "%d\t%s\t%s", i+1, mr.Name, strings.Join(mr.Templates, " "))
+		if mr.Out != "" {
+			fmt.Fprintf(buf, "\t-> %s", mr.Out)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String(), nil
+}
+
+// crossSignTemplates places the cross sign template of the given certificate as the base template.
+// Any certificate base template in the given templates is replaced by it.
+func crossSignTemplates(fingerprint string, temps []templates.Template) ([]templates.Template, error) {
+	cert, err := resolveCertificate(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := factories.NewCrossSignTemplate(cert)
+	if err != nil {
+		return nil, err
+	}
+	found := []templates.Template{ct}
+	for _, t := range temps {
+		if templates.IsBaseTemplate(t) {
+			if _, ok := t.(*templates.CertificateTemplate); !ok {
+				return nil, fmt.Errorf("can not cross sign using a %s template", templates.TypeOfBaseTemplate(t))
+			}
+			continue
+		}
+		found = append(found, t)
+	}
+	return found, nil
+}
+
+func resolveCertificate(fingerprint string) (*model.Certificate, error) {
+	certs := repositories.Certificates(config.SearchPath()).MatchByFingerPrint(fingerprint)
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%q certificate not found", fingerprint)
+	}
+	if len(certs) > 1 {
+		fpz := strings.Join(tools.StringerToString(certs...), ", ")
+		return nil, fmt.Errorf("%q certificate matches multiple certificates: %s", fingerprint, fpz)
+	}
+	return certs[0], nil
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/repositories"
+	"os"
+	"strings"
+)
+
+// NormaliseCommand re-saves private keys, encoded as PKCS#1, SEC1 or OpenSSH, as PKCS#8
+// @Command(normalise)
+type NormaliseCommand struct {
+	// Check, when set, lists the key files which are not normalised, without changing them.
+	// @Flag(check, c)
+	Check bool
+}
+
+// NormaliseKeys rewrites the key files found in the given path(s) so all keys are PKCS#8 encoded.
+// Encrypted keys remain encrypted, using the same passphrase.
+// When no path is given, the key path is used.
+// @Action
+func (cmd NormaliseCommand) NormaliseKeys(paths ...string) (string, error) {
+	path := config.KeyPath()
+	if len(paths) > 0 {
+		path = strings.Join(paths, string(os.PathListSeparator))
+	}
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+	found, err := repositories.Keys(path).Normalise(ctx, cmd.Check)
+	if err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "all keys are normalised\n", nil
+	}
+	action := "normalised"
+	if cmd.Check {
+		action = "not normalised"
+	}
+	buf := strings.Builder{}
+	for _, p := range found {
+		buf.WriteString(fmt.Sprintf("%s\t%s\n", action, p))
+	}
+	return buf.String(), nil
+}
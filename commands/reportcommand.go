@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/reports"
+	"github.com/eurozulu/pempal/resourcefiles"
+	"github.com/eurozulu/pempal/tools"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReportCommand produces an inventory report of the repository, as a self contained html or markdown document.
+// The report lists the CA hierarchy, certificates by issuer, an expiry timeline, revoked certificates
+// and any keys or requests with no certificate.
+// The dot format outputs the issuer to subject graph of the certificates, requests and CRLs, as a Graphviz DOT graph.
+// @Command(report)
+type ReportCommand struct {
+	// Format is the document format, html, markdown or dot. Defaults to html
+	// @Flag(format)
+	Format string
+
+	// Days is the number of days before expiry, a certificate is reported as expiring. Defaults to 30
+	// @Flag(days, d)
+	Days int
+
+	// Out is the file path to write the report to.
+	// When not set, the report is output.
+	// @Flag(out, o)
+	Out string
+
+	// Force when set, allows an existing file to be overwritten.
+	// @Flag(force, f)
+	Force bool
+}
+
+// Report produces the report of the resources in the repository search path.
+// optionally, paths may be given, seperated with a space, to report on in place of the repository.
+// @Action
+func (cmd ReportCommand) Report(paths ...string) (string, error) {
+	if cmd.Out != "" && !cmd.Force && tools.IsPathExists(cmd.Out) {
+		return "", fmt.Errorf("%s already exists. Use -force to overwrite it", cmd.Out)
+	}
+	path := config.SearchPath()
+	if len(paths) > 0 {
+		path = strings.Join(paths, string(filepath.ListSeparator))
+	}
+	format := cmd.Format
+	if format == "" {
+		format = reports.FormatHTML
+	}
+	days := cmd.Days
+	if days == 0 {
+		days = 30
+	}
+
+	var files []*model.PemFile
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+	for pemFile := range resourcefiles.PemFiles(path).Find(ctx) {
+		files = append(files, pemFile)
+	}
+	inv := reports.NewInventory(files, time.Now(), days)
+
+	buf := bytes.NewBuffer(nil)
+	if err := reports.WriteReport(buf, format, inv); err != nil {
+		return "", err
+	}
+	if cmd.Out == "" {
+		return buf.String(), nil
+	}
+	if err := os.WriteFile(cmd.Out, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("report of %d certificates written to %s", len(inv.Certificates), cmd.Out), nil
+}
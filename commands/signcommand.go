@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/eurozulu/pempal/cms"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/repositories"
+	"github.com/eurozulu/pempal/tools"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SignCommand signs a file, or stdin, with the key of a user certificate, producing a detached signature.
+// The signature is a SIGNATURE pem, followed by the signers certificate and its issuers, or with -cms, a CMS pem.
+// @Command(sign)
+type SignCommand struct {
+	// User identifies the certificate to sign with, by its common name, email address or fingerprint.
+	// When more than one current certificate matches, the one expiring last is used.
+	// @Flag(user, u)
+	User string
+
+	// SignatureAlgorithm, when set, is the algorithm to sign with.  Defaults to the algorithm for the signers key.
+	// @Flag(signature-algorithm, sa)
+	SignatureAlgorithm string
+
+	// CMS when set, outputs the signature as CMS signed data (PKCS#7), rather than a signature pem.
+	// @Flag(cms, c)
+	CMS bool
+
+	// Attached when set, includes the signed content in the CMS signed data.  Implies -cms
+	// @Flag(attached, a)
+	Attached bool
+
+	// Out when set, writes the signature to the given file path, rather than outputting it.
+	// @Flag(out, o)
+	Out string
+
+	// Force when set, allows an existing file to be overwritten when using -out.
+	// @Flag(force, f)
+	Force bool
+}
+
+// Sign signs the given file.  Use '-' to sign stdin.
+// @Action
+func (cmd SignCommand) Sign(path string) (string, error) {
+	if cmd.User == "" {
+		return "", fmt.Errorf("no signer given. Use -user to name the certificate to sign with")
+	}
+	cert, prk, err := resolveSigner(cmd.User)
+	if err != nil {
+		return "", err
+	}
+	var sa model.SignatureAlgorithm
+	if cmd.SignatureAlgorithm != "" {
+		if err := sa.UnmarshalText([]byte(cmd.SignatureAlgorithm)); err != nil {
+			return "", err
+		}
+	}
+	if sa, err = model.SignatureAlgorithmForKey(sa, cert.PublicKey); err != nil {
+		return "", err
+	}
+	if cmd.Out != "" && !cmd.Force && tools.IsPathExists(cmd.Out) {
+		return "", fmt.Errorf("%s already exists. Use -force to overwrite it", cmd.Out)
+	}
+
+	in, err := openInput(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	chain := repositories.Certificates(config.SearchPath()).Chain(cert)
+	var text []byte
+	if cmd.CMS || cmd.Attached {
+		text, err = signCMS(in, cert, prk, sa, chain, !cmd.Attached)
+	} else {
+		text, err = signPem(in, cert, prk, sa, chain)
+	}
+	if err != nil {
+		return "", err
+	}
+	if cmd.Out == "" {
+		return string(text), nil
+	}
+	if err := os.WriteFile(cmd.Out, text, 0644); err != nil {
+		return "", err
+	}
+	return cmd.Out, nil
+}
+
+func signPem(in io.Reader, cert *model.Certificate, prk *model.PrivateKey, sa model.SignatureAlgorithm, chain []*model.Certificate) ([]byte, error) {
+	data, err := model.SignStream(in, prk.Signer(), sa)
+	if err != nil {
+		return nil, err
+	}
+	sig := &model.Signature{
+		SignatureAlgorithm: sa,
+		Signer:             cert.Fingerprint(),
+		Chain:              append([]*model.Certificate{cert}, chain...),
+		Data:               data,
+	}
+	return sig.MarshalText()
+}
+
+// signCMS signs as CMS signed data, including the issuers of the certificate, other than its root.
+func signCMS(in io.Reader, cert *model.Certificate, prk *model.PrivateKey, sa model.SignatureAlgorithm, chain []*model.Certificate, detached bool) ([]byte, error) {
+	var certs []*x509.Certificate
+	for _, c := range chain {
+		if bytes.Equal(c.RawSubject, c.RawIssuer) {
+			continue
+		}
+		certs = append(certs, (*x509.Certificate)(c))
+	}
+	der, err := cms.Sign(in, cms.Signer{
+		Certificate:        (*x509.Certificate)(cert),
+		Key:                prk.Signer(),
+		SignatureAlgorithm: x509.SignatureAlgorithm(sa),
+	}, detached, certs...)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: cms.PemTypeCMS, Bytes: der}), nil
+}
+
+// resolveSigner finds the current certificate, with a private key, identified by the given user.
+func resolveSigner(user string) (*model.Certificate, *model.PrivateKey, error) {
+	certs := userCertificates(user)
+	if len(certs) == 0 {
+		return nil, nil, fmt.Errorf("no current certificate found for user %q", user)
+	}
+	keyz := repositories.Keys(config.KeyPath())
+	for _, c := range certs {
+		prk, err := keyz.ByPublicKey(model.NewPublicKey(c.PublicKey))
+		if err != nil {
+			continue
+		}
+		return c, prk, nil
+	}
+	return nil, nil, fmt.Errorf("no private key found for the certificates of user %q", user)
+}
+
+// userCertificates finds the current certificates identified by the given user, the latest expiring first.
+// The user is matched to the certificates fingerprint, common name or email addresses.
+func userCertificates(user string) []*model.Certificate {
+	now := time.Now()
+	certs := repositories.Certificates(config.SearchPath()).FindAll(func(c *model.Certificate) bool {
+		if now.Before(c.NotBefore) || now.After(c.NotAfter) {
+			return false
+		}
+		return c.Fingerprint().Match(user) ||
+			strings.EqualFold(c.Subject.CommonName, user) ||
+			slices.ContainsFunc(c.EmailAddresses, func(e string) bool {
+				return strings.EqualFold(e, user)
+			})
+	})
+	slices.SortFunc(certs, func(a, b *model.Certificate) int {
+		return b.NotAfter.Compare(a.NotAfter)
+	})
+	return certs
+}
+
+// openInput opens the given file path, or stdin when the path is '-'
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
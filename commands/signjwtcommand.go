@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/jws"
+	"github.com/eurozulu/pempal/repositories"
+	"github.com/eurozulu/pempal/tools"
+	"io"
+	"os"
+	"time"
+)
+
+// SignJWTCommand signs a json payload, from a file or stdin, as a JWS in its compact form, such as a JWT.
+// The signing algorithm is chosen from the type of the signers key, RS256, ES256, ES384, ES512 or EdDSA.
+// The 'x5c' header holds the signers certificate and its issuers, other than the root, and 'kid' the certificates fingerprint.
+// @Command(sign-jwt)
+type SignJWTCommand struct {
+	// User identifies the certificate to sign with, by its common name, email address or fingerprint.
+	// When more than one current certificate matches, the one expiring last is used.
+	// @Flag(user, u)
+	User string
+
+	// PSS when set, signs with PS256 rather than RS256, for RSA keys.
+	// @Flag(pss)
+	PSS bool
+
+	// Expires when set, is the duration the token is valid for, e.g. 15m or 24h.
+	// The 'iat' and 'exp' claims of the payload are set to now and now plus the duration.
+	// @Flag(expires, e)
+	Expires string
+
+	// Out when set, writes the token to the given file path, rather than outputting it.
+	// @Flag(out, o)
+	Out string
+
+	// Force when set, allows an existing file to be overwritten when using -out.
+	// @Flag(force, f)
+	Force bool
+}
+
+// SignJWT signs the json payload in the given file.  Use '-' to sign stdin.
+// @Action
+func (cmd SignJWTCommand) SignJWT(path string) (string, error) {
+	if cmd.User == "" {
+		return "", fmt.Errorf("no signer given. Use -user to name the certificate to sign with")
+	}
+	cert, prk, err := resolveSigner(cmd.User)
+	if err != nil {
+		return "", err
+	}
+	alg, err := jws.AlgorithmForKey(cert.PublicKey, cmd.PSS)
+	if err != nil {
+		return "", err
+	}
+	if cmd.Out != "" && !cmd.Force && tools.IsPathExists(cmd.Out) {
+		return "", fmt.Errorf("%s already exists. Use -force to overwrite it", cmd.Out)
+	}
+
+	in, err := openInput(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	payload, err := io.ReadAll(in)
+	if err != nil {
+		return "", err
+	}
+	payload = bytes.TrimSpace(payload)
+	if !jws.IsJSONObject(payload) {
+		return "", fmt.Errorf("payload of %s is not a json object", path)
+	}
+	if cmd.Expires != "" {
+		expires, err := time.ParseDuration(cmd.Expires)
+		if err != nil {
+			return "", fmt.Errorf("invalid expires %q  %v", cmd.Expires, err)
+		}
+		if payload, err = jws.SetExpiry(payload, time.Now(), expires); err != nil {
+			return "", err
+		}
+	}
+
+	header := jws.Header{
+		Algorithm: alg,
+		Type:      "JWT",
+		KeyID:     cert.Fingerprint().String(),
+		X5C:       []string{base64.StdEncoding.EncodeToString(cert.Raw)},
+	}
+	for _, c := range repositories.Certificates(config.SearchPath()).Chain(cert) {
+		if bytes.Equal(c.RawSubject, c.RawIssuer) {
+			continue
+		}
+		header.X5C = append(header.X5C, base64.StdEncoding.EncodeToString(c.Raw))
+	}
+	token, err := jws.Sign(payload, header, prk.Signer())
+	if err != nil {
+		return "", err
+	}
+	if cmd.Out == "" {
+		return token, nil
+	}
+	if err := os.WriteFile(cmd.Out, []byte(token+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return cmd.Out, nil
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/repositories"
+	"github.com/eurozulu/pempal/tools"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SplitCommand splits a private key into shares, so no single holder of a share holds the key.
+// Any 'threshold' of the shares can be combined, with the combine command, to recreate the key.
+// @Command(split)
+type SplitCommand struct {
+	// Shares is the number of shares to split the key into. Defaults to 5
+	// @Flag(shares, n)
+	Shares int
+
+	// Threshold is the number of shares required to recreate the key. Defaults to 3
+	// @Flag(threshold, m)
+	Threshold int
+
+	// Out is a directory into which each share is written as a separate file.
+	// When not set, the shares are output as pem blocks.
+	// @Flag(out, o)
+	Out string
+
+	// Force overwrites existing share files
+	// @Flag(force, f)
+	Force bool
+}
+
+// SplitKey splits the key of the given fingerprint into shares.
+// @Action
+func (cmd SplitCommand) SplitKey(fingerprint string) (string, error) {
+	keyz := repositories.Keys(config.KeyPath()).MatchByAnyFingerPrint(fingerprint)
+	if len(keyz) == 0 {
+		return "", fmt.Errorf("%q key not found", fingerprint)
+	}
+	if len(keyz) > 1 {
+		fpz := strings.Join(tools.StringerToString(keyz...), ", ")
+		return "", fmt.Errorf("%q key matches multiple keys: %s", fingerprint, fpz)
+	}
+	count, threshold := cmd.Shares, cmd.Threshold
+	if count == 0 {
+		count = 5
+	}
+	if threshold == 0 {
+		threshold = 3
+	}
+	shares, err := model.SplitPrivateKey(keyz[0], count, threshold)
+	if err != nil {
+		return "", err
+	}
+	if cmd.Out != "" {
+		return cmd.writeShares(shares)
+	}
+	buf := bytes.NewBuffer(nil)
+	for _, s := range shares {
+		data, err := s.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		buf.Write(data)
+	}
+	return buf.String(), nil
+}
+
+func (cmd SplitCommand) writeShares(shares []*model.KeyShare) (string, error) {
+	paths := make([]string, len(shares))
+	for i, s := range shares {
+		paths[i] = filepath.Join(cmd.Out, fmt.Sprintf("%s-share-%d-of-%d.pem", s.Key, s.Index, s.Count))
+		if !cmd.Force && tools.IsPathExists(paths[i]) {
+			return "", fmt.Errorf("%s already exists. Use -force to overwrite it", paths[i])
+		}
+	}
+	if err := os.MkdirAll(cmd.Out, 0700); err != nil {
+		return "", err
+	}
+	for i, s := range shares {
+		data, err := s.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(paths[i], data, 0600); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(paths, "\n"), nil
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"github.com/eurozulu/pempal/jks"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
+	"github.com/eurozulu/pempal/resourcefiles"
+	"github.com/eurozulu/pempal/tools"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TruststoreCommand builds a Java truststore of the CA certificates found in the given path(s).
+// Each certificate is a trusted certificate entry, aliased by its lower case common name.
+// The store password is the passphrase for the -out path.
+// @Command(truststore)
+type TruststoreCommand struct {
+	// Out is the file path to write the truststore to.
+	// @Flag(out, o)
+	Out string
+
+	// Force when set, allows an existing file to be overwritten.
+	// @Flag(force, f)
+	Force bool
+
+	// JCEKS when set, writes a JCEKS rather than a JKS truststore.
+	// @Flag(jceks)
+	JCEKS bool
+}
+
+// Truststore writes the CA certificates of the given path(s) into a truststore.
+// optionally additional paths may be given, seperated with a space.
+// @Action
+func (cmd TruststoreCommand) Truststore(path string, paths ...string) (string, error) {
+	if cmd.Out == "" {
+		return "", fmt.Errorf("no truststore file given. Use -out to name the file to write")
+	}
+	if !cmd.Force && tools.IsPathExists(cmd.Out) {
+		return "", fmt.Errorf("%s already exists. Use -force to overwrite it", cmd.Out)
+	}
+	paths = append([]string{path}, paths...)
+	path = strings.Join(paths, string(filepath.ListSeparator))
+
+	ks := &jks.KeyStore{JCEKS: cmd.JCEKS}
+	aliases := map[string]bool{}
+	fingerprints := map[model.Fingerprint]bool{}
+	now := time.Now()
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+	for pemFile := range resourcefiles.PemFiles(path).FindByType(ctx, model.ResourceTypeCertificate) {
+		for _, res := range pemFile.Resources() {
+			cert, ok := res.(*model.Certificate)
+			if !ok || !cert.IsCA || fingerprints[cert.Fingerprint()] {
+				continue
+			}
+			fingerprints[cert.Fingerprint()] = true
+			ks.TrustedCertificates = append(ks.TrustedCertificates, &jks.TrustedCertificateEntry{
+				Alias:       uniqueAlias(cert, aliases),
+				Created:     now,
+				Certificate: (*x509.Certificate)(cert),
+			})
+		}
+	}
+	if len(ks.TrustedCertificates) == 0 {
+		return "", fmt.Errorf("no CA certificates found in %s", strings.Join(paths, ", "))
+	}
+
+	pass, err := passphrases.DefaultProvider.Passphrase(cmd.Out, true)
+	if err != nil {
+		return "", err
+	}
+	data, err := jks.Encode(ks, string(pass))
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(cmd.Out, data, 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d CA certificates written to %s", len(ks.TrustedCertificates), cmd.Out), nil
+}
+
+// uniqueAlias gives the lower case common name of the certificate, or its fingerprint, suffixed when already used.
+func uniqueAlias(cert *model.Certificate, used map[string]bool) string {
+	alias := strings.ToLower(cert.Subject.CommonName)
+	if alias == "" {
+		alias = cert.Fingerprint().String()
+	}
+	unique := alias
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", alias, i)
+	}
+	used[unique] = true
+	return unique
+}
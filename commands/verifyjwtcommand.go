@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/jws"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/repositories"
+	"github.com/eurozulu/pempal/tools"
+	"io"
+	"time"
+)
+
+// VerifyJWTCommand verifies a JWS in its compact form, such as a JWT, and outputs its payload.
+// The signer is the first certificate of the 'x5c' header or, without it, the certificate with the 'kid' fingerprint.
+// The signer must be a certificate in the repository, whose chain is validated to a root in the repository.
+// When the payload has 'nbf' or 'exp' claims, the token must be valid now.
+// @Command(verify-jwt)
+type VerifyJWTCommand struct{}
+
+// VerifyJWT verifies the token in the given file, or the given token itself.  Use '-' to verify stdin.
+// @Action
+func (cmd VerifyJWTCommand) VerifyJWT(path string) (string, error) {
+	token := path
+	if path == "-" || tools.IsFileExists(path) {
+		in, err := openInput(path)
+		if err != nil {
+			return "", err
+		}
+		defer in.Close()
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return "", err
+		}
+		token = string(data)
+	}
+	j, err := jws.Parse(token)
+	if err != nil {
+		return "", err
+	}
+	x5c, err := j.Header.Certificates()
+	if err != nil {
+		return "", err
+	}
+	var fp model.Fingerprint
+	switch {
+	case len(x5c) > 0:
+		fp = (*model.Certificate)(x5c[0]).Fingerprint()
+	case j.Header.KeyID != "":
+		if fp, err = model.ParseFingerPrint(j.Header.KeyID); err != nil {
+			return "", fmt.Errorf("kid %q is not a certificate fingerprint", j.Header.KeyID)
+		}
+	default:
+		return "", fmt.Errorf("token has neither a x5c or kid header to identify its signer")
+	}
+	cert, err := repositories.Certificates(config.SearchPath()).ByFingerPrint(fp)
+	if err != nil {
+		return "", fmt.Errorf("signer certificate %s is not in the repository", fp)
+	}
+	if err := j.Verify(cert.PublicKey); err != nil {
+		return "", err
+	}
+	var inters []*model.Certificate
+	for _, c := range x5c {
+		inters = append(inters, (*model.Certificate)(c))
+	}
+	chain, err := verifySignerChain(cert, inters...)
+	if err != nil {
+		return "", err
+	}
+	if err := jws.CheckExpiry(j.Payload, time.Now()); err != nil {
+		return "", fmt.Errorf("signature is valid but %v", err)
+	}
+	return formatSignerChains(chain) + string(j.Payload), nil
+}
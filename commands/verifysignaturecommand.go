@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"github.com/eurozulu/pempal/cms"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/repositories"
+	"github.com/eurozulu/pempal/tools"
+	"io"
+	"os"
+)
+
+// VerifySignatureCommand verifies a detached signature, made by the sign command, or CMS signed data.
+// The signer must be a certificate in the repository, whose chain is validated to a root in the repository.
+// @Command(verify-signature)
+type VerifySignatureCommand struct {
+	// Signature is the path of the signature file.
+	// Defaults to the signed file path with a '.sig' or '.p7s' extension.
+	// When neither exist, the file itself is verified as CMS signed data with its content attached.
+	// @Flag(signature, s)
+	Signature string
+}
+
+// Verify verifies the signature of the given file.  Use '-' to verify stdin, with the -signature flag.
+// @Action
+func (cmd VerifySignatureCommand) Verify(path string) (string, error) {
+	sigPath := cmd.Signature
+	if sigPath == "" {
+		if path == "-" {
+			return "", fmt.Errorf("no signature given. Use -signature to verify stdin")
+		}
+		sigPath = path
+		for _, ext := range []string{".sig", ".p7s"} {
+			if tools.IsFileExists(path + ext) {
+				sigPath = path + ext
+				break
+			}
+		}
+	}
+	data, err := os.ReadFile(sigPath)
+	if err != nil {
+		return "", err
+	}
+	if sd, err := cms.ReadSignedData(data); err == nil {
+		var in io.ReadCloser
+		if sigPath != path {
+			if in, err = openInput(path); err != nil {
+				return "", err
+			}
+			defer in.Close()
+		}
+		return verifyCMS(sd, in)
+	}
+	sig, err := model.ParseSignature(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to read signature %s  %v", sigPath, err)
+	}
+	certs := repositories.Certificates(config.SearchPath())
+	cert, err := certs.ByFingerPrint(sig.Signer)
+	if err != nil {
+		return "", fmt.Errorf("signer certificate %s is not in the repository", sig.Signer)
+	}
+
+	in, err := openInput(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	if err := model.VerifyStream(in, cert.PublicKey, sig.SignatureAlgorithm, sig.Data); err != nil {
+		return "", err
+	}
+	chain, err := verifySignerChain(cert, sig.Chain...)
+	if err != nil {
+		return "", err
+	}
+	return formatSignerChains(chain), nil
+}
+
+// verifyCMS verifies the signed data against the given content, or its attached content when nil.
+// Signer certificates are found in the signed data or the repository, and must be in the repository.
+func verifyCMS(sd *cms.SignedData, content io.Reader) (string, error) {
+	repoCerts := repositories.Certificates(config.SearchPath())
+	// include repository certificates of signers not held in the signed data
+	var extra []*x509.Certificate
+	for _, si := range sd.Signers {
+		if si.FindCertificate(sd.Certificates) != nil {
+			continue
+		}
+		for _, c := range repoCerts.FindAll(func(c *model.Certificate) bool {
+			return si.FindCertificate([]*x509.Certificate{(*x509.Certificate)(c)}) != nil
+		}) {
+			extra = append(extra, (*x509.Certificate)(c))
+		}
+	}
+	signers, err := sd.Verify(content, extra...)
+	if err != nil {
+		return "", err
+	}
+	inters := make([]*model.Certificate, len(sd.Certificates))
+	for i, c := range sd.Certificates {
+		inters[i] = (*model.Certificate)(c)
+	}
+	var chains [][]*model.Certificate
+	for _, s := range signers {
+		fp := (*model.Certificate)(s).Fingerprint()
+		cert, err := repoCerts.ByFingerPrint(fp)
+		if err != nil {
+			return "", fmt.Errorf("signer certificate %s (%s) is not in the repository", s.Subject, fp)
+		}
+		chain, err := verifySignerChain(cert, inters...)
+		if err != nil {
+			return "", err
+		}
+		chains = append(chains, chain)
+	}
+	return formatSignerChains(chains...), nil
+}
+
+func verifySignerChain(cert *model.Certificate, intermediates ...*model.Certificate) ([]*model.Certificate, error) {
+	chain, err := repositories.Certificates(config.SearchPath()).Verify(cert, intermediates...)
+	if err != nil {
+		return nil, fmt.Errorf("signature is valid but the signers certificate %s is not  %v", cert.Subject, err)
+	}
+	return chain, nil
+}
+
+func formatSignerChains(chains ...[]*model.Certificate) string {
+	buf := bytes.NewBufferString("signature is valid\n")
+	for _, chain := range chains {
+		for i, c := range chain {
+			label := "issued by"
+			if i == 0 {
+				label = "signed by"
+			}
+			fmt.Fprintf(buf, "%s\t%s\t%s\n", label, c.Subject, c.Fingerprint())
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/config"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerifySignature(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pem files are found relative to the working directory
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	now := time.Now()
+	rootKey, root := testCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	signerKey, signer := testCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "signer"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, root, rootKey)
+	keyDer, err := x509.MarshalPKCS8PrivateKey(signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootPath := filepath.Join(config.CertificatePath(), "root.pem")
+	for path, blk := range map[string]*pem.Block{
+		rootPath: {Type: "CERTIFICATE", Bytes: root.Raw},
+		filepath.Join(config.CertificatePath(), "signer.pem"): {Type: "CERTIFICATE", Bytes: signer.Raw},
+		filepath.Join(config.KeyPath(), "signer.pem"):         {Type: "PRIVATE KEY", Bytes: keyDer},
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(blk), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile("data.txt", []byte("signed data\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (SignCommand{User: "signer", Out: "data.txt.sig"}).Sign("data.txt"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := VerifySignatureCommand{}.Verify("data.txt")
+	if err != nil {
+		t.Fatalf("expected signature to verify  %v", err)
+	}
+	if !strings.Contains(out, "signed by\tCN=signer") || !strings.Contains(out, "issued by\tCN=root") {
+		t.Errorf("expected the signers chain, found:\n%s", out)
+	}
+
+	if err := os.WriteFile("tampered.txt", []byte("signed data!\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (VerifySignatureCommand{Signature: "data.txt.sig"}).Verify("tampered.txt"); err == nil {
+		t.Errorf("expected tampered data to fail")
+	}
+
+	// the root in the signature is not trusted, once removed from the repository
+	if err := os.Remove(rootPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (VerifySignatureCommand{}).Verify("data.txt"); err == nil || !strings.Contains(err.Error(), "signature is valid but") {
+		t.Errorf("expected the signers chain to fail without its root, found %v", err)
+	}
+}
+
+func testCertificate(t *testing.T, tmpl, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
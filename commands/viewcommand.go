@@ -3,6 +3,8 @@ package commands
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
 	"github.com/eurozulu/pempal/resourcefiles"
 	"github.com/eurozulu/pempal/resourceformat"
 	"path/filepath"
@@ -19,6 +21,16 @@ type ViewCommand struct {
 	// pem	Outputs resource(s) as Pem encoded blocks
 	// yaml Outputs a yaml document(s) of the properties in each resource
 	// json Outputs a json document(s) of the properties in each resource
+	// text Outputs each resource as human readable text, in the style of openssl -text
+	// csv	Outputs a comma separated record of each resource, with a header line
+	// jsonl	Outputs a single line json object of each resource
+	// ssh	Outputs the public key of each resource as an OpenSSH authorized_keys line
+	// jwk	Outputs the public key of each resource as a JSON Web Key
+	// pkix	Outputs the public key of each resource as a PKIX public key pem
+	// pkix-der	Outputs the public key of a single resource as PKIX der
+	// pfx	Outputs a certificate, its key and issuers as a password protected PKCS#12, from a single certificate file
+	// pfx-legacy	Outputs pfx using triple DES and a SHA-1 mac, for older consumers
+	// p7b	Outputs every certificate and CRL as a single PKCS#7 bundle
 	// @Flag(format,f)
 	Format string
 }
@@ -40,14 +52,47 @@ func (cmd ViewCommand) ViewResources(path string, paths ...string) (string, erro
 	ctx, cnl := context.WithCancel(context.Background())
 	defer cnl()
 	pemz := resourcefiles.PemFiles(path)
+	var files []*model.PemFile
 	for pemFile := range pemz.Find(ctx, filter) {
+		files = append(files, pemFile)
+	}
+	if sf, ok := format.(resourceformat.SingleFileFormat); ok {
+		if err := checkSingleFile(sf, files); err != nil {
+			return "", err
+		}
+	}
+	if hf, ok := format.(resourceformat.HeaderFormat); ok {
+		if err := hf.Header(buf); err != nil {
+			return "", err
+		}
+	}
+	for _, pemFile := range files {
 		if err := format.Format(buf, pemFile); err != nil {
 			return "", err
 		}
 	}
+	if bf, ok := format.(resourceformat.BundleFormat); ok {
+		if err := bf.Flush(buf); err != nil {
+			return "", err
+		}
+	}
 	return buf.String(), nil
 }
 
+// checkSingleFile checks only one of the given files would be formatted by the single file format.
+func checkSingleFile(sf resourceformat.SingleFileFormat, files []*model.PemFile) error {
+	var paths []string
+	for _, p := range files {
+		if sf.Formats(p) {
+			paths = append(paths, p.Path)
+		}
+	}
+	if len(paths) > 1 {
+		return fmt.Errorf("format can only output a single file, found %d: %s. View each file separately", len(paths), strings.Join(paths, ", "))
+	}
+	return nil
+}
+
 func (cmd ViewCommand) buildFilter() resourcefiles.PemFileFilter {
 	return nil
 }
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/passphrases"
+	"github.com/eurozulu/pempal/pkcs12"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestViewPFXCertificateAndKeyFiles(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pem files are found relative to the working directory
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(p passphrases.Provider) {
+		passphrases.DefaultProvider = p
+	}(passphrases.DefaultProvider)
+	passphrases.DefaultProvider = passphrases.MappedProvider{"*": passphrases.EnvProvider("TEST_PFX_PASSPHRASE")}
+	t.Setenv("TEST_PFX_PASSPHRASE", "secret")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "me"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, blk := range map[string]*pem.Block{
+		filepath.Join("certs", "me.pem"):   {Type: "CERTIFICATE", Bytes: der},
+		filepath.Join("private", "me.pem"): {Type: "PRIVATE KEY", Bytes: keyDer},
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(blk), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, paths := range [][]string{{"certs/me.pem", "private/me.pem"}, {"."}} {
+		out, err := ViewCommand{Format: "pfx"}.ViewResources(paths[0], paths[1:]...)
+		if err != nil {
+			t.Fatalf("%v: %v", paths, err)
+		}
+		p12, err := pkcs12.Decode([]byte(out), "secret")
+		if err != nil {
+			t.Fatalf("%v: %v", paths, err)
+		}
+		if len(p12.PrivateKeys) != 1 || len(p12.Certificates) != 1 || p12.Certificates[0].Subject.CommonName != "me" {
+			t.Errorf("%v: expected the certificate and its key", paths)
+		}
+	}
+}
@@ -28,6 +28,12 @@ type PPConfig struct {
 	TemplatePath       string   `yaml:"template-path,omitempty"`
 	DefaultKeyTemplate string   `yaml:"default-key-template,omitempty"`
 	FileExt            []string `yaml:"file-extensions"`
+	// Passphrase is the default source of passphrases for encrypted keys, e.g. "env:MY_PASS", "file:~/.pass", "cmd:pass show root" or "prompt"
+	Passphrase string `yaml:"passphrase,omitempty"`
+	// Passphrases maps key file names or patterns to the source of their passphrase.
+	Passphrases map[string]string `yaml:"passphrases,omitempty"`
+	// Signers maps the names of external signers to the command which runs them.
+	Signers map[string]string `yaml:"signers,omitempty"`
 }
 
 func init() {
@@ -82,6 +88,10 @@ func NewPPConfig() *PPConfig {
 			"", ".pem",
 			".crt", ".cert", ".cer",
 			".key", ".pub", ".prk", ".puk", ".rsa",
+			".jwk", ".der",
+			".p7s", ".p7m", ".p7b", ".p7c",
+			".p12", ".pfx",
+			".jks", ".jceks", ".keystore", ".truststore",
 			".x509",
 			".csr", ".request",
 			".crl", ".revoke",
@@ -7,12 +7,13 @@ import (
 	"fmt"
 	"github.com/eurozulu/pempal/config"
 	"github.com/eurozulu/pempal/model"
-	"github.com/eurozulu/pempal/repositories"
 	"github.com/eurozulu/pempal/templates"
 	"time"
 )
 
-type CertificateFactory struct{}
+type CertificateFactory struct {
+	resolver
+}
 
 func (cf CertificateFactory) Make(ct *templates.CertificateTemplate) ([]model.PemResource, error) {
 	var newKey *model.PrivateKey
@@ -35,7 +36,7 @@ func (cf CertificateFactory) Make(ct *templates.CertificateTemplate) ([]model.Pe
 	if ct.SelfSigned {
 		// If using an existing key, go find it
 		if newKey == nil {
-			prk, err := repositories.Keys(config.KeyPath()).ByPublicKey(ct.PublicKey)
+			prk, err := cf.resolveKey(config.KeyPath(), ct.PublicKey)
 			if err != nil {
 				return nil, fmt.Errorf("no private key found for certificate %s. %v", ct.Subject, err)
 			}
@@ -44,18 +45,27 @@ func (cf CertificateFactory) Make(ct *templates.CertificateTemplate) ([]model.Pe
 		issuer = model.NewIssuer(cert, newKey)
 	} else {
 		// Using existing issuer
-		is, err := resolveIssuer(ct.Issuer)
+		is, err := cf.resolveIssuer(ct.Issuer)
 		if err != nil {
 			return nil, err
 		}
+		if err := ValidateAgainstIssuer(cert, is.Certificate()); err != nil {
+			return nil, err
+		}
 		issuer = is
 	}
+	sa, err := model.SignatureAlgorithmForKey(ct.SignatureAlgorithm, issuer.PublicKey().Public())
+	if err != nil {
+		return nil, err
+	}
+	cert.SignatureAlgorithm = x509.SignatureAlgorithm(sa)
+
 	der, err := x509.CreateCertificate(
 		rand.Reader,
 		(*x509.Certificate)(cert),
 		(*x509.Certificate)(issuer.Certificate()),
 		ct.PublicKey.Public(),
-		issuer.PrivateKey().Private(),
+		issuer.Signer(),
 	)
 	if err != nil {
 		return nil, err
@@ -6,11 +6,11 @@ import (
 	"errors"
 	"github.com/eurozulu/pempal/config"
 	"github.com/eurozulu/pempal/model"
-	"github.com/eurozulu/pempal/repositories"
 	"github.com/eurozulu/pempal/templates"
 )
 
 type CertificateRequestFactory struct {
+	resolver
 }
 
 func (cf CertificateRequestFactory) Make(t *templates.CertificateRequestTemplate) ([]model.PemResource, error) {
@@ -30,25 +30,29 @@ func (cf CertificateRequestFactory) Make(t *templates.CertificateRequestTemplate
 	csr := &model.CertificateRequest{}
 	t.ApplyTo(csr)
 
-	puk := model.NewPublicKey(csr.PublicKey)
-	prk, err := repositories.Keys(config.SearchPath()).ByPublicKey(puk)
-	if err != nil {
-		return nil, err
+	prk := newKey
+	if prk == nil {
+		k, err := cf.resolveKey(config.SearchPath(), t.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		prk = k
 	}
-	der, err := x509.CreateCertificateRequest(rand.Reader, (*x509.CertificateRequest)(csr), prk)
+	der, err := x509.CreateCertificateRequest(rand.Reader, (*x509.CertificateRequest)(csr), prk.Signer())
 	if err != nil {
 		return nil, err
 	}
 	if err = csr.UnmarshalBinary(der); err != nil {
 		return nil, err
 	}
-	return []model.PemResource{csr}, nil
+	result := []model.PemResource{csr}
+	if newKey != nil {
+		result = append(result, newKey)
+	}
+	return result, nil
 }
 
 func ValidateCertificateRequestTemplate(t *templates.CertificateRequestTemplate) error {
-	if x509.SignatureAlgorithm(t.SignatureAlgorithm) == x509.UnknownSignatureAlgorithm {
-		return errors.New("Signature Algorithm is required")
-	}
 	if t.Subject.String() == "" {
 		return errors.New("subject name is required")
 	}
@@ -56,9 +60,13 @@ func ValidateCertificateRequestTemplate(t *templates.CertificateRequestTemplate)
 	if t.PublicKey == nil {
 		return errors.New("public key is required")
 	}
-	puk := model.NewPublicKey(t.PublicKey)
-	if puk.PublicKeyAlgorithm() != t.PublicKeyAlgorithm {
-		t.PublicKeyAlgorithm = puk.PublicKeyAlgorithm()
+	if t.PublicKey.PublicKeyAlgorithm() != t.PublicKeyAlgorithm {
+		t.PublicKeyAlgorithm = t.PublicKey.PublicKeyAlgorithm()
+	}
+	sa, err := model.SignatureAlgorithmForKey(t.SignatureAlgorithm, t.PublicKey.Public())
+	if err != nil {
+		return err
 	}
+	t.SignatureAlgorithm = sa
 	return nil
 }
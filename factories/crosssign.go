@@ -0,0 +1,56 @@
+package factories
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/templates"
+	"slices"
+)
+
+// typedExtensions are the extensions generated from the fields of a certificate template.
+// These are not copied when cross signing, so the template fields, and any overrides of them, are used.
+var typedExtensions = []asn1.ObjectIdentifier{
+	{2, 5, 29, 14},              // subject key id
+	{2, 5, 29, 15},              // key usage
+	{2, 5, 29, 17},              // subject alt names
+	{2, 5, 29, 19},              // basic constraints
+	{2, 5, 29, 30},              // name constraints
+	{2, 5, 29, 31},              // CRL distribution points
+	{2, 5, 29, 35},              // authority key id
+	{2, 5, 29, 37},              // extended key usage
+	{1, 3, 6, 1, 5, 5, 7, 1, 1}, // authority info access
+}
+
+// NewCrossSignTemplate creates a certificate template to re-issue the given CA certificate under a different issuer.
+// The subject, public key, subject key id and extensions of the certificate are preserved.
+// Extensions with a template field are set from that field, other extensions are copied as they are.
+// The issuer, serial number and signature are left empty, to be set by the new issuer.
+func NewCrossSignTemplate(cert *model.Certificate) (*templates.CertificateTemplate, error) {
+	if !cert.IsCA {
+		return nil, fmt.Errorf("certificate %s is not a CA certificate and can not be cross signed", cert.Subject)
+	}
+	ct := templates.NewCertificateTemplate(cert)
+	ct.Issuer = model.DistinguishedName{}
+	ct.SerialNumber = nil
+	ct.Signature = nil
+	ct.SignatureAlgorithm = 0
+	ct.AuthorityKeyId = nil
+	ct.SelfSigned = false
+	ct.BasicConstraintsValid = cert.BasicConstraintsValid
+	ct.ExtKeyUsage = cert.ExtKeyUsage
+	ct.UnknownExtKeyUsage = cert.UnknownExtKeyUsage
+	ct.PermittedDNSDomainsCritical = cert.PermittedDNSDomainsCritical
+	ct.PermittedDNSDomains = cert.PermittedDNSDomains
+	ct.ExcludedDNSDomains = cert.ExcludedDNSDomains
+
+	// Copy the extensions the template has no field for, such as policies, as they are.
+	for _, ext := range cert.Extensions {
+		if slices.ContainsFunc(typedExtensions, ext.Id.Equal) {
+			continue
+		}
+		ct.ExtraExtensions = append(ct.ExtraExtensions, pkix.Extension(ext))
+	}
+	return ct, nil
+}
@@ -0,0 +1,111 @@
+package factories
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"github.com/eurozulu/pempal/model"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestNewCrossSignTemplate(t *testing.T) {
+	oidUnknown := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+	oidPolicy := asn1.ObjectIdentifier{2, 5, 29, 32}
+	caKey, ca := testCrossSignCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "old ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		Policies:              []x509.OID{mustOID(t, "1.2.3.4")},
+		ExtraExtensions:       []pkix.Extension{{Id: oidUnknown, Value: []byte{5, 0}}},
+	}, nil, nil)
+	issuerKey, issuer := testCrossSignCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "new ca"},
+		SubjectKeyId:          []byte{9, 9, 9, 9},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+
+	ct, err := NewCrossSignTemplate((*model.Certificate)(ca))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct.KeyUsage = model.KeyUsage(x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature)
+	ct.SerialNumber = (*model.SerialNumber)(big.NewInt(3))
+	ct.NotBefore = model.TimeDTO(time.Now())
+	ct.NotAfter = model.TimeDTO(time.Now().Add(model.DurationYear))
+	tmpl := &model.Certificate{}
+	ct.ApplyTo(tmpl)
+
+	der, err := x509.CreateCertificate(rand.Reader, (*x509.Certificate)(tmpl), issuer, caKey.Public(), issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cross, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cross.KeyUsage != x509.KeyUsageCertSign|x509.KeyUsageDigitalSignature {
+		t.Errorf("expected key usage override, found %d", cross.KeyUsage)
+	}
+	if !cross.IsCA || !bytes.Equal(cross.SubjectKeyId, ca.SubjectKeyId) {
+		t.Errorf("expected CA with the original subject key id")
+	}
+	if !bytes.Equal(cross.AuthorityKeyId, issuer.SubjectKeyId) {
+		t.Errorf("expected authority key id of the new issuer")
+	}
+	counts := map[string]int{}
+	for _, ext := range cross.Extensions {
+		counts[ext.Id.String()]++
+	}
+	for _, oid := range []asn1.ObjectIdentifier{oidUnknown, oidPolicy, {2, 5, 29, 15}} {
+		if counts[oid.String()] != 1 {
+			t.Errorf("expected one %s extension, found %d", oid, counts[oid.String()])
+		}
+	}
+	if err := cross.CheckSignatureFrom(issuer); err != nil {
+		t.Errorf("expected cross signed certificate signed by new issuer  %v", err)
+	}
+
+	if _, err := NewCrossSignTemplate(&model.Certificate{Subject: pkix.Name{CommonName: "leaf"}}); err == nil {
+		t.Errorf("expected non CA certificate to fail")
+	}
+}
+
+func testCrossSignCertificate(t *testing.T, tmpl, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.NotBefore = time.Now()
+	tmpl.NotAfter = time.Now().Add(model.DurationYear)
+	if issuer == nil {
+		issuer, issuerKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, key.Public(), issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+func mustOID(t *testing.T, s string) x509.OID {
+	oid, err := x509.ParseOID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return oid
+}
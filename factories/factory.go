@@ -13,26 +13,45 @@ type Factory interface {
 }
 
 func Make(t templates.Template) ([]model.PemResource, error) {
+	return resolver{}.make(t)
+}
+
+// resolver finds the issuers and keys used to make resources.
+// unsaved holds the resources made during a manifest run, which were written outside of the repository.
+// They are searched before the repository, so the later resources of the run can find them.
+type resolver struct {
+	unsaved []model.PemResource
+}
+
+func (r resolver) make(t templates.Template) ([]model.PemResource, error) {
 	switch tt := t.(type) {
 	case *templates.PrivateKeyTemplate:
 		return KeyFactory{}.Make(tt)
 
 	case *templates.RevocationListTemplate:
-		return RevocationListFactory{}.Make(tt)
+		return RevocationListFactory{r}.Make(tt)
 
 	case *templates.CertificateTemplate:
-		return CertificateFactory{}.Make(tt)
+		return CertificateFactory{r}.Make(tt)
 
 	case *templates.CertificateRequestTemplate:
-		return CertificateRequestFactory{}.Make(tt)
+		return CertificateRequestFactory{r}.Make(tt)
+
+	case *templates.SSHCertificateTemplate:
+		return SSHCertificateFactory{}.Make(tt)
 
 	default:
 		return nil, fmt.Errorf("template type: %T is not a known base template", t)
 	}
 }
 
-func resolveIssuer(dn model.DistinguishedName) (*model.Issuer, error) {
-	issuer := repositories.Issuers(config.SearchPath()).MatchByName(dn)
+func (r resolver) resolveIssuer(dn model.DistinguishedName) (*model.Issuer, error) {
+	issuer := r.unsavedIssuers(func(cert *model.Certificate) bool {
+		return model.DistinguishedName(cert.Subject).Matches(dn)
+	})
+	if len(issuer) == 0 {
+		issuer = repositories.Issuers(config.SearchPath()).MatchByName(dn)
+	}
 	if len(issuer) == 0 {
 		return nil, fmt.Errorf("issuer %s not found", dn)
 	}
@@ -41,3 +60,30 @@ func resolveIssuer(dn model.DistinguishedName) (*model.Issuer, error) {
 	}
 	return issuer[0], nil
 }
+
+// resolveKey finds the private key of the given public key, in the unsaved resources or the keys of the given path.
+func (r resolver) resolveKey(path string, puk *model.PublicKey) (*model.PrivateKey, error) {
+	for _, res := range r.unsaved {
+		if prk, ok := res.(*model.PrivateKey); ok && prk.Public().Fingerprint() == puk.Fingerprint() {
+			return prk, nil
+		}
+	}
+	return repositories.Keys(path).ByPublicKey(puk)
+}
+
+// unsavedIssuers finds the CA certificates in the unsaved resources, matching the filter, which have a private key.
+func (r resolver) unsavedIssuers(filter repositories.CertificateFilter) []*model.Issuer {
+	var issuers []*model.Issuer
+	for _, res := range r.unsaved {
+		cert, ok := res.(*model.Certificate)
+		if !ok || !cert.IsCA || !filter(cert) {
+			continue
+		}
+		prk, err := r.resolveKey(config.SearchPath(), model.NewPublicKey(cert.PublicKey))
+		if err != nil {
+			continue
+		}
+		issuers = append(issuers, model.NewIssuer(cert, prk))
+	}
+	return issuers
+}
@@ -0,0 +1,86 @@
+package factories
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/templates"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestMakeWithUnsavedResources(t *testing.T) {
+	caKey, ca := testCrossSignCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "unsaved ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}, nil, nil)
+	caPrk := testPrivateKey(t, caKey)
+	leafPrk := testPrivateKey(t, nil)
+	r := resolver{unsaved: []model.PemResource{(*model.Certificate)(ca), caPrk, leafPrk}}
+
+	now := time.Now()
+	leafTemplate := func() *templates.CertificateTemplate {
+		return &templates.CertificateTemplate{
+			Subject:      model.DistinguishedName{CommonName: "leaf"},
+			Issuer:       model.DistinguishedName{CommonName: "unsaved ca"},
+			SerialNumber: (*model.SerialNumber)(big.NewInt(2)),
+			PublicKey:    leafPrk.Public(),
+			NotBefore:    model.TimeDTO(now),
+			NotAfter:     model.TimeDTO(now.Add(model.DurationMonth)),
+		}
+	}
+	resz, err := CertificateFactory{r}.Make(leafTemplate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := resz[0].(*model.Certificate)
+	if err := (*x509.Certificate)(leaf).CheckSignatureFrom(ca); err != nil {
+		t.Errorf("expected certificate issued by unsaved ca  %v", err)
+	}
+
+	// the unsaved resources are only known to the factories given them
+	if _, err := (CertificateFactory{}).Make(leafTemplate()); err == nil {
+		t.Errorf("expected unsaved ca not to be found by another factory")
+	}
+
+	resz, err = CertificateFactory{r}.Make(&templates.CertificateTemplate{
+		Subject:      model.DistinguishedName{CommonName: "self"},
+		SerialNumber: (*model.SerialNumber)(big.NewInt(3)),
+		PublicKey:    leafPrk.Public(),
+		SelfSigned:   true,
+		NotBefore:    model.TimeDTO(now),
+		NotAfter:     model.TimeDTO(now.Add(model.DurationMonth)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	self := (*x509.Certificate)(resz[0].(*model.Certificate))
+	if err := self.CheckSignature(self.SignatureAlgorithm, self.RawTBSCertificate, self.Signature); err != nil {
+		t.Errorf("expected certificate self signed with the unsaved key  %v", err)
+	}
+}
+
+func testPrivateKey(t *testing.T, key *ecdsa.PrivateKey) *model.PrivateKey {
+	if key == nil {
+		var err error
+		if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prk := &model.PrivateKey{}
+	if err := prk.UnmarshalBinary(der); err != nil {
+		t.Fatal(err)
+	}
+	return prk
+}
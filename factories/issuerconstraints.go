@@ -0,0 +1,157 @@
+package factories
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"net"
+	"strings"
+)
+
+// ValidateAgainstIssuer checks the given certificate can be validated when issued by the given issuer certificate.
+// The issuer must be a CA with the CertSign key usage and the certificate must fall within its validity period.
+// When the certificate is a CA, the issuers maximum path length must allow it.
+// Any names in the certificate must be permitted by the issuers name constraints.
+func ValidateAgainstIssuer(cert, issuer *model.Certificate) error {
+	if !issuer.IsCA {
+		return fmt.Errorf("issuer %s is not a CA certificate", issuer.Subject)
+	}
+	if issuer.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("issuer %s does not have the CertSign key usage", issuer.Subject)
+	}
+	if !cert.NotBefore.IsZero() && cert.NotBefore.Before(issuer.NotBefore) {
+		return fmt.Errorf("certificate not-before %s is before the issuers not-before %s",
+			model.TimeDTO(cert.NotBefore), model.TimeDTO(issuer.NotBefore))
+	}
+	if cert.NotAfter.After(issuer.NotAfter) {
+		return fmt.Errorf("certificate not-after %s is after the issuers not-after %s",
+			model.TimeDTO(cert.NotAfter), model.TimeDTO(issuer.NotAfter))
+	}
+	if cert.IsCA {
+		if err := validatePathLen(cert, issuer); err != nil {
+			return err
+		}
+	}
+	return validateNameConstraints(cert, issuer)
+}
+
+func validatePathLen(cert, issuer *model.Certificate) error {
+	if !issuer.BasicConstraintsValid || (issuer.MaxPathLen <= 0 && !issuer.MaxPathLenZero) {
+		// no path length constraint
+		return nil
+	}
+	if issuer.MaxPathLen == 0 {
+		return fmt.Errorf("issuer %s has a max-path-len of zero and can not issue CA certificates", issuer.Subject)
+	}
+	if !cert.BasicConstraintsValid || (cert.MaxPathLen <= 0 && !cert.MaxPathLenZero) {
+		return nil
+	}
+	if cert.MaxPathLen >= issuer.MaxPathLen {
+		return fmt.Errorf("max-path-len %d must be less than the issuers max-path-len %d", cert.MaxPathLen, issuer.MaxPathLen)
+	}
+	return nil
+}
+
+func validateNameConstraints(cert, issuer *model.Certificate) error {
+	for _, dns := range cert.DNSNames {
+		if !isNamePermitted(dns, issuer.PermittedDNSDomains, issuer.ExcludedDNSDomains, matchDomain) {
+			return fmt.Errorf("dns name %q is not permitted by the issuers name constraints", dns)
+		}
+	}
+	for _, email := range cert.EmailAddresses {
+		if !isNamePermitted(email, issuer.PermittedEmailAddresses, issuer.ExcludedEmailAddresses, matchEmail) {
+			return fmt.Errorf("email address %q is not permitted by the issuers name constraints", email)
+		}
+	}
+	for _, uri := range cert.URIs {
+		if !isNamePermitted(uri.Hostname(), issuer.PermittedURIDomains, issuer.ExcludedURIDomains, matchURIDomain) {
+			return fmt.Errorf("uri %q is not permitted by the issuers name constraints", uri)
+		}
+	}
+	for _, ip := range cert.IPAddresses {
+		if !isIPPermitted(ip, issuer.PermittedIPRanges, issuer.ExcludedIPRanges) {
+			return fmt.Errorf("ip address %s is not permitted by the issuers name constraints", ip)
+		}
+	}
+	return nil
+}
+
+func isNamePermitted(name string, permitted, excluded []string, match func(name, constraint string) bool) bool {
+	for _, c := range excluded {
+		if match(name, c) {
+			return false
+		}
+	}
+	if len(permitted) == 0 {
+		return true
+	}
+	for _, c := range permitted {
+		if match(name, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIPPermitted(ip net.IP, permitted, excluded []*net.IPNet) bool {
+	for _, n := range excluded {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	if len(permitted) == 0 {
+		return true
+	}
+	for _, n := range permitted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain matches the domain to the constraint, as per RFC 5280 4.2.1.10
+// A constraint beginning with a dot only matches subdomains, otherwise the domain itself and its subdomains match.
+func matchDomain(domain, constraint string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	constraint = strings.ToLower(constraint)
+	if constraint == "" {
+		return true
+	}
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(domain, constraint)
+	}
+	return domain == constraint || strings.HasSuffix(domain, "."+constraint)
+}
+
+// matchURIDomain matches the host of a uri to the constraint, as per RFC 5280 4.2.1.10
+// A constraint beginning with a dot only matches subdomains, otherwise only the host itself matches.
+func matchURIDomain(host, constraint string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	constraint = strings.ToLower(constraint)
+	if constraint == "" {
+		return true
+	}
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(host, constraint)
+	}
+	return host == constraint
+}
+
+// matchEmail matches the email address to the constraint, as per RFC 5280 4.2.1.10
+// A constraint containing an @ is a single mailbox, otherwise it is a domain constraint on the host part of the address.
+func matchEmail(email, constraint string) bool {
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(email, constraint)
+	}
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return false
+	}
+	host := strings.ToLower(email[i+1:])
+	constraint = strings.ToLower(constraint)
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(host, constraint)
+	}
+	return host == constraint
+}
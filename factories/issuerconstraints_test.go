@@ -0,0 +1,92 @@
+package factories
+
+import (
+	"crypto/x509"
+	"github.com/eurozulu/pempal/model"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestValidateAgainstIssuer(t *testing.T) {
+	now := time.Now()
+	issuer := &model.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		MaxPathLen:            1,
+		KeyUsage:              x509.KeyUsageCertSign,
+		NotAfter:              now.Add(model.DurationYear),
+		PermittedDNSDomains:   []string{"acme.com"},
+		ExcludedDNSDomains:    []string{"test.acme.com"},
+	}
+	cert := &model.Certificate{
+		NotAfter: now.Add(model.DurationMonth),
+		DNSNames: []string{"www.acme.com", "acme.com"},
+	}
+	if err := ValidateAgainstIssuer(cert, issuer); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	cert.DNSNames = []string{"www.test.acme.com"}
+	if err := ValidateAgainstIssuer(cert, issuer); err == nil {
+		t.Errorf("expected excluded dns name to fail")
+	}
+	cert.DNSNames = []string{"www.acme.org"}
+	if err := ValidateAgainstIssuer(cert, issuer); err == nil {
+		t.Errorf("expected non permitted dns name to fail")
+	}
+	cert.DNSNames = nil
+
+	issuer.PermittedURIDomains = []string{"acme.com", ".acme.net"}
+	for uri, permitted := range map[string]bool{
+		"https://acme.com/path":     true,
+		"https://www.acme.com/path": false,
+		"https://www.acme.net":      true,
+		"https://acme.net":          false,
+	} {
+		u, err := url.Parse(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert.URIs = []*url.URL{u}
+		if err := ValidateAgainstIssuer(cert, issuer); (err == nil) != permitted {
+			t.Errorf("expected uri %s permitted %v, found %v", uri, permitted, err)
+		}
+	}
+	cert.URIs = nil
+
+	issuer.NotBefore = now
+	cert.NotBefore = now.Add(-time.Hour)
+	if err := ValidateAgainstIssuer(cert, issuer); err == nil {
+		t.Errorf("expected certificate valid before issuer to fail")
+	}
+	cert.NotBefore = now
+
+	cert.NotAfter = now.Add(model.DurationYear * 2)
+	if err := ValidateAgainstIssuer(cert, issuer); err == nil {
+		t.Errorf("expected certificate outliving issuer to fail")
+	}
+	cert.NotAfter = now.Add(model.DurationMonth)
+
+	cert.IsCA = true
+	cert.BasicConstraintsValid = true
+	cert.MaxPathLen = 1
+	if err := ValidateAgainstIssuer(cert, issuer); err == nil {
+		t.Errorf("expected max-path-len equal to issuers to fail")
+	}
+	cert.MaxPathLen = 0
+	cert.MaxPathLenZero = true
+	if err := ValidateAgainstIssuer(cert, issuer); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	issuer.KeyUsage = x509.KeyUsageDigitalSignature
+	if err := ValidateAgainstIssuer(cert, issuer); err == nil {
+		t.Errorf("expected issuer without CertSign to fail")
+	}
+	issuer.KeyUsage = x509.KeyUsageCertSign
+	issuer.IsCA = false
+	if err := ValidateAgainstIssuer(cert, issuer); err == nil {
+		t.Errorf("expected leaf issuer to fail")
+	}
+}
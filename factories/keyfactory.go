@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"github.com/eurozulu/pempal/config"
 	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
 	"github.com/eurozulu/pempal/repositories"
 	"github.com/eurozulu/pempal/templates"
 )
@@ -39,8 +40,16 @@ func (kf KeyFactory) Make(kt *templates.PrivateKeyTemplate) ([]model.PemResource
 	if err != nil {
 		return nil, err
 	}
-	return []model.PemResource{model.NewPrivateKey(prk)}, nil
-
+	key := model.NewPrivateKey(prk)
+	if kt.IsEncrypted {
+		name := fmt.Sprintf("new key %s", key.Public().Fingerprint())
+		pass, err := passphrases.DefaultProvider.Passphrase(name, true)
+		if err != nil {
+			return nil, err
+		}
+		key.SetPassphrase(pass)
+	}
+	return []model.PemResource{key}, nil
 }
 
 func ValidateKeyTemplate(kt *templates.PrivateKeyTemplate) error {
@@ -62,12 +71,11 @@ func ValidateKeyTemplate(kt *templates.PrivateKeyTemplate) error {
 }
 
 func CreateDefaultKey() (*model.PrivateKey, error) {
-	temps, err := repositories.Templates(config.TemplatePath()).ExpandedByName(config.DefaultKeyTemplateName())
+	kt, err := DefaultKeyTemplate()
 	if err != nil {
-		return nil, fmt.Errorf("The default key template could not be found: %s", err)
+		return nil, err
 	}
-	t, err := templates.MergeTemplates(temps)
-	res, err := Make(t)
+	res, err := Make(kt)
 	if err != nil {
 		return nil, err
 	}
@@ -77,3 +85,20 @@ func CreateDefaultKey() (*model.PrivateKey, error) {
 	}
 	return prk, nil
 }
+
+// DefaultKeyTemplate resolves the default key template, without making a key from it.
+func DefaultKeyTemplate() (*templates.PrivateKeyTemplate, error) {
+	temps, err := repositories.Templates(config.TemplatePath()).ExpandedByName(config.DefaultKeyTemplateName())
+	if err != nil {
+		return nil, fmt.Errorf("The default key template could not be found: %s", err)
+	}
+	t, err := templates.MergeTemplates(temps)
+	if err != nil {
+		return nil, err
+	}
+	kt, ok := t.(*templates.PrivateKeyTemplate)
+	if !ok {
+		return nil, fmt.Errorf("The default key template is not a key template, found %T", t)
+	}
+	return kt, nil
+}
@@ -0,0 +1,204 @@
+package factories
+
+import (
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/logging"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/repositories"
+	"github.com/eurozulu/pempal/templates"
+	"github.com/eurozulu/pempal/tools"
+	"gopkg.in/yaml.v2"
+	"os"
+	"slices"
+)
+
+// Manifest describes a collection of resources to be made together.
+// Each resource is made in dependency order, so resources which depend on others, such as a certificate issued by
+// a CA in the same manifest, are made after the resources they depend upon.
+type Manifest struct {
+	Resources []*ManifestResource `yaml:"resources"`
+}
+
+// ManifestResource describes a single resource in a manifest.
+// Templates are the template names to merge, in the same manner as the make command.
+// Properties are applied after the templates, in the same way as flags given to make.
+// Key names another resource in the manifest, whose private key is used as the public-key of this resource.
+// If Key is not the name of a resource, it is used as a fingerprint of an existing key.
+// DependsOn names any other resources in the manifest which must be made before this one.
+// Out, when set, is the file path the resources are written to, which may contain macros, as the make -out flag.
+// When empty, resources are saved into the repository.
+type ManifestResource struct {
+	Name       string                 `yaml:"name"`
+	Templates  []string               `yaml:"templates"`
+	Properties map[string]interface{} `yaml:"properties,omitempty"`
+	Key        string                 `yaml:"key,omitempty"`
+	DependsOn  []string               `yaml:"depends-on,omitempty"`
+	Out        string                 `yaml:"out,omitempty"`
+}
+
+// ManifestResult contains the resources made for a single manifest resource.
+type ManifestResult struct {
+	Name      string
+	Resources []model.PemResource
+}
+
+// Make makes the resources in the manifest, in dependency order.
+// If names are given, only those resources, and the resources they depend upon, are made.
+// Each resource is saved as soon as it is made, so it can be found by the resources which depend upon it.
+// Resources written to Out are held until the run ends, so they can be found by the resources which depend upon them.
+// Existing files named by Out are only overwritten when force is true.
+func (m Manifest) Make(force bool, names ...string) ([]*ManifestResult, error) {
+	ordered, err := m.Ordered(names...)
+	if err != nil {
+		return nil, err
+	}
+	var r resolver
+	var results []*ManifestResult
+	made := map[string][]model.PemResource{}
+	for _, mr := range ordered {
+		logging.Info("making manifest resource %s", mr.Name)
+		t, err := mr.template(made)
+		if err != nil {
+			return nil, fmt.Errorf("manifest resource %s: %v", mr.Name, err)
+		}
+		resz, err := r.make(t)
+		if err != nil {
+			return nil, fmt.Errorf("manifest resource %s: %v", mr.Name, err)
+		}
+		if mr.Out != "" {
+			var paths []string
+			if paths, err = OutputPaths(mr.Out, t, resz...); err == nil {
+				err = WriteResources(paths, force, resz...)
+			}
+			r.unsaved = append(r.unsaved, resz...)
+		} else {
+			err = SaveResource(resz...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("manifest resource %s: %v", mr.Name, err)
+		}
+		made[mr.Name] = resz
+		results = append(results, &ManifestResult{Name: mr.Name, Resources: resz})
+	}
+	return results, nil
+}
+
+// Ordered returns the resources of the manifest, ordered so that every resource follows the resources it depends upon.
+// If names are given, only those resources, and the resources they depend upon, are returned.
+func (m Manifest) Ordered(names ...string) ([]*ManifestResource, error) {
+	index := map[string]*ManifestResource{}
+	for _, mr := range m.Resources {
+		if mr.Name == "" {
+			return nil, fmt.Errorf("manifest contains a resource with no name")
+		}
+		if _, ok := index[mr.Name]; ok {
+			return nil, fmt.Errorf("manifest resource %s is named more than once", mr.Name)
+		}
+		index[mr.Name] = mr
+	}
+	if len(names) == 0 {
+		for _, mr := range m.Resources {
+			names = append(names, mr.Name)
+		}
+	}
+
+	var ordered []*ManifestResource
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		mr, ok := index[name]
+		if !ok {
+			return fmt.Errorf("manifest resource %s not found", name)
+		}
+		if slices.Contains(ordered, mr) {
+			return nil
+		}
+		if slices.Contains(path, name) {
+			return fmt.Errorf("manifest resource %s has a circular dependency: %v", name, append(path, name))
+		}
+		path = append(path, name)
+		for _, dep := range mr.dependencies(index) {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		ordered = append(ordered, mr)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func (mr ManifestResource) dependencies(index map[string]*ManifestResource) []string {
+	deps := mr.DependsOn
+	if _, ok := index[mr.Key]; ok {
+		deps = tools.AppendUnique(deps, mr.Key)
+	}
+	return deps
+}
+
+func (mr ManifestResource) template(made map[string][]model.PemResource) (templates.Template, error) {
+	temps, err := repositories.Templates(config.TemplatePath()).ExpandedByName(mr.Templates...)
+	if err != nil {
+		return nil, err
+	}
+	props := map[string]interface{}{}
+	for k, v := range mr.Properties {
+		props[k] = v
+	}
+	if mr.Key != "" {
+		puk, err := mr.publicKey(made)
+		if err != nil {
+			return nil, err
+		}
+		data, err := puk.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		props["public-key"] = string(data)
+	}
+	if len(props) > 0 {
+		data, err := yaml.Marshal(props)
+		if err != nil {
+			return nil, err
+		}
+		temps = append(temps, &model.TemplateFile{Data: data})
+	}
+	return templates.MergeTemplates(temps)
+}
+
+func (mr ManifestResource) publicKey(made map[string][]model.PemResource) (*model.PublicKey, error) {
+	if resz, ok := made[mr.Key]; ok {
+		for _, res := range resz {
+			if prk, ok := res.(*model.PrivateKey); ok {
+				return prk.Public(), nil
+			}
+		}
+		return nil, fmt.Errorf("manifest resource %s did not make a private key", mr.Key)
+	}
+	keyz := repositories.Keys(config.KeyPath()).MatchByAnyFingerPrint(mr.Key)
+	if len(keyz) == 0 {
+		return nil, fmt.Errorf("%q key not found", mr.Key)
+	}
+	if len(keyz) > 1 {
+		return nil, fmt.Errorf("%q key matches multiple keys", mr.Key)
+	}
+	return keyz[0].Public(), nil
+}
+
+// LoadManifest reads the manifest file at the given path
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %v", path, err)
+	}
+	return m, nil
+}
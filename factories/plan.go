@@ -0,0 +1,265 @@
+package factories
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/repositories"
+	"github.com/eurozulu/pempal/templates"
+	"gopkg.in/yaml.v2"
+	"math/big"
+	"path/filepath"
+)
+
+const planFingerprint = "<fingerprint>"
+
+// Plan describes what a make would generate, without generating it.
+type Plan struct {
+	ResourceType       model.ResourceType       `yaml:"resource-type"`
+	PublicKey          string                   `yaml:"public-key,omitempty"`
+	Issuer             string                   `yaml:"issuer,omitempty"`
+	SignatureAlgorithm model.SignatureAlgorithm `yaml:"signature-algorithm,omitempty"`
+	Outputs            []string                 `yaml:"outputs"`
+	Template           string                   `yaml:"template"`
+
+	outputTypes []model.ResourceType
+}
+
+func (p Plan) String() string {
+	data, err := yaml.Marshal(&p)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// PlanMake validates the given template and resolves the keys and issuer it requires,
+// returning a plan of what would be made.  Nothing is signed or written.
+func PlanMake(t templates.Template) (*Plan, error) {
+	switch tt := t.(type) {
+	case *templates.PrivateKeyTemplate:
+		return planKey(tt)
+	case *templates.CertificateTemplate:
+		return planCertificate(tt)
+	case *templates.CertificateRequestTemplate:
+		return planCertificateRequest(tt)
+	case *templates.RevocationListTemplate:
+		return planRevocationList(tt)
+	case *templates.SSHCertificateTemplate:
+		return planSSHCertificate(tt)
+	default:
+		return nil, fmt.Errorf("template type: %T is not a known base template", t)
+	}
+}
+
+func planKey(kt *templates.PrivateKeyTemplate) (*Plan, error) {
+	if err := ValidateKeyTemplate(kt); err != nil {
+		return nil, err
+	}
+	return &Plan{
+		ResourceType: model.ResourceTypePrivateKey,
+		PublicKey:    fmt.Sprintf("new %s key", kt.KeyAlgoritum.String()),
+		Outputs:      []string{planSavePath(model.ResourceTypePrivateKey)},
+		outputTypes:  []model.ResourceType{model.ResourceTypePrivateKey},
+		Template:     kt.String(),
+	}, nil
+}
+
+func planCertificate(ct *templates.CertificateTemplate) (*Plan, error) {
+	p := &Plan{ResourceType: model.ResourceTypeCertificate}
+	newKey := ct.PublicKey == nil
+	if newKey {
+		puk, desc, err := planDefaultKey()
+		if err != nil {
+			return nil, err
+		}
+		ct.PublicKey = puk
+		defer func() {
+			ct.PublicKey = nil
+		}()
+		p.PublicKey = desc
+	} else {
+		p.PublicKey = ct.PublicKey.Fingerprint().String()
+	}
+	if err := ValidateCertificateTemplate(ct); err != nil {
+		return nil, err
+	}
+
+	var signer crypto.PublicKey
+	if ct.SelfSigned {
+		if !newKey {
+			if _, err := (resolver{}).resolveKey(config.KeyPath(), ct.PublicKey); err != nil {
+				return nil, fmt.Errorf("no private key found for certificate %s. %v", ct.Subject, err)
+			}
+		}
+		p.Issuer = fmt.Sprintf("self signed %s", ct.Subject)
+		signer = ct.PublicKey.Public()
+	} else {
+		issuer, err := (resolver{}).resolveIssuer(ct.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		cert := &model.Certificate{}
+		ct.ApplyTo(cert)
+		if err := ValidateAgainstIssuer(cert, issuer.Certificate()); err != nil {
+			return nil, err
+		}
+		p.Issuer = fmt.Sprintf("%s (%s)", issuer.Certificate().Subject, issuer.Certificate().Fingerprint())
+		signer = issuer.PublicKey().Public()
+	}
+	sa, err := model.SignatureAlgorithmForKey(ct.SignatureAlgorithm, signer)
+	if err != nil {
+		return nil, err
+	}
+	p.SignatureAlgorithm = sa
+	p.outputTypes = []model.ResourceType{model.ResourceTypeCertificate}
+	if newKey {
+		p.outputTypes = append(p.outputTypes, model.ResourceTypePrivateKey)
+	}
+	p.Outputs = planSavePaths(p.outputTypes)
+	if newKey {
+		// the placeholder key is not part of the template
+		ct.PublicKey = nil
+	}
+	p.Template = ct.String()
+	return p, nil
+}
+
+func planCertificateRequest(t *templates.CertificateRequestTemplate) (*Plan, error) {
+	p := &Plan{ResourceType: model.ResourceTypeCertificateRequest}
+	p.outputTypes = []model.ResourceType{model.ResourceTypeCertificateRequest}
+	newKey := t.PublicKey == nil
+	if newKey {
+		puk, desc, err := planDefaultKey()
+		if err != nil {
+			return nil, err
+		}
+		t.PublicKey = puk
+		defer func() {
+			t.PublicKey = nil
+		}()
+		p.PublicKey = desc
+		p.outputTypes = append(p.outputTypes, model.ResourceTypePrivateKey)
+	} else {
+		if _, err := (resolver{}).resolveKey(config.SearchPath(), t.PublicKey); err != nil {
+			return nil, err
+		}
+		p.PublicKey = t.PublicKey.Fingerprint().String()
+	}
+	if err := ValidateCertificateRequestTemplate(t); err != nil {
+		return nil, err
+	}
+	p.SignatureAlgorithm = t.SignatureAlgorithm
+	p.Outputs = planSavePaths(p.outputTypes)
+	if newKey {
+		// the placeholder key is not part of the template
+		t.PublicKey = nil
+	}
+	p.Template = t.String()
+	return p, nil
+}
+
+// planDefaultKey resolves the default key template, giving a description of the key it would make
+// and a placeholder public key of the same algorithm and size.
+// The placeholder holds no key material, it is only used to validate the template and select its signature algorithm.
+func planDefaultKey() (*model.PublicKey, string, error) {
+	kt, err := DefaultKeyTemplate()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := ValidateKeyTemplate(kt); err != nil {
+		return nil, "", err
+	}
+	var puk crypto.PublicKey
+	switch x509.PublicKeyAlgorithm(kt.KeyAlgoritum) {
+	case x509.RSA:
+		puk = &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), uint(kt.RSAKeyLength-1)), E: 65537}
+	case x509.ECDSA:
+		puk = &ecdsa.PublicKey{Curve: kt.ECDSAKeyCurve.ToCurve()}
+	default:
+		puk = make(ed25519.PublicKey, ed25519.PublicKeySize)
+	}
+	desc := fmt.Sprintf("new %s key, using template %q", kt.KeyAlgoritum.String(), config.DefaultKeyTemplateName())
+	return model.NewPublicKey(puk), desc, nil
+}
+
+func planRevocationList(t *templates.RevocationListTemplate) (*Plan, error) {
+	if err := ValidateCRLTemplate(t); err != nil {
+		return nil, err
+	}
+	issuer, err := repositories.Certificates(config.SearchPath()).ByName(t.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer: %v", err)
+	}
+	puk := model.NewPublicKey(issuer.PublicKey)
+	if _, err := repositories.Keys(config.SearchPath()).ByPublicKey(puk); err != nil {
+		return nil, fmt.Errorf("failed to find key for issuer %s: %v", issuer.Subject.String(), err)
+	}
+	sa, err := model.SignatureAlgorithmForKey(t.SignatureAlgorithm, puk.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{
+		ResourceType:       model.ResourceTypeRevokationList,
+		PublicKey:          puk.Fingerprint().String(),
+		Issuer:             fmt.Sprintf("%s (%s)", issuer.Subject, issuer.Fingerprint()),
+		SignatureAlgorithm: sa,
+		Outputs:            []string{planSavePath(model.ResourceTypeRevokationList)},
+		outputTypes:        []model.ResourceType{model.ResourceTypeRevokationList},
+		Template:           t.String(),
+	}, nil
+}
+
+func planSSHCertificate(t *templates.SSHCertificateTemplate) (*Plan, error) {
+	if err := ValidateSSHCertificateTemplate(t); err != nil {
+		return nil, err
+	}
+	ca, err := resolveSSHCAKey(t.CAKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sshSigner(ca); err != nil {
+		return nil, err
+	}
+	return &Plan{
+		ResourceType: model.ResourceTypeSSHCertificate,
+		PublicKey:    t.PublicKey.Fingerprint().String(),
+		Issuer:       fmt.Sprintf("ssh ca key %s", ca.Public().Fingerprint()),
+		Outputs:      []string{planSavePath(model.ResourceTypeSSHCertificate)},
+		outputTypes:  []model.ResourceType{model.ResourceTypeSSHCertificate},
+		Template:     t.String(),
+	}, nil
+}
+
+// SetOutputPath replaces the outputs of the plan with the given out path, expanding its macros with the given template.
+func (p *Plan) SetOutputPath(out string, t templates.Template) error {
+	fingerprints := make([]string, len(p.outputTypes))
+	for i := range fingerprints {
+		fingerprints[i] = planFingerprint
+	}
+	outputs, err := outputPaths(out, t, p.outputTypes, fingerprints)
+	if err != nil {
+		return err
+	}
+	p.Outputs = outputs
+	return nil
+}
+
+func planSavePaths(types []model.ResourceType) []string {
+	paths := make([]string, len(types))
+	for i, rt := range types {
+		paths[i] = planSavePath(rt)
+	}
+	return paths
+}
+
+// planSavePath gives the path SaveResource would write a resource of the given type.
+// As the fingerprint is not known until the resource is made, a placeholder is used in its place.
+func planSavePath(rt model.ResourceType) string {
+	return filepath.Join(PathForResource(rt), planFingerprint+savedExtension(rt))
+}
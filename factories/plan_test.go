@@ -0,0 +1,45 @@
+package factories
+
+import (
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/templates"
+	"strings"
+	"testing"
+)
+
+func TestPlanMakeNewKey(t *testing.T) {
+	ct := &templates.CertificateRequestTemplate{
+		Subject: model.DistinguishedName{CommonName: "plan"},
+	}
+	p, err := PlanMake(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(p.PublicKey, "new ") {
+		t.Errorf("expected new key description, found %q", p.PublicKey)
+	}
+	if ct.PublicKey != nil {
+		t.Errorf("expected template to have no public key after planning")
+	}
+	if x := p.SignatureAlgorithm.String(); x == "" || strings.EqualFold(x, "unknown") {
+		t.Errorf("expected signature algorithm of the default key, found %q", x)
+	}
+	if len(p.outputTypes) != 2 || p.outputTypes[1] != model.ResourceTypePrivateKey {
+		t.Errorf("expected request and private key outputs, found %v", p.outputTypes)
+	}
+}
+
+func TestPlanSetOutputPath(t *testing.T) {
+	p := &Plan{outputTypes: []model.ResourceType{model.ResourceTypeCertificate, model.ResourceTypePrivateKey}}
+	if err := p.SetOutputPath("out/{{ .fingerprint }}", nil); err != nil {
+		t.Fatal(err)
+	}
+	if p.Outputs[0] == p.Outputs[1] || !strings.HasPrefix(p.Outputs[0], "out/"+planFingerprint) {
+		t.Errorf("expected separate outputs, found %v", p.Outputs)
+	}
+
+	p = &Plan{outputTypes: []model.ResourceType{model.ResourceTypeCertificate, model.ResourceTypeCertificate}}
+	if err := p.SetOutputPath("same.pem", nil); err == nil {
+		t.Errorf("expected duplicate output paths to fail")
+	}
+}
@@ -4,15 +4,17 @@ import (
 	"fmt"
 	"github.com/eurozulu/pempal/config"
 	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/templates"
 	"github.com/eurozulu/pempal/tools"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 func PathForResource(rt model.ResourceType) string {
 	switch rt {
-	case model.ResourceTypeCertificate:
+	case model.ResourceTypeCertificate, model.ResourceTypeSSHCertificate:
 		return config.CertificatePath()
 	case model.ResourceTypePrivateKey:
 		return config.KeyPath()
@@ -53,7 +55,7 @@ func uniqueFileName(path string) string {
 
 func SaveResource(res ...model.PemResource) error {
 	for _, r := range res {
-		name := strings.Join([]string{PublicFingerPrint(r).String(), ".pem"}, "")
+		name := strings.Join([]string{PublicFingerPrint(r).String(), savedExtension(r.ResourceType())}, "")
 		path := PathForResource(r.ResourceType())
 		perm := os.FileMode(0644)
 		if r.ResourceType() == model.ResourceTypePrivateKey {
@@ -77,3 +79,125 @@ func SaveResource(res ...model.PemResource) error {
 	}
 	return nil
 }
+
+// ExtensionForResource gives the file extension used for the given resource type, when an output path has none.
+func ExtensionForResource(rt model.ResourceType) string {
+	switch rt {
+	case model.ResourceTypePrivateKey:
+		return ".key"
+	case model.ResourceTypeCertificateRequest:
+		return ".csr"
+	case model.ResourceTypeRevokationList:
+		return ".crl"
+	case model.ResourceTypeSSHCertificate:
+		return "-cert.pub"
+	default:
+		return ".pem"
+	}
+}
+
+// savedExtension gives the file extension SaveResource uses for the given resource type.
+// SSH certificates are saved in the OpenSSH format, rather than as pems.
+func savedExtension(rt model.ResourceType) string {
+	if rt == model.ResourceTypeSSHCertificate {
+		return ExtensionForResource(rt)
+	}
+	return ".pem"
+}
+
+// OutputPaths gives the file path to write each of the given resources, expanding any macros in the given out path.
+// Macros are expanded using the properties of the given template, along with the 'fingerprint' and 'resource-type' of each resource.
+// When the path has no extension, the extension for the resource type is added.
+// When more than one resource would share the same path, its extension is replaced with the extension for its type.
+func OutputPaths(out string, t templates.Template, res ...model.PemResource) ([]string, error) {
+	types := make([]model.ResourceType, len(res))
+	fingerprints := make([]string, len(res))
+	for i, r := range res {
+		types[i] = r.ResourceType()
+		fingerprints[i] = PublicFingerPrint(r).String()
+	}
+	return outputPaths(out, t, types, fingerprints)
+}
+
+func outputPaths(out string, t templates.Template, types []model.ResourceType, fingerprints []string) ([]string, error) {
+	paths := make([]string, len(types))
+	for i, rt := range types {
+		p, err := templates.ExpandMacros(out, t, map[string]interface{}{
+			"fingerprint":   fingerprints[i],
+			"resource-type": strings.ToLower(rt.String()),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Ext(p) == "" {
+			p = strings.Join([]string{p, ExtensionForResource(rt)}, "")
+		}
+		if slices.Contains(paths[:i], p) {
+			p = strings.Join([]string{strings.TrimSuffix(p, filepath.Ext(p)), ExtensionForResource(rt)}, "")
+			if slices.Contains(paths[:i], p) {
+				return nil, fmt.Errorf("output path %s is the same for multiple resources. Use a macro such as {{ .resource-type }} to separate them", p)
+			}
+		}
+		paths[i] = p
+	}
+	return paths, nil
+}
+
+// publicKeyFileExtension is the extension of the file written alongside an encrypted key, holding its public key.
+// Key searches read it to find the public key of an encrypted key, without decrypting it.
+const publicKeyFileExtension = ".pub"
+
+// WriteResources writes each of the given resources, pem encoded, to the path of the same index.
+// Existing files are only overwritten when force is true. Paths are all checked before any are written.
+// Private keys are written only readable by the owner.
+// Encrypted private keys are written with their public key in a .pub file, alongside the key, so the key is
+// found by its public key, as it is when saved in the key path, named by its fingerprint.
+func WriteResources(paths []string, force bool, res ...model.PemResource) error {
+	if len(paths) != len(res) {
+		return fmt.Errorf("%d paths given for %d resources", len(paths), len(res))
+	}
+	if !force {
+		for i, p := range paths {
+			if tools.IsPathExists(p) {
+				return fmt.Errorf("%s already exists. Use -force to overwrite it", p)
+			}
+			if isEncryptedKey(res[i]) && tools.IsPathExists(p+publicKeyFileExtension) {
+				return fmt.Errorf("%s already exists. Use -force to overwrite it", p+publicKeyFileExtension)
+			}
+		}
+	}
+	for i, r := range res {
+		if err := writeResource(paths[i], r); err != nil {
+			return err
+		}
+		if isEncryptedKey(r) {
+			if err := writeResource(paths[i]+publicKeyFileExtension, r.(*model.PrivateKey).Public()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isEncryptedKey(res model.PemResource) bool {
+	prk, ok := res.(*model.PrivateKey)
+	return ok && prk.IsEncrypted()
+}
+
+func writeResource(path string, res model.PemResource) error {
+	perm := os.FileMode(0644)
+	if res.ResourceType() == model.ResourceTypePrivateKey {
+		perm = 0600
+	}
+	data, err := res.MarshalText()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
@@ -0,0 +1,64 @@
+package factories
+
+import (
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
+	"github.com/eurozulu/pempal/repositories"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteResourcesEncryptedKey(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keys are found relative to the working directory
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(p passphrases.Provider) {
+		passphrases.DefaultProvider = p
+	}(passphrases.DefaultProvider)
+	passphrases.DefaultProvider = passphrases.MappedProvider{"*": passphrases.EnvProvider("TEST_KEY_PASSPHRASE")}
+	t.Setenv("TEST_KEY_PASSPHRASE", "secret")
+
+	prk := testPrivateKey(t, nil)
+	prk.SetPassphrase([]byte("secret"))
+	plain := testPrivateKey(t, nil)
+	paths := []string{filepath.Join("out", "ca.key"), filepath.Join("out", "plain.key")}
+	if err := WriteResources(paths, false, prk, plain); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(paths[0] + ".pub")
+	if err != nil {
+		t.Fatalf("expected a public key file alongside the encrypted key  %v", err)
+	}
+	puk := &model.PublicKey{}
+	if err := puk.UnmarshalText(data); err != nil {
+		t.Fatal(err)
+	}
+	if puk.Fingerprint() != prk.Public().Fingerprint() {
+		t.Errorf("expected the public key of the encrypted key")
+	}
+	if _, err := os.Stat(paths[1] + ".pub"); err == nil {
+		t.Errorf("expected no public key file alongside the unencrypted key")
+	}
+
+	keyz := repositories.Keys("out").MatchByPublicKeyFingerPrint(prk.Public().Fingerprint().String())
+	if len(keyz) != 1 || keyz[0].Fingerprint() != prk.Fingerprint() {
+		t.Errorf("expected the encrypted key to be found by its public key")
+	}
+
+	if err := os.Remove(paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteResources(paths[:1], false, prk); err == nil {
+		t.Errorf("expected an existing public key file not to be overwritten")
+	}
+	if err := WriteResources(paths[:1], true, prk); err != nil {
+		t.Errorf("expected -force to overwrite the public key file  %v", err)
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/eurozulu/pempal/templates"
 )
 
-type RevocationListFactory struct{}
+type RevocationListFactory struct {
+	resolver
+}
 
 func (fac RevocationListFactory) Make(ct *templates.RevocationListTemplate) ([]model.PemResource, error) {
 	err := ValidateCRLTemplate(ct)
@@ -23,16 +25,25 @@ func (fac RevocationListFactory) Make(ct *templates.RevocationListTemplate) ([]m
 		return nil, err
 	}
 
-	issuer, err := repositories.Certificates(config.SearchPath()).ByName(ct.Issuer)
-	if err != nil {
+	var issuer *model.Certificate
+	if unsavedIssuer := fac.unsavedIssuers(func(cert *model.Certificate) bool {
+		return model.DistinguishedName(cert.Subject).Matches(ct.Issuer)
+	}); len(unsavedIssuer) > 0 {
+		issuer = unsavedIssuer[0].Certificate()
+	} else if issuer, err = repositories.Certificates(config.SearchPath()).ByName(ct.Issuer); err != nil {
 		return nil, fmt.Errorf("invalid issuer: %v", err)
 	}
 
 	puk := model.NewPublicKey(issuer.PublicKey)
-	prk, err := repositories.Keys(config.SearchPath()).ByPublicKey(puk)
+	prk, err := fac.resolveKey(config.SearchPath(), puk)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find key for issuer %s: %v", issuer.Issuer.String(), err)
 	}
+	sa, err := model.SignatureAlgorithmForKey(ct.SignatureAlgorithm, puk.Public())
+	if err != nil {
+		return nil, err
+	}
+	rlist.SignatureAlgorithm = x509.SignatureAlgorithm(sa)
 
 	der, err := x509.CreateRevocationList(rand.Reader, (*x509.RevocationList)(rlist), (*x509.Certificate)(issuer), prk.Signer())
 	if err != nil {
@@ -52,8 +63,5 @@ func ValidateCRLTemplate(t templates.Template) error {
 	if ct.Issuer.IsEmpty() {
 		return fmt.Errorf("requires an issue name")
 	}
-	if x509.SignatureAlgorithm(ct.SignatureAlgorithm) == x509.UnknownSignatureAlgorithm {
-		return fmt.Errorf("invalid signature algorithm")
-	}
 	return nil
 }
@@ -0,0 +1,138 @@
+package factories
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/repositories"
+	"github.com/eurozulu/pempal/templates"
+	"github.com/eurozulu/pempal/tools"
+	"golang.org/x/crypto/ssh"
+	"math"
+	"strings"
+	"time"
+)
+
+// sshNeverExpires is the OpenSSH valid-before of a certificate which does not expire
+const sshNeverExpires = math.MaxUint64
+
+type SSHCertificateFactory struct{}
+
+func (sf SSHCertificateFactory) Make(t *templates.SSHCertificateTemplate) ([]model.PemResource, error) {
+	if err := ValidateSSHCertificateTemplate(t); err != nil {
+		return nil, err
+	}
+	ca, err := resolveSSHCAKey(t.CAKey)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := sshSigner(ca)
+	if err != nil {
+		return nil, err
+	}
+	puk, err := ssh.NewPublicKey(t.PublicKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	serial := t.Serial
+	if serial == 0 {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, err
+		}
+		serial = binary.BigEndian.Uint64(b[:])
+	}
+	cert := &ssh.Certificate{
+		Key:             puk,
+		Serial:          serial,
+		CertType:        uint32(t.CertType),
+		KeyId:           t.KeyId,
+		ValidPrincipals: t.Principals,
+		ValidAfter:      sshValidAfter(t.ValidAfter),
+		ValidBefore:     sshValidBefore(t.ValidBefore),
+		Permissions: ssh.Permissions{
+			CriticalOptions: t.CriticalOptions,
+			Extensions:      t.Extensions,
+		},
+	}
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		return nil, err
+	}
+	return []model.PemResource{&model.SSHCertificate{Certificate: cert}}, nil
+}
+
+func ValidateSSHCertificateTemplate(t *templates.SSHCertificateTemplate) error {
+	switch uint32(t.CertType) {
+	case ssh.UserCert, ssh.HostCert:
+	default:
+		return errors.New("Invalid ssh certificate template. cert-type must be user or host")
+	}
+	if t.KeyId == "" {
+		return errors.New("Invalid ssh certificate template. key-id is empty")
+	}
+	if t.PublicKey == nil {
+		return errors.New("Public key is missing")
+	}
+	if t.CAKey == "" {
+		return errors.New("Invalid ssh certificate template. ca-key is empty")
+	}
+	if t.CertType == model.SSHCertType(ssh.HostCert) && len(t.Extensions) > 0 {
+		return errors.New("Invalid ssh certificate template. host certificates do not have extensions")
+	}
+	for _, p := range t.Principals {
+		if strings.TrimSpace(p) == "" {
+			return errors.New("Invalid ssh certificate template. principals contains an empty name")
+		}
+	}
+	if !time.Time(t.ValidBefore).IsZero() && !time.Time(t.ValidBefore).After(time.Time(t.ValidAfter)) {
+		return errors.New("valid-before is not after valid-after")
+	}
+	return nil
+}
+
+// resolveSSHCAKey finds the single key in the key path matching the given fingerprint
+func resolveSSHCAKey(fingerprint string) (*model.PrivateKey, error) {
+	keyz := repositories.Keys(config.KeyPath()).MatchByAnyFingerPrint(fingerprint)
+	if len(keyz) == 0 {
+		return nil, fmt.Errorf("ca key %q not found", fingerprint)
+	}
+	if len(keyz) > 1 {
+		fpz := strings.Join(tools.StringerToString(keyz...), ", ")
+		return nil, fmt.Errorf("ca key %q matches multiple keys: %s", fingerprint, fpz)
+	}
+	return keyz[0], nil
+}
+
+// sshSigner creates an ssh signer of the given key.
+// RSA keys sign with SHA-512, as OpenSSH no longer accepts SHA-1 signatures.
+func sshSigner(prk *model.PrivateKey) (ssh.Signer, error) {
+	signer, err := ssh.NewSignerFromSigner(prk.Signer())
+	if err != nil {
+		return nil, err
+	}
+	if signer.PublicKey().Type() != ssh.KeyAlgoRSA {
+		return signer, nil
+	}
+	as, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return signer, nil
+	}
+	return ssh.NewSignerWithAlgorithms(as, []string{ssh.KeyAlgoRSASHA512})
+}
+
+func sshValidAfter(t model.TimeDTO) uint64 {
+	if time.Time(t).IsZero() {
+		return uint64(time.Now().Unix())
+	}
+	return uint64(time.Time(t).Unix())
+}
+
+func sshValidBefore(t model.TimeDTO) uint64 {
+	if time.Time(t).IsZero() {
+		return sshNeverExpires
+	}
+	return uint64(time.Time(t).Unix())
+}
@@ -0,0 +1,154 @@
+package factories
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/templates"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSSHCertificateFactory(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the ca key is found in the key path, relative to the working directory
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(config.KeyPath(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	caPath := filepath.Join(config.KeyPath(), "ca.pem")
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	caPuk, err := ssh.NewPublicKey(caKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	isCA := func(auth ssh.PublicKey) bool {
+		return bytes.Equal(auth.Marshal(), caPuk.Marshal())
+	}
+	_, userKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	resz, err := SSHCertificateFactory{}.Make(&templates.SSHCertificateTemplate{
+		CertType:        model.SSHCertType(ssh.UserCert),
+		KeyId:           "alice@acme.com",
+		Principals:      model.SSHPrincipals{"alice", "admin"},
+		ValidAfter:      model.TimeDTO(now.Add(-time.Minute)),
+		ValidBefore:     model.TimeDTO(now.Add(time.Hour)),
+		CriticalOptions: map[string]string{"force-command": "/bin/true", "source-address": "127.0.0.1/32"},
+		Extensions:      map[string]string{"permit-pty": ""},
+		PublicKey:       model.NewPublicKey(userKey.Public()),
+		CAKey:           model.NewPublicKey(caKey.Public()).Fingerprint().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userCert := resz[0].(*model.SSHCertificate).Certificate
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority:          isCA,
+		IsHostAuthority:          func(auth ssh.PublicKey, _ string) bool { return isCA(auth) },
+		SupportedCriticalOptions: []string{"force-command"},
+	}
+	conn := testConnMetadata{user: "alice", addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2222}}
+	perms, err := checker.Authenticate(conn, userCert)
+	if err != nil {
+		t.Fatalf("expected user certificate to authenticate  %v", err)
+	}
+	// source-address is enforced by the ssh server, rather than the checker
+	if perms.CriticalOptions["force-command"] != "/bin/true" || perms.CriticalOptions["source-address"] != "127.0.0.1/32" {
+		t.Errorf("expected force-command and source-address critical options, found %v", perms.CriticalOptions)
+	}
+	if _, ok := perms.Extensions["permit-pty"]; !ok || len(perms.Extensions) != 1 {
+		t.Errorf("expected permit-pty extension, found %v", perms.Extensions)
+	}
+	if _, err := checker.Authenticate(testConnMetadata{user: "bob", addr: conn.addr}, userCert); err == nil {
+		t.Errorf("expected user certificate to fail for a user not in its principals")
+	}
+	expired := &ssh.CertChecker{
+		IsUserAuthority:          isCA,
+		SupportedCriticalOptions: []string{"force-command"},
+		Clock:                    func() time.Time { return now.Add(2 * time.Hour) },
+	}
+	if _, err := expired.Authenticate(conn, userCert); err == nil {
+		t.Errorf("expected user certificate to fail after its valid-before")
+	}
+	unsupported := &ssh.CertChecker{IsUserAuthority: isCA}
+	if _, err := unsupported.Authenticate(conn, userCert); err == nil {
+		t.Errorf("expected user certificate to fail when its critical options are not supported")
+	}
+
+	resz, err = SSHCertificateFactory{}.Make(&templates.SSHCertificateTemplate{
+		CertType:   model.SSHCertType(ssh.HostCert),
+		KeyId:      "host.acme.com",
+		Serial:     42,
+		Principals: model.SSHPrincipals{"host.acme.com"},
+		PublicKey:  model.NewPublicKey(hostKey.Public()),
+		CAKey:      model.NewPublicKey(caKey.Public()).Fingerprint().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostCert := resz[0].(*model.SSHCertificate).Certificate
+	if hostCert.Serial != 42 || hostCert.ValidBefore != ssh.CertTimeInfinity {
+		t.Errorf("expected serial 42 and no expiry, found %d, %d", hostCert.Serial, hostCert.ValidBefore)
+	}
+	if len(hostCert.Extensions) != 0 || len(hostCert.CriticalOptions) != 0 {
+		t.Errorf("expected host certificate without options or extensions")
+	}
+	if err := checker.CheckHostKey("host.acme.com:22", conn.addr, hostCert); err != nil {
+		t.Errorf("expected host certificate to be valid for its host  %v", err)
+	}
+	if err := checker.CheckHostKey("other.acme.com:22", conn.addr, hostCert); err == nil {
+		t.Errorf("expected host certificate to fail for another host")
+	}
+	if err := checker.CheckHostKey("host.acme.com:22", conn.addr, userCert); err == nil {
+		t.Errorf("expected user certificate to fail as a host certificate")
+	}
+	if _, err := checker.Authenticate(testConnMetadata{user: "host.acme.com", addr: conn.addr}, hostCert); err == nil {
+		t.Errorf("expected host certificate to fail as a user certificate")
+	}
+}
+
+type testConnMetadata struct {
+	user string
+	addr net.Addr
+}
+
+func (c testConnMetadata) User() string          { return c.user }
+func (c testConnMetadata) SessionID() []byte     { return nil }
+func (c testConnMetadata) ClientVersion() []byte { return nil }
+func (c testConnMetadata) ServerVersion() []byte { return nil }
+func (c testConnMetadata) RemoteAddr() net.Addr  { return c.addr }
+func (c testConnMetadata) LocalAddr() net.Addr   { return c.addr }
@@ -7,9 +7,13 @@ toolchain go1.24.2
 require (
 	github.com/eurozulu/colout v0.0.5
 	github.com/eurozulu/spud v0.6.4
+	golang.org/x/crypto v0.36.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
-require golang.org/x/text v0.22.0 // indirect
+require (
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
 
 replace github.com/eurozulu/spud v0.6.4 => /Users/rpgi/GolandProjects/spud
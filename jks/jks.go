@@ -0,0 +1,303 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf16"
+)
+
+// ErrIncorrectPassword is returned when the password does not match the integrity check or key protection of the keystore
+var ErrIncorrectPassword = errors.New("keystore password incorrect")
+
+const (
+	magicJKS   = 0xFEEDFEED
+	magicJCEKS = 0xCECECECE
+
+	tagPrivateKey  = 1
+	tagTrustedCert = 2
+	tagSecretKey   = 3
+
+	certTypeX509 = "X.509"
+)
+
+// integritySalt is mixed into the keyed hash which ends the keystore
+var integritySalt = []byte("Mighty Aphrodite")
+
+// KeyStore is the content of a Java JKS or JCEKS keystore
+type KeyStore struct {
+	JCEKS               bool
+	PrivateKeys         []*PrivateKeyEntry
+	TrustedCertificates []*TrustedCertificateEntry
+}
+
+// PrivateKeyEntry is an encrypted private key with its certificate chain, the key certificate first.
+type PrivateKeyEntry struct {
+	Alias   string
+	Created time.Time
+	// EncryptedKey is the der of the EncryptedPrivateKeyInfo, protecting the key
+	EncryptedKey []byte
+	Certificates []*x509.Certificate
+}
+
+// TrustedCertificateEntry is a trusted certificate, such as the CAs of a truststore.
+type TrustedCertificateEntry struct {
+	Alias       string
+	Created     time.Time
+	Certificate *x509.Certificate
+}
+
+// IsKeyStore checks if the given data is a JKS or JCEKS keystore
+func IsKeyStore(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	magic := binary.BigEndian.Uint32(data)
+	version := binary.BigEndian.Uint32(data[4:])
+	return (magic == magicJKS || magic == magicJCEKS) && (version == 1 || version == 2)
+}
+
+// Decode decodes a JKS or JCEKS keystore, verifying its integrity with the password.
+// With an empty password the integrity is not verified, so only the certificates should be relied upon.
+// Private keys remain encrypted, use PrivateKeyEntry.Decrypt with their password to decrypt them.
+func Decode(data []byte, password string) (*KeyStore, error) {
+	if !IsKeyStore(data) {
+		return nil, errors.New("not a jks keystore")
+	}
+	if len(data) < 12+sha1.Size {
+		return nil, errors.New("invalid keystore, too short")
+	}
+	if password != "" {
+		if err := VerifyIntegrity(data, password); err != nil {
+			return nil, err
+		}
+	}
+	content := data[:len(data)-sha1.Size]
+
+	r := bytes.NewReader(content)
+	var header struct {
+		Magic   uint32
+		Version uint32
+		Count   uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	ks := &KeyStore{JCEKS: header.Magic == magicJCEKS}
+	for i := uint32(0); i < header.Count; i++ {
+		tag, err := readUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		alias, err := readUTF(r)
+		if err != nil {
+			return nil, err
+		}
+		created, err := readDate(r)
+		if err != nil {
+			return nil, err
+		}
+		switch tag {
+		case tagPrivateKey:
+			entry := &PrivateKeyEntry{Alias: alias, Created: created}
+			if entry.EncryptedKey, err = readBytes(r); err != nil {
+				return nil, err
+			}
+			count, err := readUint32(r)
+			if err != nil {
+				return nil, err
+			}
+			for j := uint32(0); j < count; j++ {
+				c, err := readCertificate(r, header.Version)
+				if err != nil {
+					return nil, fmt.Errorf("invalid certificate of %s  %v", alias, err)
+				}
+				entry.Certificates = append(entry.Certificates, c)
+			}
+			ks.PrivateKeys = append(ks.PrivateKeys, entry)
+		case tagTrustedCert:
+			c, err := readCertificate(r, header.Version)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate of %s  %v", alias, err)
+			}
+			ks.TrustedCertificates = append(ks.TrustedCertificates, &TrustedCertificateEntry{
+				Alias:       alias,
+				Created:     created,
+				Certificate: c,
+			})
+		case tagSecretKey:
+			// secret keys are serialized java objects, without a length to skip them by
+			return nil, fmt.Errorf("secret key entry %s is not supported", alias)
+		default:
+			return nil, fmt.Errorf("unknown keystore entry type %d", tag)
+		}
+	}
+	return ks, nil
+}
+
+// VerifyIntegrity checks the integrity hash, ending the keystore, matches the hash of the password.
+// ErrIncorrectPassword is returned when the password is incorrect or the keystore has been modified.
+func VerifyIntegrity(data []byte, password string) error {
+	if !IsKeyStore(data) {
+		return errors.New("not a jks keystore")
+	}
+	if len(data) < 12+sha1.Size {
+		return errors.New("invalid keystore, too short")
+	}
+	content := data[:len(data)-sha1.Size]
+	if !bytes.Equal(integrityHash(password, content), data[len(content):]) {
+		return ErrIncorrectPassword
+	}
+	return nil
+}
+
+// Encode encodes the keystore, ending it with the integrity hash of the password.
+// Private key entries are written with their existing encrypted key.
+func Encode(ks *KeyStore, password string) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	magic := uint32(magicJKS)
+	if ks.JCEKS {
+		magic = magicJCEKS
+	}
+	writeUint32(buf, magic)
+	writeUint32(buf, 2)
+	writeUint32(buf, uint32(len(ks.PrivateKeys)+len(ks.TrustedCertificates)))
+	for _, entry := range ks.PrivateKeys {
+		writeUint32(buf, tagPrivateKey)
+		if err := writeUTF(buf, entry.Alias); err != nil {
+			return nil, err
+		}
+		writeDate(buf, entry.Created)
+		writeBytes(buf, entry.EncryptedKey)
+		writeUint32(buf, uint32(len(entry.Certificates)))
+		for _, c := range entry.Certificates {
+			if err := writeCertificate(buf, c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, entry := range ks.TrustedCertificates {
+		writeUint32(buf, tagTrustedCert)
+		if err := writeUTF(buf, entry.Alias); err != nil {
+			return nil, err
+		}
+		writeDate(buf, entry.Created)
+		if err := writeCertificate(buf, entry.Certificate); err != nil {
+			return nil, err
+		}
+	}
+	buf.Write(integrityHash(password, buf.Bytes()))
+	return buf.Bytes(), nil
+}
+
+// integrityHash is the SHA-1 of the password, the integrity salt and the content
+func integrityHash(password string, content []byte) []byte {
+	h := sha1.New()
+	h.Write(passwordBytes(password))
+	h.Write(integritySalt)
+	h.Write(content)
+	return h.Sum(nil)
+}
+
+// passwordBytes gives the big endian UTF-16 of the password, as java chars
+func passwordBytes(password string) []byte {
+	chars := utf16.Encode([]rune(password))
+	b := make([]byte, 2*len(chars))
+	for i, c := range chars {
+		binary.BigEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+func readCertificate(r *bytes.Reader, version uint32) (*x509.Certificate, error) {
+	if version == 2 {
+		certType, err := readUTF(r)
+		if err != nil {
+			return nil, err
+		}
+		if certType != certTypeX509 {
+			return nil, fmt.Errorf("certificate type %s is not supported", certType)
+		}
+	}
+	der, err := readBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func writeCertificate(w *bytes.Buffer, c *x509.Certificate) error {
+	if err := writeUTF(w, certTypeX509); err != nil {
+		return err
+	}
+	writeBytes(w, c.Raw)
+	return nil
+}
+
+func readUint32(r io.Reader) (uint32, error) {
+	var n uint32
+	err := binary.Read(r, binary.BigEndian, &n)
+	return n, err
+}
+
+func writeUint32(w *bytes.Buffer, n uint32) {
+	binary.Write(w, binary.BigEndian, n)
+}
+
+// readUTF reads a java modified UTF-8 string, prefixed with its 16 bit length
+func readUTF(r io.Reader) (string, error) {
+	var l uint16
+	if err := binary.Read(r, binary.BigEndian, &l); err != nil {
+		return "", err
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func writeUTF(w *bytes.Buffer, s string) error {
+	if len(s) > 0xFFFF {
+		return fmt.Errorf("%q is too long", s)
+	}
+	binary.Write(w, binary.BigEndian, uint16(len(s)))
+	w.WriteString(s)
+	return nil
+}
+
+// readDate reads the milliseconds since the epoch
+func readDate(r io.Reader) (time.Time, error) {
+	var ms int64
+	if err := binary.Read(r, binary.BigEndian, &ms); err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func writeDate(w *bytes.Buffer, t time.Time) {
+	binary.Write(w, binary.BigEndian, t.UnixMilli())
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	l, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if int64(l) > int64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, l)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+func writeBytes(w *bytes.Buffer, b []byte) {
+	writeUint32(w, uint32(len(b)))
+	w.Write(b)
+}
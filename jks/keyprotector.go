@@ -0,0 +1,120 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+var (
+	// oidJKSKeyProtector is the proprietary SHA-1 key protection of JKS keystores
+	oidJKSKeyProtector = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1}
+	// oidPBEWithMD5AndTripleDES is the proprietary key protection of JCEKS keystores
+	oidPBEWithMD5AndTripleDES = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 19, 1}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// Decrypt decrypts the private key with its password, giving its PKCS#8 der.
+func (e PrivateKeyEntry) Decrypt(password string) ([]byte, error) {
+	var epki encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(e.EncryptedKey, &epki); err != nil {
+		return nil, fmt.Errorf("invalid encrypted key of %s  %v", e.Alias, err)
+	}
+	switch {
+	case epki.Algorithm.Algorithm.Equal(oidJKSKeyProtector):
+		return jksRecover(epki.EncryptedData, password)
+	case epki.Algorithm.Algorithm.Equal(oidPBEWithMD5AndTripleDES):
+		var params pbeParams
+		if _, err := asn1.Unmarshal(epki.Algorithm.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("invalid key protection parameters of %s  %v", e.Alias, err)
+		}
+		return jcePBERecover(epki.EncryptedData, password, params)
+	default:
+		return nil, fmt.Errorf("key protection %s of %s is not supported", epki.Algorithm.Algorithm, e.Alias)
+	}
+}
+
+// jksRecover reverses the JKS key protection.
+// The protected key is a salt, the key XORed with a stream of chained SHA-1 digests of the password and a check digest.
+func jksRecover(protected []byte, password string) ([]byte, error) {
+	if len(protected) < 2*sha1.Size {
+		return nil, errors.New("invalid jks protected key")
+	}
+	salt := protected[:sha1.Size]
+	encrypted := protected[sha1.Size : len(protected)-sha1.Size]
+	check := protected[len(protected)-sha1.Size:]
+	pass := passwordBytes(password)
+
+	key := make([]byte, len(encrypted))
+	digest := salt
+	for i := 0; i < len(key); i += sha1.Size {
+		h := sha1.New()
+		h.Write(pass)
+		h.Write(digest)
+		digest = h.Sum(nil)
+		for j := 0; j < sha1.Size && i+j < len(key); j++ {
+			key[i+j] = encrypted[i+j] ^ digest[j]
+		}
+	}
+	h := sha1.New()
+	h.Write(pass)
+	h.Write(key)
+	if !bytes.Equal(h.Sum(nil), check) {
+		return nil, ErrIncorrectPassword
+	}
+	return key, nil
+}
+
+// jcePBERecover decrypts the PBEWithMD5AndTripleDES protection of JCEKS keystores.
+// The key and iv are derived from each half of the salt, with iterated MD5 digests of the ascii password.
+func jcePBERecover(encrypted []byte, password string, params pbeParams) ([]byte, error) {
+	if len(params.Salt) != 8 {
+		return nil, errors.New("invalid jceks key protection salt")
+	}
+	if len(encrypted) == 0 || len(encrypted)%des.BlockSize != 0 {
+		return nil, errors.New("invalid jceks protected key")
+	}
+	halves := [][]byte{append([]byte{}, params.Salt[:4]...), params.Salt[4:]}
+	if bytes.Equal(halves[0], halves[1]) {
+		// equal halves are 'inverted' as the original java implementation does, which is not a reversal
+		s := halves[0]
+		halves[0] = []byte{s[3], s[0], s[1], s[3]}
+	}
+	var derived []byte
+	for _, half := range halves {
+		digest := half
+		for i := 0; i < params.Iterations; i++ {
+			h := md5.New()
+			h.Write(digest)
+			h.Write([]byte(password))
+			digest = h.Sum(nil)
+		}
+		derived = append(derived, digest...)
+	}
+	block, err := des.NewTripleDESCipher(derived[:24])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, derived[24:]).CryptBlocks(plain, encrypted)
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > des.BlockSize || !bytes.Equal(plain[len(plain)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, ErrIncorrectPassword
+	}
+	return plain[:len(plain)-pad], nil
+}
@@ -0,0 +1,77 @@
+package jws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SetExpiry sets the 'iat' and 'exp' claims of the json object payload, to now and now plus the given duration.
+func SetExpiry(payload []byte, now time.Time, expires time.Duration) ([]byte, error) {
+	claims, err := readClaims(payload)
+	if err != nil {
+		return nil, err
+	}
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(expires).Unix()
+	return json.Marshal(claims)
+}
+
+// CheckExpiry checks the 'nbf' and 'exp' claims of a json object payload, when present, are valid at the given time.
+// Payloads other than json objects are not checked.
+func CheckExpiry(payload []byte, now time.Time) error {
+	if !IsJSONObject(payload) {
+		return nil
+	}
+	claims, err := readClaims(payload)
+	if err != nil {
+		return err
+	}
+	if nbf, ok := claims["nbf"]; ok {
+		t, err := claimTime(nbf)
+		if err != nil {
+			return fmt.Errorf("invalid nbf claim  %v", err)
+		}
+		if now.Before(t) {
+			return fmt.Errorf("token is not valid before %s", t)
+		}
+	}
+	if exp, ok := claims["exp"]; ok {
+		t, err := claimTime(exp)
+		if err != nil {
+			return fmt.Errorf("invalid exp claim  %v", err)
+		}
+		if !now.Before(t) {
+			return fmt.Errorf("token expired at %s", t)
+		}
+	}
+	return nil
+}
+
+func readClaims(payload []byte) (map[string]interface{}, error) {
+	if !IsJSONObject(payload) {
+		return nil, errors.New("payload is not a json object")
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	claims := map[string]interface{}{}
+	if err := dec.Decode(&claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// claimTime reads a NumericDate claim, the seconds since the epoch
+func claimTime(v interface{}) (time.Time, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, errors.New("not a number")
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(f), 0), nil
+}
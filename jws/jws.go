@@ -0,0 +1,257 @@
+package jws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Algorithm is a JWS signature algorithm, as named in RFC 7518
+type Algorithm string
+
+const (
+	RS256 Algorithm = "RS256"
+	RS384 Algorithm = "RS384"
+	RS512 Algorithm = "RS512"
+	PS256 Algorithm = "PS256"
+	PS384 Algorithm = "PS384"
+	PS512 Algorithm = "PS512"
+	ES256 Algorithm = "ES256"
+	ES384 Algorithm = "ES384"
+	ES512 Algorithm = "ES512"
+	EdDSA Algorithm = "EdDSA"
+)
+
+// Header is the protected header of a JWS
+type Header struct {
+	Algorithm Algorithm `json:"alg"`
+	Type      string    `json:"typ,omitempty"`
+	KeyID     string    `json:"kid,omitempty"`
+	// X5C is the base64 (not base64url) der of the signers certificate, followed by its issuers
+	X5C []string `json:"x5c,omitempty"`
+}
+
+// JWS is a JSON web signature in its compact serialisation
+type JWS struct {
+	Header    Header
+	Payload   []byte
+	Signature []byte
+
+	signingInput string
+}
+
+// Certificates parses the certificates of the x5c header
+func (h Header) Certificates() ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for _, s := range h.X5C {
+		der, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x5c certificate  %v", err)
+		}
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x5c certificate  %v", err)
+		}
+		certs = append(certs, c)
+	}
+	return certs, nil
+}
+
+// AlgorithmForKey gives the algorithm for signing with the given public key.
+// RSA keys use RS256, or PS256 when pss is true.  ECDSA keys use the algorithm of their curve.
+func AlgorithmForKey(puk crypto.PublicKey, pss bool) (Algorithm, error) {
+	switch k := puk.(type) {
+	case *rsa.PublicKey:
+		if pss {
+			return PS256, nil
+		}
+		return RS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return ES256, nil
+		case 384:
+			return ES384, nil
+		case 521:
+			return ES512, nil
+		}
+		return "", fmt.Errorf("curve %s has no jws algorithm", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return EdDSA, nil
+	default:
+		return "", fmt.Errorf("%T keys have no jws algorithm", puk)
+	}
+}
+
+// Sign signs the payload with the given key, returning the compact serialisation of the JWS.
+func Sign(payload []byte, header Header, key crypto.Signer) (string, error) {
+	if err := header.Algorithm.checkKey(key.Public()); err != nil {
+		return "", err
+	}
+	hdr, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	input := encode(hdr) + "." + encode(payload)
+	sig, err := header.Algorithm.sign(key, []byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + encode(sig), nil
+}
+
+// Parse parses the compact serialisation of a JWS
+func Parse(token string) (*JWS, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid jws, expected three parts")
+	}
+	hdr, err := decode(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid jws header  %v", err)
+	}
+	j := &JWS{signingInput: parts[0] + "." + parts[1]}
+	if err := json.Unmarshal(hdr, &j.Header); err != nil {
+		return nil, fmt.Errorf("invalid jws header  %v", err)
+	}
+	if j.Payload, err = decode(parts[1]); err != nil {
+		return nil, fmt.Errorf("invalid jws payload  %v", err)
+	}
+	if j.Signature, err = decode(parts[2]); err != nil {
+		return nil, fmt.Errorf("invalid jws signature  %v", err)
+	}
+	return j, nil
+}
+
+// Verify verifies the signature with the given public key.
+// The algorithm of the header must be one for the type of key.
+func (j JWS) Verify(puk crypto.PublicKey) error {
+	alg := j.Header.Algorithm
+	if err := alg.checkKey(puk); err != nil {
+		return err
+	}
+	h := alg.hash()
+	digest := []byte(j.signingInput)
+	if h != 0 {
+		hh := h.New()
+		hh.Write(digest)
+		digest = hh.Sum(nil)
+	}
+	switch k := puk.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(string(alg), "PS") {
+			return rsa.VerifyPSS(k, h, digest, j.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(k, h, digest, j.Signature)
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(j.Signature) != 2*size {
+			return errors.New("invalid ecdsa signature length")
+		}
+		r := new(big.Int).SetBytes(j.Signature[:size])
+		s := new(big.Int).SetBytes(j.Signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("ecdsa verification failure")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, digest, j.Signature) {
+			return errors.New("ed25519 verification failure")
+		}
+		return nil
+	}
+	return fmt.Errorf("%T keys are not supported", puk)
+}
+
+func (alg Algorithm) hash() crypto.Hash {
+	switch alg {
+	case RS256, PS256, ES256:
+		return crypto.SHA256
+	case RS384, PS384, ES384:
+		return crypto.SHA384
+	case RS512, PS512, ES512:
+		return crypto.SHA512
+	default:
+		return 0
+	}
+}
+
+// checkKey checks the algorithm can be used with the given key, so a token can not choose a weaker algorithm.
+func (alg Algorithm) checkKey(puk crypto.PublicKey) error {
+	ok := false
+	switch k := puk.(type) {
+	case *rsa.PublicKey:
+		ok = strings.HasPrefix(string(alg), "RS") || strings.HasPrefix(string(alg), "PS")
+		ok = ok && alg.hash() != 0
+	case *ecdsa.PublicKey:
+		expected, _ := AlgorithmForKey(k, false)
+		ok = alg == expected
+	case ed25519.PublicKey:
+		ok = alg == EdDSA
+	}
+	if !ok {
+		return fmt.Errorf("jws algorithm %q can not be used with a %T key", alg, puk)
+	}
+	return nil
+}
+
+func (alg Algorithm) sign(key crypto.Signer, input []byte) ([]byte, error) {
+	h := alg.hash()
+	if alg == EdDSA {
+		return key.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+	hh := h.New()
+	hh.Write(input)
+	digest := hh.Sum(nil)
+	var opts crypto.SignerOpts = h
+	if strings.HasPrefix(string(alg), "PS") {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h}
+	}
+	sig, err := key.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+	if k, ok := key.Public().(*ecdsa.PublicKey); ok {
+		return ecdsaRawSignature(sig, (k.Curve.Params().BitSize+7)/8)
+	}
+	return sig, nil
+}
+
+// ecdsaRawSignature converts an asn1 ecdsa signature into the fixed length r || s of RFC 7518
+func ecdsaRawSignature(sig []byte, size int) ([]byte, error) {
+	var rs struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(sig, &rs); err != nil {
+		return nil, fmt.Errorf("invalid ecdsa signature  %v", err)
+	}
+	raw := make([]byte, 2*size)
+	rs.R.FillBytes(raw[:size])
+	rs.S.FillBytes(raw[size:])
+	return raw, nil
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// IsJSONObject checks if the given data is a json object
+func IsJSONObject(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{' && json.Valid(data)
+}
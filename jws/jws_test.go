@@ -0,0 +1,88 @@
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ec256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ec384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	payload := []byte(`{"sub":"service"}`)
+	for _, tc := range []struct {
+		key      crypto.Signer
+		pss      bool
+		expected Algorithm
+	}{
+		{rsaKey, false, RS256},
+		{rsaKey, true, PS256},
+		{ec256, false, ES256},
+		{ec384, false, ES384},
+		{edKey, false, EdDSA},
+	} {
+		alg, err := AlgorithmForKey(tc.key.Public(), tc.pss)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if alg != tc.expected {
+			t.Fatalf("expected algorithm %s, found %s", tc.expected, alg)
+		}
+		token, err := Sign(payload, Header{Algorithm: alg, Type: "JWT"}, tc.key)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		j, err := Parse(token)
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if string(j.Payload) != string(payload) {
+			t.Fatalf("%s: unexpected payload %s", alg, j.Payload)
+		}
+		if err := j.Verify(tc.key.Public()); err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		parts := strings.Split(token, ".")
+		altered, err := Parse(parts[0] + "." + encode([]byte(`{"sub":"admin"}`)) + "." + parts[2])
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if err := altered.Verify(tc.key.Public()); err == nil {
+			t.Fatalf("%s: expected altered payload to fail verification", alg)
+		}
+	}
+	token, err := Sign(payload, Header{Algorithm: ES256}, ec256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := Parse(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Header.Algorithm = RS256
+	if err := j.Verify(ec256.Public()); err == nil {
+		t.Fatal("expected algorithm not matching the key to fail verification")
+	}
+}
+
+func TestCheckExpiry(t *testing.T) {
+	now := time.Now()
+	payload, err := SetExpiry([]byte(`{"sub":"service"}`), now, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckExpiry(payload, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckExpiry(payload, now.Add(2*time.Minute)); err == nil {
+		t.Fatal("expected expired token to fail")
+	}
+}
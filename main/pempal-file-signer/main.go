@@ -0,0 +1,60 @@
+// pempal-file-signer is the reference external signer, signing with a private key file.
+// Configure it as a signer in .ppconfig:
+//
+//	signers:
+//	  file-signer: pempal-file-signer private/signer.key
+//
+// Encrypted keys use the same passphrase sources as pempal.
+package main
+
+import (
+	"encoding/pem"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
+	"github.com/eurozulu/pempal/signers"
+	"os"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: pempal-file-signer <private key file>")
+	}
+	prk, err := readKey(args[0])
+	if err != nil {
+		return err
+	}
+	return signers.ServeFileSigner(prk, os.Stdin, os.Stdout)
+}
+
+func readKey(path string) (*model.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for blk, rest := pem.Decode(data); blk != nil; blk, rest = pem.Decode(rest) {
+		if model.ParseResourceType(blk.Type) != model.ResourceTypePrivateKey {
+			continue
+		}
+		if !model.IsEncryptedPem(blk) {
+			return model.NewPrivateKeyFromPem(blk)
+		}
+		pass, err := passphrases.DefaultProvider.Passphrase(path, false)
+		if err != nil {
+			return nil, err
+		}
+		prk, err := model.NewPrivateKeyFromEncryptedPem(blk, pass)
+		if err != nil {
+			passphrases.Invalidate(path)
+		}
+		return prk, err
+	}
+	return nil, fmt.Errorf("no private key found in %s", path)
+}
@@ -60,7 +60,9 @@ func (c *EllipticCurve) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// ParseCurve parses the curve name, with or without a dash, e.g. P256 or P-256
 func ParseCurve(s string) (EllipticCurve, error) {
+	s = strings.ReplaceAll(s, "-", "")
 	for i, n := range CurveNames {
 		if strings.EqualFold(n, s) {
 			return EllipticCurve(i), nil
@@ -0,0 +1,281 @@
+package model
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/scrypt"
+	"hash"
+	"strings"
+)
+
+// PemTypeEncryptedPrivateKey is the pem type of a PKCS#8 encrypted private key
+const PemTypeEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+
+// scrypt cost parameters used to encrypt keys, the same as openssl uses, so its default memory limit can decrypt them
+const (
+	scryptCost        = 1 << 14
+	scryptBlockSize   = 8
+	scryptParallelism = 1
+	scryptSaltLength  = 16
+)
+
+// Limits on the key derivation cost of keys being decrypted, so a crafted key can not stall or exhaust the process.
+const (
+	maxPBKDF2Iterations = 10000000
+	maxScryptMemory     = 1 << 30
+	maxScryptParallel   = 16
+)
+
+var ErrPassphraseRequired = errors.New("private key is encrypted and requires a passphrase")
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidScrypt         = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// encryptedPrivateKeyInfo as defined in RFC 5208
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params as defined in RFC 8018 A.4
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params as defined in RFC 8018 A.2
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// scryptParams as defined in RFC 7914 7.1
+type scryptParams struct {
+	Salt                     []byte
+	CostParameter            int
+	BlockSize                int
+	ParallelizationParameter int
+	KeyLength                int `asn1:"optional"`
+}
+
+// IsEncryptedPem checks if the given pem block is an encrypted private key.
+// Encrypted PKCS#8, legacy encrypted PKCS#1 and SEC1, and encrypted OpenSSH keys are recognised.
+func IsEncryptedPem(blk *pem.Block) bool {
+	switch strings.ToUpper(blk.Type) {
+	case PemTypeEncryptedPrivateKey:
+		return true
+	case PemTypeRSAPrivateKey, PemTypeECPrivateKey:
+		return isLegacyEncryptedPem(blk)
+	case PemTypeOpenSSHPrivateKey:
+		return isOpenSSHEncrypted(blk)
+	default:
+		return false
+	}
+}
+
+// EncryptPKCS8PrivateKey encrypts the given PKCS#8 der key with the passphrase, using PBES2, with scrypt and AES-256-CBC.
+// The result is the der encoding of a PKCS#8 EncryptedPrivateKeyInfo.
+func EncryptPKCS8PrivateKey(der, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrPassphraseRequired
+	}
+	salt := make([]byte, scryptSaltLength)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key(passphrase, salt, scryptCost, scryptBlockSize, scryptParallelism, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	data := pkcs7Pad(der, aes.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	kdfParams, err := asn1.Marshal(scryptParams{
+		Salt:                     salt,
+		CostParameter:            scryptCost,
+		BlockSize:                scryptBlockSize,
+		ParallelizationParameter: scryptParallelism,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidScrypt, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: data,
+	})
+}
+
+// DecryptPKCS8PrivateKey decrypts the given der encoded PKCS#8 EncryptedPrivateKeyInfo, returning the PKCS#8 der key.
+// Only PBES2 with scrypt or PBKDF2 and AES-CBC is supported.
+func DecryptPKCS8PrivateKey(der, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrPassphraseRequired
+	}
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("invalid encrypted private key  %v", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported private key encryption %s. Only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("invalid PBES2 parameters  %v", err)
+	}
+	keyLen, err := aesKeyLength(params.EncryptionScheme.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid AES-CBC iv")
+	}
+	key, err := deriveKey(params.KeyDerivationFunc, passphrase, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted private key length")
+	}
+	data := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, info.EncryptedData)
+	data, err = pkcs7Unpad(data, aes.BlockSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key, incorrect passphrase")
+	}
+	return data, nil
+}
+
+// deriveKey derives the encryption key from the passphrase, using the given scrypt or PBKDF2 key derivation function.
+// Parameters exceeding the cost limits are refused, rather than derived.
+func deriveKey(kdf pkix.AlgorithmIdentifier, passphrase []byte, keyLen int) ([]byte, error) {
+	switch {
+	case kdf.Algorithm.Equal(oidScrypt):
+		var sp scryptParams
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &sp); err != nil {
+			return nil, fmt.Errorf("invalid scrypt parameters  %v", err)
+		}
+		if sp.CostParameter <= 1 || sp.BlockSize <= 0 || sp.ParallelizationParameter <= 0 {
+			return nil, fmt.Errorf("invalid scrypt parameters")
+		}
+		if sp.ParallelizationParameter > maxScryptParallel ||
+			sp.BlockSize > maxScryptMemory/128 || sp.CostParameter > maxScryptMemory/(128*sp.BlockSize) {
+			return nil, fmt.Errorf("scrypt parameters N=%d, r=%d, p=%d exceed the supported limits",
+				sp.CostParameter, sp.BlockSize, sp.ParallelizationParameter)
+		}
+		return scrypt.Key(passphrase, sp.Salt, sp.CostParameter, sp.BlockSize, sp.ParallelizationParameter, keyLen)
+
+	case kdf.Algorithm.Equal(oidPBKDF2):
+		var pp pbkdf2Params
+		if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &pp); err != nil {
+			return nil, fmt.Errorf("invalid PBKDF2 parameters  %v", err)
+		}
+		if pp.IterationCount <= 0 || pp.IterationCount > maxPBKDF2Iterations {
+			return nil, fmt.Errorf("PBKDF2 iteration count %d is outside the supported range of 1 to %d",
+				pp.IterationCount, maxPBKDF2Iterations)
+		}
+		h, err := pbkdf2Hash(pp.PRF.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		return pbkdf2.Key(h, string(passphrase), pp.Salt, pp.IterationCount, keyLen)
+
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function %s. Only scrypt and PBKDF2 are supported", kdf.Algorithm)
+	}
+}
+
+func pbkdf2Hash(oid asn1.ObjectIdentifier) (func() hash.Hash, error) {
+	switch {
+	case len(oid) == 0, oid.Equal(oidHMACWithSHA1):
+		return sha1.New, nil
+	case oid.Equal(oidHMACWithSHA256):
+		return sha256.New, nil
+	case oid.Equal(oidHMACWithSHA384):
+		return sha512.New384, nil
+	case oid.Equal(oidHMACWithSHA512):
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 hash %s", oid)
+	}
+}
+
+func aesKeyLength(oid asn1.ObjectIdentifier) (int, error) {
+	switch {
+	case oid.Equal(oidAES128CBC):
+		return 16, nil
+	case oid.Equal(oidAES192CBC):
+		return 24, nil
+	case oid.Equal(oidAES256CBC):
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("unsupported private key cipher %s. Only AES-CBC is supported", oid)
+	}
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(bytes.Clone(data), bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("invalid padding")
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return data[:len(data)-n], nil
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"crypto"
+	"errors"
+)
+
+// ErrExternalKey is returned when attempting to marshal a key held outside of pempal.
+var ErrExternalKey = errors.New("private key is held externally and can not be exported")
+
+// ExternalKey is a private key held outside of pempal, such as in the key agent.
+// External keys can sign, but never reveal the key itself.
+type ExternalKey interface {
+	crypto.Signer
+	// Fingerprint is the fingerprint of the private key
+	Fingerprint() Fingerprint
+}
+
+// IsExternal returns true if the key is held outside of pempal
+func (k PrivateKey) IsExternal() bool {
+	_, ok := k.prk.(ExternalKey)
+	return ok
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"strings"
+)
+
+// Pem types of the private key encodings, other than PKCS#8, which can be read.
+const (
+	PemTypeRSAPrivateKey     = "RSA PRIVATE KEY"
+	PemTypeECPrivateKey      = "EC PRIVATE KEY"
+	PemTypeOpenSSHPrivateKey = "OPENSSH PRIVATE KEY"
+)
+
+const openSSHKeyMagic = "openssh-key-v1\x00"
+
+// IsNormalisedPem checks if the given private key pem is encoded as PKCS#8, encrypted or otherwise.
+func IsNormalisedPem(blk *pem.Block) bool {
+	return strings.EqualFold(blk.Type, ResourceTypePrivateKey.String()) ||
+		strings.EqualFold(blk.Type, PemTypeEncryptedPrivateKey)
+}
+
+// isLegacyEncryptedPem checks if the pem is encrypted with the legacy OpenSSL 'Proc-Type: 4,ENCRYPTED' header
+func isLegacyEncryptedPem(blk *pem.Block) bool {
+	return strings.Contains(blk.Headers["Proc-Type"], "ENCRYPTED")
+}
+
+// isOpenSSHEncrypted checks if the cipher named in an OpenSSH private key is anything other than 'none'
+func isOpenSSHEncrypted(blk *pem.Block) bool {
+	data, ok := bytes.CutPrefix(blk.Bytes, []byte(openSSHKeyMagic))
+	if !ok || len(data) < 4 {
+		return false
+	}
+	l := binary.BigEndian.Uint32(data)
+	if uint32(len(data)-4) < l {
+		return false
+	}
+	return string(data[4:4+l]) != "none"
+}
+
+// parseKeyPem parses the private key in the given pem, decrypting it with the passphrase when it is encrypted.
+func parseKeyPem(blk *pem.Block, passphrase []byte) (crypto.PrivateKey, error) {
+	if IsEncryptedPem(blk) && len(passphrase) == 0 {
+		return nil, ErrPassphraseRequired
+	}
+	switch strings.ToUpper(blk.Type) {
+	case ResourceTypePrivateKey.String():
+		return x509.ParsePKCS8PrivateKey(blk.Bytes)
+
+	case PemTypeEncryptedPrivateKey:
+		der, err := DecryptPKCS8PrivateKey(blk.Bytes, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return x509.ParsePKCS8PrivateKey(der)
+
+	case PemTypeRSAPrivateKey, PemTypeECPrivateKey:
+		der := blk.Bytes
+		if isLegacyEncryptedPem(blk) {
+			var err error
+			if der, err = x509.DecryptPEMBlock(blk, passphrase); err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key  %v", err)
+			}
+		}
+		if strings.EqualFold(blk.Type, PemTypeRSAPrivateKey) {
+			return x509.ParsePKCS1PrivateKey(der)
+		}
+		return x509.ParseECPrivateKey(der)
+
+	case PemTypeOpenSSHPrivateKey:
+		data := pem.EncodeToMemory(blk)
+		var k interface{}
+		var err error
+		if len(passphrase) > 0 && isOpenSSHEncrypted(blk) {
+			k, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+		} else {
+			k, err = ssh.ParseRawPrivateKey(data)
+		}
+		if err != nil {
+			var missing *ssh.PassphraseMissingError
+			if errors.As(err, &missing) {
+				return nil, ErrPassphraseRequired
+			}
+			return nil, err
+		}
+		// ssh returns ed25519 keys as pointers, unlike x509
+		if pk, ok := k.(*ed25519.PrivateKey); ok {
+			return *pk, nil
+		}
+		return k, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported private key pem %s", blk.Type)
+	}
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/eurozulu/pempal/shamir"
+	"strconv"
+	"strings"
+)
+
+// PemTypeKeyShare is the pem type of a single share of a split private key
+const PemTypeKeyShare = "PRIVATE KEY SHARE"
+
+// KeyShare is one of the shares of a private key, split with Shamir's secret sharing.
+// Key is the fingerprint of the private key and PublicKey that of its public key, so shares can be matched to the key.
+type KeyShare struct {
+	Key       Fingerprint
+	PublicKey Fingerprint
+	Index     int
+	Count     int
+	Threshold int
+	Data      []byte
+}
+
+func (s KeyShare) String() string {
+	return fmt.Sprintf("%s\tshare %d of %d, threshold %d", s.Key, s.Index, s.Count, s.Threshold)
+}
+
+func (s KeyShare) MarshalText() (text []byte, err error) {
+	return pem.EncodeToMemory(&pem.Block{
+		Type: PemTypeKeyShare,
+		Headers: map[string]string{
+			"Key":        s.Key.String(),
+			"Public-Key": s.PublicKey.String(),
+			"Share":      fmt.Sprintf("%d/%d", s.Index, s.Count),
+			"Threshold":  strconv.Itoa(s.Threshold),
+		},
+		Bytes: s.Data,
+	}), nil
+}
+
+// NewKeyShareFromPem reads the key share in the given pem
+func NewKeyShareFromPem(blk *pem.Block) (*KeyShare, error) {
+	if !strings.EqualFold(blk.Type, PemTypeKeyShare) {
+		return nil, fmt.Errorf("pem %s is not a key share", blk.Type)
+	}
+	s := &KeyShare{Data: blk.Bytes}
+	var err error
+	if s.Key, err = ParseFingerPrint(blk.Headers["Key"]); err != nil {
+		return nil, fmt.Errorf("invalid key share key  %v", err)
+	}
+	if s.PublicKey, err = ParseFingerPrint(blk.Headers["Public-Key"]); err != nil {
+		return nil, fmt.Errorf("invalid key share public-key  %v", err)
+	}
+	index, count, _ := strings.Cut(blk.Headers["Share"], "/")
+	if s.Index, err = strconv.Atoi(index); err != nil {
+		return nil, fmt.Errorf("invalid key share index %q", index)
+	}
+	if s.Count, err = strconv.Atoi(count); err != nil {
+		return nil, fmt.Errorf("invalid key share count %q", count)
+	}
+	if s.Threshold, err = strconv.Atoi(blk.Headers["Threshold"]); err != nil {
+		return nil, fmt.Errorf("invalid key share threshold %q", blk.Headers["Threshold"])
+	}
+	return s, nil
+}
+
+// SplitPrivateKey splits the key into count shares, any threshold of which can be combined to recreate the key.
+func SplitPrivateKey(prk *PrivateKey, count, threshold int) ([]*KeyShare, error) {
+	der, err := prk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	data, err := shamir.Split(der, count, threshold)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]*KeyShare, len(data))
+	for i, d := range data {
+		shares[i] = &KeyShare{
+			Key:       prk.Fingerprint(),
+			PublicKey: prk.Public().Fingerprint(),
+			Index:     i + 1,
+			Count:     count,
+			Threshold: threshold,
+			Data:      d,
+		}
+	}
+	return shares, nil
+}
+
+// CombineKeyShares recreates the private key from the given shares.
+// The shares must all be of the same key and meet its threshold.
+func CombineKeyShares(shares []*KeyShare) (*PrivateKey, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no key shares given")
+	}
+	first := shares[0]
+	data := make([][]byte, len(shares))
+	for i, s := range shares {
+		if s.Key != first.Key {
+			return nil, fmt.Errorf("key share %d is of key %s, not %s", s.Index, s.Key, first.Key)
+		}
+		data[i] = s.Data
+	}
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("%d key shares given, %d are required", len(shares), first.Threshold)
+	}
+	der, err := shamir.Combine(data)
+	if err != nil {
+		return nil, err
+	}
+	if NewFingerPrint(der) != first.Key {
+		return nil, fmt.Errorf("key shares did not recreate key %s", first.Key)
+	}
+	prk := &PrivateKey{}
+	if err := prk.UnmarshalBinary(der); err != nil {
+		return nil, err
+	}
+	return prk, nil
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestKeyShareRoundTrip(t *testing.T) {
+	newKey := func(k crypto.PrivateKey) *PrivateKey {
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prk := &PrivateKey{}
+		if err := prk.UnmarshalBinary(der); err != nil {
+			t.Fatal(err)
+		}
+		return prk
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]*PrivateKey{"rsa": newKey(rsaKey), "ecdsa": newKey(ecKey), "ed25519": newKey(edKey)}
+
+	for name, prk := range keys {
+		shares, err := SplitPrivateKey(prk, 5, 3)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// shares are written as pems, one after the other, as to a single file
+		buf := bytes.NewBuffer(nil)
+		for _, s := range shares {
+			data, err := s.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf.Write(data)
+		}
+		var parsed []*KeyShare
+		for data := buf.Bytes(); len(data) > 0; {
+			blk, rest := pem.Decode(data)
+			if blk == nil {
+				break
+			}
+			data = rest
+			s, err := NewKeyShareFromPem(blk)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			parsed = append(parsed, s)
+		}
+		if len(parsed) != 5 {
+			t.Fatalf("%s: expected 5 shares, found %d", name, len(parsed))
+		}
+		for i, s := range parsed {
+			if s.Key != prk.Fingerprint() || s.PublicKey != prk.Public().Fingerprint() ||
+				s.Index != i+1 || s.Count != 5 || s.Threshold != 3 || !bytes.Equal(s.Data, shares[i].Data) {
+				t.Errorf("%s: share %d did not parse as it was split", name, i+1)
+			}
+		}
+
+		for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+			var given []*KeyShare
+			for _, i := range subset {
+				given = append(given, parsed[i])
+			}
+			combined, err := CombineKeyShares(given)
+			if err != nil {
+				t.Errorf("%s: shares %v: %v", name, subset, err)
+				continue
+			}
+			if combined.Fingerprint() != prk.Fingerprint() || combined.Public().Fingerprint() != prk.Public().Fingerprint() {
+				t.Errorf("%s: shares %v did not recreate the key", name, subset)
+			}
+		}
+
+		if _, err := CombineKeyShares(parsed[:2]); err == nil {
+			t.Errorf("%s: expected shares below the threshold to fail", name)
+		}
+		tampered := *parsed[1]
+		tampered.Data = bytes.Clone(tampered.Data)
+		tampered.Data[len(tampered.Data)/2] ^= 1
+		if _, err := CombineKeyShares([]*KeyShare{parsed[0], &tampered, parsed[2]}); err == nil {
+			t.Errorf("%s: expected a tampered share to fail", name)
+		}
+	}
+
+	rsaShares, err := SplitPrivateKey(keys["rsa"], 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecShares, err := SplitPrivateKey(keys["ecdsa"], 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CombineKeyShares([]*KeyShare{rsaShares[0], ecShares[1]}); err == nil {
+		t.Errorf("expected shares of different keys to fail")
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 type KeyUsage x509.KeyUsage
 
+const keyUsageCritical = "Critical"
+
 var keyUsageNames = []string{
 	"UnknownKeyUsage",
 	"DigitalSignature",
@@ -27,10 +29,10 @@ func (k KeyUsage) String() string {
 	var names []string
 	ku := int(k)
 	for i, name := range keyUsageNames {
-		if i == 0 {
+		if i == 0 || name == keyUsageCritical {
 			continue
 		}
-		p := 2 ^ i
+		p := 1 << (i - 1)
 		if (p & ku) != p {
 			continue
 		}
@@ -72,10 +74,16 @@ func ParseKeyUsage(s string) (KeyUsage, error) {
 		}
 		return k, nil
 	}
+	s = strings.TrimSpace(s)
 	for i, n := range keyUsageNames {
-		if strings.EqualFold(n, s) {
-			return KeyUsage(i), nil
+		if !strings.EqualFold(n, s) {
+			continue
+		}
+		// key usage is always marked critical, so Critical is accepted but has no value
+		if i == 0 || n == keyUsageCritical {
+			return KeyUsage(0), nil
 		}
+		return KeyUsage(1 << (i - 1)), nil
 	}
 	return KeyUsage(0), fmt.Errorf("invalid key usage: %q", s)
 }
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestKeyUsageString(t *testing.T) {
+	ku := KeyUsage(x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign)
+	if s := ku.String(); s != "DigitalSignature,CertSign,CRLSign" {
+		t.Fatalf("unexpected key usage names %q", s)
+	}
+}
+
+func TestParseKeyUsage(t *testing.T) {
+	for name, expect := range map[string]x509.KeyUsage{
+		"DigitalSignature": x509.KeyUsageDigitalSignature,
+		"keyEncipherment":  x509.KeyUsageKeyEncipherment,
+		" CertSign ":       x509.KeyUsageCertSign,
+		"DecipherOnly":     x509.KeyUsageDecipherOnly,
+		"Critical":         0,
+	} {
+		ku, err := ParseKeyUsage(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if x509.KeyUsage(ku) != expect {
+			t.Errorf("%s: expected %d, found %d", name, expect, ku)
+		}
+	}
+	if _, err := ParseKeyUsage("NoSuchUsage"); err == nil {
+		t.Errorf("expected unknown key usage to fail")
+	}
+}
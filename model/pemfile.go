@@ -27,6 +27,8 @@ func (f PemFile) Resources() []PemResource {
 			res, err = NewCertificateRequestFromPem(blk)
 		case ResourceTypeRevokationList:
 			res, err = NewRevocationListFromPem(blk)
+		case ResourceTypeSSHCertificate:
+			res, err = NewSSHCertificateFromPem(blk)
 		default:
 			err = fmt.Errorf("unsupported resource type %v ignored", blk.Type)
 		}
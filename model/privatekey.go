@@ -15,7 +15,9 @@ import (
 )
 
 type PrivateKey struct {
-	prk crypto.PrivateKey
+	prk        crypto.PrivateKey
+	passphrase []byte
+	pemType    string
 }
 
 func (k PrivateKey) ResourceType() ResourceType {
@@ -23,6 +25,9 @@ func (k PrivateKey) ResourceType() ResourceType {
 }
 
 func (k PrivateKey) Fingerprint() Fingerprint {
+	if ek, ok := k.prk.(ExternalKey); ok {
+		return ek.Fingerprint()
+	}
 	data, err := k.MarshalBinary()
 	if err != nil {
 		logging.Error("Error marshalling key: %v", err)
@@ -45,7 +50,7 @@ func (k PrivateKey) String() string {
 }
 
 func (k PrivateKey) RSAKeyLength() int {
-	key, ok := k.prk.(*rsa.PrivateKey)
+	key, ok := k.Public().Public().(*rsa.PublicKey)
 	if !ok {
 		return 0
 	}
@@ -53,7 +58,7 @@ func (k PrivateKey) RSAKeyLength() int {
 }
 
 func (k PrivateKey) ECDSACurve() EllipticCurve {
-	key, ok := k.prk.(*ecdsa.PrivateKey)
+	key, ok := k.Public().Public().(*ecdsa.PublicKey)
 	if !ok {
 		return 0
 	}
@@ -75,11 +80,30 @@ func (k PrivateKey) PublicKeyAlgorithm() PublicKeyAlgorithm {
 		return PublicKeyAlgorithm(x509.Ed25519)
 	case *dsa.PrivateKey:
 		return PublicKeyAlgorithm(x509.DSA)
+	case ExternalKey:
+		return k.Public().PublicKeyAlgorithm()
 	default:
 		return PublicKeyAlgorithm(x509.UnknownPublicKeyAlgorithm)
 	}
 }
 
+// IsEncrypted returns true if the key is marshalled as text in its encrypted form.
+func (k PrivateKey) IsEncrypted() bool {
+	return len(k.passphrase) > 0
+}
+
+// SetPassphrase sets the passphrase used to encrypt the key when it is marshalled as text.
+// Setting an empty passphrase marshals the key unencrypted.
+func (k *PrivateKey) SetPassphrase(passphrase []byte) {
+	k.passphrase = passphrase
+}
+
+// IsNormalised returns false if the key was read from an encoding other than PKCS#8,
+// such as PKCS#1, SEC1 or OpenSSH.
+func (k PrivateKey) IsNormalised() bool {
+	return k.pemType == "" || IsNormalisedPem(&pem.Block{Type: k.pemType})
+}
+
 func (k PrivateKey) Private() crypto.PrivateKey {
 	return k.prk
 }
@@ -102,39 +126,70 @@ func (k *PrivateKey) UnmarshalBinary(der []byte) error {
 }
 
 func (k PrivateKey) MarshalBinary() (der []byte, err error) {
+	if k.IsExternal() {
+		return nil, ErrExternalKey
+	}
 	return x509.MarshalPKCS8PrivateKey(k.prk)
 }
 
 // UnmarshalText will parse the data as pem block(s), to find a private key.
 // if data contains multiple pem blocks, the first private key in the data is used.
+// Keys may be PKCS#8, PKCS#1, SEC1 or OpenSSH encoded.  Encrypted keys return ErrPassphraseRequired.
 func (k *PrivateKey) UnmarshalText(data []byte) error {
-	var der []byte
 	for len(data) > 0 {
 		blk, rest := pem.Decode(data)
 		if blk == nil {
 			break
 		}
-		if strings.Contains(blk.Type, ResourceTypePrivateKey.String()) {
-			der = blk.Bytes
-			break
+		if ParseResourceType(blk.Type) == ResourceTypePrivateKey {
+			return k.unmarshalPem(blk, nil)
 		}
 		data = rest
 	}
-	if der == nil {
-		return errors.New("no private key PEM found")
+	return errors.New("no private key PEM found")
+}
+
+func (k *PrivateKey) unmarshalPem(blk *pem.Block, passphrase []byte) error {
+	prk, err := parseKeyPem(blk, passphrase)
+	if err != nil {
+		return err
 	}
-	return k.UnmarshalBinary(der)
+	k.prk = prk
+	k.passphrase = passphrase
+	k.pemType = strings.ToUpper(blk.Type)
+	return nil
 }
 
 // MarshalText marshals the key into a PEM
+// If the key has a passphrase, it is marshalled as an encrypted PKCS#8 key.
 func (k PrivateKey) MarshalText() (text []byte, err error) {
 	der, err := k.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
+	if k.IsEncrypted() {
+		der, err = EncryptPKCS8PrivateKey(der, k.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: PemTypeEncryptedPrivateKey, Bytes: der}), nil
+	}
 	return pem.EncodeToMemory(&pem.Block{Type: ResourceTypePrivateKey.String(), Bytes: der}), nil
 }
 
+// NewPrivateKeyFromEncryptedPem decrypts the given encrypted private key pem, using the passphrase.
+// The key retains the passphrase, so it remains encrypted when marshalled as text.
+func NewPrivateKeyFromEncryptedPem(blk *pem.Block, passphrase []byte) (*PrivateKey, error) {
+	if !IsEncryptedPem(blk) {
+		return nil, fmt.Errorf("pem %s is not an encrypted private key", blk.Type)
+	}
+	prk := &PrivateKey{}
+	if err := prk.unmarshalPem(blk, passphrase); err != nil {
+		return nil, err
+	}
+	return prk, nil
+}
+
 func NewPrivateKeyFromPem(blk *pem.Block) (*PrivateKey, error) {
 	prk := &PrivateKey{}
 	if err := prk.UnmarshalText(pem.EncodeToMemory(blk)); err != nil {
@@ -44,7 +44,7 @@ func (k PublicKey) PublicKeyAlgorithm() PublicKeyAlgorithm {
 		return PublicKeyAlgorithm(x509.RSA)
 	case *ecdsa.PublicKey:
 		return PublicKeyAlgorithm(x509.ECDSA)
-	case *ed25519.PublicKey:
+	case ed25519.PublicKey:
 		return PublicKeyAlgorithm(x509.Ed25519)
 	case *dsa.PublicKey:
 		return PublicKeyAlgorithm(x509.DSA)
@@ -89,6 +89,11 @@ func (k *PublicKey) UnmarshalText(text []byte) error {
 		text = rest
 	}
 	if der == nil {
+		// fall back to an OpenSSH public key line
+		if pukz, err := ParseAuthorizedKeys(text); err == nil && len(pukz) > 0 {
+			k.puk = pukz[0].puk
+			return nil
+		}
 		return errors.New("no public key PEM found")
 	}
 	return k.UnmarshalBinary(der)
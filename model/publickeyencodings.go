@@ -0,0 +1,233 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"math/big"
+	"strings"
+)
+
+// JWK is a public JSON Web Key, as defined in RFC 7517.
+// The kid is the fingerprint of the public key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	// X5c is the certificate chain of the key, as base64 der.
+	X5c []string `json:"x5c,omitempty"`
+}
+
+// JWKSet is a set of JWKs, as defined in RFC 7517 section 5
+type JWKSet struct {
+	Keys []*JWK `json:"keys"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// PublicKey returns the public key of the JWK
+func (j JWK) PublicKey() (*PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := b64.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk n  %v", err)
+		}
+		e, err := b64.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk e  %v", err)
+		}
+		return NewPublicKey(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}), nil
+
+	case "EC":
+		curve := jwkCurve(j.Crv)
+		if curve == nil {
+			return nil, fmt.Errorf("unsupported jwk curve %q", j.Crv)
+		}
+		x, err := b64.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk x  %v", err)
+		}
+		y, err := b64.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwk y  %v", err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid jwk ec point")
+		}
+		puk, err := ecdsaPublicKey(curve, x, y)
+		if err != nil {
+			return nil, err
+		}
+		return NewPublicKey(puk), nil
+
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported jwk curve %q", j.Crv)
+		}
+		x, err := b64.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid jwk Ed25519 key")
+		}
+		return NewPublicKey(ed25519.PublicKey(x)), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported jwk key type %q", j.Kty)
+	}
+}
+
+// NewJWK creates the JWK of the given public key
+func NewJWK(puk *PublicKey) (*JWK, error) {
+	j := &JWK{Kid: puk.Fingerprint().String()}
+	switch k := puk.Public().(type) {
+	case *rsa.PublicKey:
+		j.Kty = "RSA"
+		j.N = b64.EncodeToString(k.N.Bytes())
+		j.E = b64.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		j.Kty = "EC"
+		j.Crv = k.Curve.Params().Name
+		if jwkCurve(j.Crv) == nil {
+			return nil, fmt.Errorf("curve %s is not supported by jwk", j.Crv)
+		}
+		point, err := k.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		data := point.Bytes()[1:]
+		j.X = b64.EncodeToString(data[:len(data)/2])
+		j.Y = b64.EncodeToString(data[len(data)/2:])
+	case ed25519.PublicKey:
+		j.Kty = "OKP"
+		j.Crv = "Ed25519"
+		j.X = b64.EncodeToString(k)
+	default:
+		return nil, fmt.Errorf("key type %s is not supported by jwk", puk.PublicKeyAlgorithm())
+	}
+	return j, nil
+}
+
+// ParseJWKs parses the data as either a single JWK or a JWK Set, returning the public keys it contains.
+func ParseJWKs(data []byte) ([]*PublicKey, error) {
+	var set JWKSet
+	if err := json.Unmarshal(data, &set); err == nil && len(set.Keys) > 0 {
+		pukz := make([]*PublicKey, len(set.Keys))
+		for i, j := range set.Keys {
+			puk, err := j.PublicKey()
+			if err != nil {
+				return nil, err
+			}
+			pukz[i] = puk
+		}
+		return pukz, nil
+	}
+	var j JWK
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	if j.Kty == "" {
+		return nil, errors.New("not a jwk")
+	}
+	puk, err := j.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return []*PublicKey{puk}, nil
+}
+
+// MarshalAuthorizedKey marshals the public key as an OpenSSH authorized_keys line, with the optional comment.
+func (k PublicKey) MarshalAuthorizedKey(comment string) ([]byte, error) {
+	spuk, err := ssh.NewPublicKey(k.puk)
+	if err != nil {
+		return nil, err
+	}
+	line := bytes.TrimSuffix(ssh.MarshalAuthorizedKey(spuk), []byte("\n"))
+	if comment != "" {
+		line = append(append(line, ' '), comment...)
+	}
+	return append(line, '\n'), nil
+}
+
+// ParseAuthorizedKeys parses the data as OpenSSH authorized_keys lines, or public key files, returning their public keys.
+// Blank and comment lines are ignored, as are certificates. See ParseSSHCertificates.
+func ParseAuthorizedKeys(data []byte) ([]*PublicKey, error) {
+	var pukz []*PublicKey
+	scn := bufio.NewScanner(bytes.NewReader(data))
+	for scn.Scan() {
+		line := strings.TrimSpace(scn.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		spuk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := spuk.(*ssh.Certificate); ok {
+			continue
+		}
+		cpuk, ok := spuk.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, fmt.Errorf("ssh key type %s is not supported", spuk.Type())
+		}
+		pukz = append(pukz, NewPublicKey(cpuk.CryptoPublicKey()))
+	}
+	return pukz, scn.Err()
+}
+
+// PublicKeyOf returns the public key of the given resource.
+// Keys, certificates and certificate requests have public keys.
+func PublicKeyOf(res PemResource) (*PublicKey, error) {
+	switch r := res.(type) {
+	case *PublicKey:
+		return r, nil
+	case *PrivateKey:
+		return r.Public(), nil
+	case *Certificate:
+		return NewPublicKey(r.PublicKey), nil
+	case *CertificateRequest:
+		return NewPublicKey(r.PublicKey), nil
+	case *SSHCertificate:
+		return r.PublicKey()
+	default:
+		return nil, fmt.Errorf("%s has no public key", res.ResourceType())
+	}
+}
+
+func jwkCurve(name string) elliptic.Curve {
+	switch name {
+	case "P-256":
+		return elliptic.P256()
+	case "P-384":
+		return elliptic.P384()
+	case "P-521":
+		return elliptic.P521()
+	default:
+		return nil
+	}
+}
+
+func ecdsaPublicKey(curve elliptic.Curve, x, y []byte) (*ecdsa.PublicKey, error) {
+	puk := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	// ECDH validates the point is on the curve
+	if _, err := puk.ECDH(); err != nil {
+		return nil, fmt.Errorf("invalid jwk ec point  %v", err)
+	}
+	return puk, nil
+}
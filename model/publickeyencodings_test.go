@@ -0,0 +1,132 @@
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPublicKeyEncodings(t *testing.T) {
+	var pukz []crypto.PublicKey
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pukz = append(pukz, rsaKey.Public())
+	for _, c := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		k, err := ecdsa.GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pukz = append(pukz, k.Public())
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pukz = append(pukz, edKey)
+
+	for _, k := range pukz {
+		puk := NewPublicKey(k)
+		name := puk.PublicKeyAlgorithm().String()
+
+		line, err := puk.MarshalAuthorizedKey("me@acme.com")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !strings.HasSuffix(string(line), " me@acme.com\n") {
+			t.Errorf("%s: expected comment in authorized key %q", name, line)
+		}
+		ssh, err := ParseAuthorizedKeys(append([]byte("# comment\n\n"), line...))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(ssh) != 1 || ssh[0].Fingerprint() != puk.Fingerprint() {
+			t.Errorf("%s: expected authorized key to parse as the same key", name)
+		}
+
+		jwk, err := NewJWK(puk)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if jwk.Kid != puk.Fingerprint().String() {
+			t.Errorf("%s: expected kid of the key fingerprint, found %s", name, jwk.Kid)
+		}
+		data, err := json.Marshal(jwk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jwks, err := ParseJWKs(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(jwks) != 1 || jwks[0].Fingerprint() != puk.Fingerprint() {
+			t.Errorf("%s: expected jwk to parse as the same key", name)
+		}
+
+		der, err := puk.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		pkix := &PublicKey{}
+		if err := pkix.UnmarshalBinary(der); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		text, err := puk.MarshalText()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		pemKey := &PublicKey{}
+		if err := pemKey.UnmarshalText(text); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if pkix.Fingerprint() != puk.Fingerprint() || pemKey.Fingerprint() != puk.Fingerprint() {
+			t.Errorf("%s: expected pkix der and pem to parse as the same key", name)
+		}
+	}
+}
+
+func TestParseJWKs(t *testing.T) {
+	// the EC key of RFC 7517 appendix A.1 and the Ed25519 key of RFC 8037 appendix A.2, as a JWK set
+	set := `{"keys":[
+		{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM","use":"enc","kid":"1"},
+		{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+	]}`
+	pukz, err := ParseJWKs([]byte(set))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pukz) != 2 {
+		t.Fatalf("expected 2 keys, found %d", len(pukz))
+	}
+	ec, ok := pukz[0].Public().(*ecdsa.PublicKey)
+	if !ok || ec.Curve != elliptic.P256() {
+		t.Fatalf("expected a P-256 key, found %T", pukz[0].Public())
+	}
+	if x := hex.EncodeToString(ec.X.Bytes()); x != "30a0424cd21c2944838a2d75c92b37e76ea20d9f00893a3b4eee8a3c0aafec3e" {
+		t.Errorf("unexpected x %s", x)
+	}
+	ed, ok := pukz[1].Public().(ed25519.PublicKey)
+	if !ok || !bytes.Equal(ed, mustHex(t, "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a")) {
+		t.Errorf("unexpected ed25519 key %x", pukz[1].Public())
+	}
+	if _, err := ParseJWKs([]byte(`{"kty":"EC","crv":"P-256","x":"AAAA","y":"AAAA"}`)); err == nil {
+		t.Errorf("expected a point not on the curve to fail")
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -14,6 +14,7 @@ const (
 	ResourceTypeCertificateRequest
 	ResourceTypeCertificate
 	ResourceTypeRevokationList
+	ResourceTypeSSHCertificate
 )
 
 var resourceTypeNames = []string{
@@ -23,18 +24,26 @@ var resourceTypeNames = []string{
 	"CERTIFICATE REQUEST",
 	"CERTIFICATE",
 	"X509 CRL",
+	"SSH CERTIFICATE",
 }
 
 var aliases = map[string]string{
-	"puk":     "PUBLIC KEY",
-	"prk":     "PRIVATE KEY",
-	"key":     "PRIVATE KEY",
-	"cert":    "CERTIFICATE",
-	"cer":     "CERTIFICATE",
-	"csr":     "CERTIFICATE REQUEST",
-	"request": "CERTIFICATE REQUEST",
-	"req":     "CERTIFICATE REQUEST",
-	"crl":     "X509 CRL",
+	"puk":      "PUBLIC KEY",
+	"prk":      "PRIVATE KEY",
+	"key":      "PRIVATE KEY",
+	"cert":     "CERTIFICATE",
+	"cer":      "CERTIFICATE",
+	"csr":      "CERTIFICATE REQUEST",
+	"request":  "CERTIFICATE REQUEST",
+	"req":      "CERTIFICATE REQUEST",
+	"crl":      "X509 CRL",
+	"sshcert":  "SSH CERTIFICATE",
+	"ssh-cert": "SSH CERTIFICATE",
+
+	"encrypted private key": "PRIVATE KEY",
+	"rsa private key":       "PRIVATE KEY",
+	"ec private key":        "PRIVATE KEY",
+	"openssh private key":   "PRIVATE KEY",
 }
 
 func (rt ResourceType) String() string {
@@ -46,7 +46,7 @@ func (r RevocationList) MarshalText() (text []byte, err error) {
 	}), nil
 }
 
-func (r RevocationList) UnmarshalText(text []byte) error {
+func (r *RevocationList) UnmarshalText(text []byte) error {
 	blk, _ := pem.Decode(text)
 	if blk == nil {
 		return fmt.Errorf("no pem found")
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestRevocationListUnmarshalText(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test ca"},
+		SubjectKeyId: []byte{1, 2, 3, 4},
+		KeyUsage:     x509.KeyUsageCRLSign,
+	}
+	now := time.Now()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(7),
+		ThisUpdate: now,
+		NextUpdate: now.Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: big.NewInt(42), RevocationTime: now},
+		},
+	}, ca, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := NewRevocationListFromPem(&pem.Block{Type: ResourceTypeRevokationList.String(), Bytes: der})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crl.Number == nil || crl.Number.Int64() != 7 {
+		t.Fatalf("expected CRL number 7, found %v", crl.Number)
+	}
+	if len(crl.RevokedCertificateEntries) != 1 || crl.RevokedCertificateEntries[0].SerialNumber.Int64() != 42 {
+		t.Fatalf("expected revoked serial 42")
+	}
+	if crl.Fingerprint() != NewFingerPrint(der) {
+		t.Fatalf("unexpected fingerprint")
+	}
+}
@@ -0,0 +1,198 @@
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PemTypeSignature is the pem type of a detached signature
+const PemTypeSignature = "SIGNATURE"
+
+// Signature is a detached signature of a byte stream.
+// Signer is the fingerprint of the certificate of the signing key and
+// Chain is that certificate followed by its issuers.
+type Signature struct {
+	SignatureAlgorithm SignatureAlgorithm
+	Signer             Fingerprint
+	Chain              []*Certificate
+	Data               []byte
+}
+
+func (s Signature) String() string {
+	return fmt.Sprintf("%s\t%s", s.Signer, s.SignatureAlgorithm)
+}
+
+// MarshalText marshals the signature as a signature pem, followed by the certificate pems of its chain.
+func (s Signature) MarshalText() (text []byte, err error) {
+	buf := bytes.NewBuffer(pem.EncodeToMemory(&pem.Block{
+		Type: PemTypeSignature,
+		Headers: map[string]string{
+			"Signer":              s.Signer.String(),
+			"Signature-Algorithm": s.SignatureAlgorithm.String(),
+		},
+		Bytes: s.Data,
+	}))
+	for _, c := range s.Chain {
+		data, err := c.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseSignature reads the signature pem, and any certificates following it, from the given data.
+func ParseSignature(data []byte) (*Signature, error) {
+	var s *Signature
+	for len(data) > 0 {
+		blk, rest := pem.Decode(data)
+		if blk == nil {
+			break
+		}
+		data = rest
+		switch {
+		case strings.EqualFold(blk.Type, PemTypeSignature):
+			if s != nil {
+				return nil, errors.New("multiple signatures found")
+			}
+			var err error
+			if s, err = newSignatureFromPem(blk); err != nil {
+				return nil, err
+			}
+		case ParseResourceType(blk.Type) == ResourceTypeCertificate && s != nil:
+			c, err := NewCertificateFromPem(blk)
+			if err != nil {
+				return nil, err
+			}
+			s.Chain = append(s.Chain, c)
+		}
+	}
+	if s == nil {
+		return nil, errors.New("no signature pem found")
+	}
+	return s, nil
+}
+
+func newSignatureFromPem(blk *pem.Block) (*Signature, error) {
+	s := &Signature{Data: blk.Bytes}
+	var err error
+	if s.Signer, err = ParseFingerPrint(blk.Headers["Signer"]); err != nil {
+		return nil, fmt.Errorf("invalid signature signer  %v", err)
+	}
+	if err := s.SignatureAlgorithm.UnmarshalText([]byte(blk.Headers["Signature-Algorithm"])); err != nil {
+		return nil, fmt.Errorf("invalid signature algorithm  %v", err)
+	}
+	return s, nil
+}
+
+// Hash gives the hash used by the signature algorithm.
+// Ed25519 signs the message itself and has no hash.
+func (s SignatureAlgorithm) Hash() crypto.Hash {
+	switch x509.SignatureAlgorithm(s) {
+	case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return crypto.SHA1
+	case x509.SHA256WithRSA, x509.SHA256WithRSAPSS, x509.DSAWithSHA256, x509.ECDSAWithSHA256:
+		return crypto.SHA256
+	case x509.SHA384WithRSA, x509.SHA384WithRSAPSS, x509.ECDSAWithSHA384:
+		return crypto.SHA384
+	case x509.SHA512WithRSA, x509.SHA512WithRSAPSS, x509.ECDSAWithSHA512:
+		return crypto.SHA512
+	default:
+		return 0
+	}
+}
+
+// IsPSS checks if the signature algorithm is RSA PSS
+func (s SignatureAlgorithm) IsPSS() bool {
+	switch x509.SignatureAlgorithm(s) {
+	case x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS:
+		return true
+	default:
+		return false
+	}
+}
+
+// SignStream signs the data read from the given reader with the signer.
+// The stream is hashed as it is read, other than for Ed25519, which reads the whole stream to sign.
+func SignStream(r io.Reader, signer crypto.Signer, sa SignatureAlgorithm) ([]byte, error) {
+	if x509.SignatureAlgorithm(sa) == x509.PureEd25519 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	h := sa.Hash()
+	if h == 0 || !h.Available() {
+		return nil, fmt.Errorf("signature algorithm %s is not supported", sa)
+	}
+	hh := h.New()
+	if _, err := io.Copy(hh, r); err != nil {
+		return nil, err
+	}
+	var opts crypto.SignerOpts = h
+	if sa.IsPSS() {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h}
+	}
+	return signer.Sign(rand.Reader, hh.Sum(nil), opts)
+}
+
+// VerifyStream verifies the signature of the data read from the given reader, with the public key.
+func VerifyStream(r io.Reader, puk crypto.PublicKey, sa SignatureAlgorithm, sig []byte) error {
+	if _, err := SignatureAlgorithmForKey(sa, puk); err != nil {
+		return err
+	}
+	if x509.SignatureAlgorithm(sa) == x509.PureEd25519 {
+		k, ok := puk.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s signature can not be verified with a %T key", sa, puk)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if !ed25519.Verify(k, data, sig) {
+			return errors.New("signature is invalid")
+		}
+		return nil
+	}
+	h := sa.Hash()
+	if h == 0 || !h.Available() {
+		return fmt.Errorf("signature algorithm %s is not supported", sa)
+	}
+	hh := h.New()
+	if _, err := io.Copy(hh, r); err != nil {
+		return err
+	}
+	digest := hh.Sum(nil)
+	switch k := puk.(type) {
+	case *rsa.PublicKey:
+		var err error
+		if sa.IsPSS() {
+			err = rsa.VerifyPSS(k, h, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		} else {
+			err = rsa.VerifyPKCS1v15(k, h, digest, sig)
+		}
+		if err != nil {
+			return errors.New("signature is invalid")
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest, sig) {
+			return errors.New("signature is invalid")
+		}
+	default:
+		return fmt.Errorf("%s signature can not be verified with a %T key", sa, puk)
+	}
+	return nil
+}
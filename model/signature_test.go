@@ -0,0 +1,88 @@
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+)
+
+func TestSignVerifyStream(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey := func(c elliptic.Curve) crypto.Signer {
+		k, err := ecdsa.GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog\n"), 1000)
+	tampered := bytes.Clone(payload)
+	tampered[len(tampered)/2] ^= 1
+
+	for _, tc := range []struct {
+		name   string
+		sa     x509.SignatureAlgorithm
+		signer crypto.Signer
+	}{
+		{"rsa", x509.SHA256WithRSA, rsaKey},
+		{"rsa sha512", x509.SHA512WithRSA, rsaKey},
+		{"rsa pss", x509.SHA384WithRSAPSS, rsaKey},
+		{"p256", x509.ECDSAWithSHA256, ecKey(elliptic.P256())},
+		{"p384", x509.ECDSAWithSHA384, ecKey(elliptic.P384())},
+		{"p521", x509.ECDSAWithSHA512, ecKey(elliptic.P521())},
+		{"ed25519", x509.PureEd25519, edKey},
+	} {
+		sa := SignatureAlgorithm(tc.sa)
+		sig, err := SignStream(bytes.NewReader(payload), tc.signer, sa)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if err := VerifyStream(bytes.NewReader(payload), tc.signer.Public(), sa, sig); err != nil {
+			t.Errorf("%s: expected signature to verify  %v", tc.name, err)
+		}
+		if err := VerifyStream(bytes.NewReader(tampered), tc.signer.Public(), sa, sig); err == nil {
+			t.Errorf("%s: expected tampered payload to fail", tc.name)
+		}
+		if err := VerifyStream(bytes.NewReader(payload[:len(payload)-1]), tc.signer.Public(), sa, sig); err == nil {
+			t.Errorf("%s: expected truncated payload to fail", tc.name)
+		}
+		badSig := bytes.Clone(sig)
+		badSig[len(badSig)-1] ^= 1
+		if err := VerifyStream(bytes.NewReader(payload), tc.signer.Public(), sa, badSig); err == nil {
+			t.Errorf("%s: expected tampered signature to fail", tc.name)
+		}
+	}
+
+	// signatures are verified with their own key and algorithm
+	sig, err := SignStream(bytes.NewReader(payload), rsaKey, SignatureAlgorithm(x509.SHA256WithRSA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyStream(bytes.NewReader(payload), otherKey.Public(), SignatureAlgorithm(x509.SHA256WithRSA), sig); err == nil {
+		t.Errorf("expected signature to fail with another key")
+	}
+	if err := VerifyStream(bytes.NewReader(payload), rsaKey.Public(), SignatureAlgorithm(x509.SHA384WithRSA), sig); err == nil {
+		t.Errorf("expected signature to fail with another hash")
+	}
+	if err := VerifyStream(bytes.NewReader(payload), edKey.Public(), SignatureAlgorithm(x509.SHA256WithRSA), sig); err == nil {
+		t.Errorf("expected rsa signature to fail with an ed25519 key")
+	}
+}
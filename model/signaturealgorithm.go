@@ -1,6 +1,10 @@
 package model
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"crypto/x509"
 	"fmt"
 	"strings"
@@ -28,12 +32,33 @@ var signatureNames = []string{
 	"Ed25519",
 }
 
-func (s SignatureAlgorithm) MarshalText() (text []byte, err error) {
+func (s SignatureAlgorithm) String() string {
 	i := int(s)
 	if i < 0 || i >= len(signatureNames) {
 		i = 0
 	}
-	return []byte(signatureNames[i]), nil
+	return signatureNames[i]
+}
+
+func (s SignatureAlgorithm) MarshalText() (text []byte, err error) {
+	return []byte(s.String()), nil
+}
+
+// PublicKeyAlgorithm gives the algorithm of the key required to sign with this signature algorithm.
+func (s SignatureAlgorithm) PublicKeyAlgorithm() PublicKeyAlgorithm {
+	switch x509.SignatureAlgorithm(s) {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA,
+		x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS:
+		return PublicKeyAlgorithm(x509.RSA)
+	case x509.DSAWithSHA1, x509.DSAWithSHA256:
+		return PublicKeyAlgorithm(x509.DSA)
+	case x509.ECDSAWithSHA1, x509.ECDSAWithSHA256, x509.ECDSAWithSHA384, x509.ECDSAWithSHA512:
+		return PublicKeyAlgorithm(x509.ECDSA)
+	case x509.PureEd25519:
+		return PublicKeyAlgorithm(x509.Ed25519)
+	default:
+		return PublicKeyAlgorithm(x509.UnknownPublicKeyAlgorithm)
+	}
 }
 
 func (s *SignatureAlgorithm) UnmarshalText(text []byte) error {
@@ -47,3 +72,44 @@ func (s *SignatureAlgorithm) UnmarshalText(text []byte) error {
 	}
 	return fmt.Errorf("%s is an unknown signature algorithm", sz)
 }
+
+// SignatureAlgorithmForKey gives the signature algorithm to use when signing with the given public key.
+// When the given algorithm is unknown, the default algorithm for the key is returned.
+// Otherwise the given algorithm is returned, providing it can be used with the key.
+func SignatureAlgorithmForKey(sa SignatureAlgorithm, puk crypto.PublicKey) (SignatureAlgorithm, error) {
+	if x509.SignatureAlgorithm(sa) == x509.UnknownSignatureAlgorithm {
+		sa = DefaultSignatureAlgorithm(puk)
+		if x509.SignatureAlgorithm(sa) == x509.UnknownSignatureAlgorithm {
+			return sa, fmt.Errorf("no signature algorithm known for %T keys", puk)
+		}
+		return sa, nil
+	}
+	keyAlgo := NewPublicKey(puk).PublicKeyAlgorithm()
+	if sa.PublicKeyAlgorithm() != keyAlgo {
+		return sa, fmt.Errorf("signature algorithm %s can not be used with a %s key. Use a %s algorithm or leave signature-algorithm unset",
+			sa, keyAlgo, keyAlgo)
+	}
+	return sa, nil
+}
+
+// DefaultSignatureAlgorithm selects the signature algorithm for the given public key, based on its type.
+// RSA keys use SHA256-RSA, whatever their size, ECDSA keys use the hash matching their curve.
+func DefaultSignatureAlgorithm(puk crypto.PublicKey) SignatureAlgorithm {
+	switch k := puk.(type) {
+	case *rsa.PublicKey:
+		return SignatureAlgorithm(x509.SHA256WithRSA)
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().BitSize {
+		case 521:
+			return SignatureAlgorithm(x509.ECDSAWithSHA512)
+		case 384:
+			return SignatureAlgorithm(x509.ECDSAWithSHA384)
+		default:
+			return SignatureAlgorithm(x509.ECDSAWithSHA256)
+		}
+	case ed25519.PublicKey:
+		return SignatureAlgorithm(x509.PureEd25519)
+	default:
+		return SignatureAlgorithm(x509.UnknownSignatureAlgorithm)
+	}
+}
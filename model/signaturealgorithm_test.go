@@ -0,0 +1,71 @@
+package model
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"testing"
+)
+
+func TestSignatureAlgorithmForKey(t *testing.T) {
+	ecKey := func(c elliptic.Curve) crypto.PublicKey {
+		k, err := ecdsa.GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k.Public()
+	}
+	rsaKey := func(bits int) crypto.PublicKey {
+		return &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), uint(bits-1)), E: 65537}
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name   string
+		sa     x509.SignatureAlgorithm
+		puk    crypto.PublicKey
+		expect x509.SignatureAlgorithm
+	}{
+		{"rsa 2048", x509.UnknownSignatureAlgorithm, rsaKey(2048), x509.SHA256WithRSA},
+		{"rsa 3072", x509.UnknownSignatureAlgorithm, rsaKey(3072), x509.SHA256WithRSA},
+		{"rsa 4096", x509.UnknownSignatureAlgorithm, rsaKey(4096), x509.SHA256WithRSA},
+		{"rsa pss", x509.SHA384WithRSAPSS, rsaKey(2048), x509.SHA384WithRSAPSS},
+		{"p224", x509.UnknownSignatureAlgorithm, ecKey(elliptic.P224()), x509.ECDSAWithSHA256},
+		{"p256", x509.UnknownSignatureAlgorithm, ecKey(elliptic.P256()), x509.ECDSAWithSHA256},
+		{"p384", x509.UnknownSignatureAlgorithm, ecKey(elliptic.P384()), x509.ECDSAWithSHA384},
+		{"p521", x509.UnknownSignatureAlgorithm, ecKey(elliptic.P521()), x509.ECDSAWithSHA512},
+		{"p256 sha512", x509.ECDSAWithSHA512, ecKey(elliptic.P256()), x509.ECDSAWithSHA512},
+		{"ed25519", x509.UnknownSignatureAlgorithm, edKey, x509.PureEd25519},
+	} {
+		sa, err := SignatureAlgorithmForKey(SignatureAlgorithm(tc.sa), tc.puk)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if x509.SignatureAlgorithm(sa) != tc.expect {
+			t.Errorf("%s: expected %s, found %s", tc.name, SignatureAlgorithm(tc.expect), sa)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		sa   x509.SignatureAlgorithm
+		puk  crypto.PublicKey
+	}{
+		{"unknown key", x509.UnknownSignatureAlgorithm, struct{}{}},
+		{"rsa with ecdsa key", x509.SHA256WithRSA, ecKey(elliptic.P256())},
+		{"ecdsa with rsa key", x509.ECDSAWithSHA256, rsaKey(2048)},
+		{"ed25519 with ecdsa key", x509.PureEd25519, ecKey(elliptic.P384())},
+	} {
+		if _, err := SignatureAlgorithmForKey(SignatureAlgorithm(tc.sa), tc.puk); err == nil {
+			t.Errorf("%s: expected to fail", tc.name)
+		}
+	}
+}
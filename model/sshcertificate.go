@@ -0,0 +1,205 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"strings"
+	"time"
+)
+
+// SSHCertificate is an OpenSSH user or host certificate
+type SSHCertificate struct {
+	*ssh.Certificate
+}
+
+func (c *SSHCertificate) ResourceType() ResourceType {
+	return ResourceTypeSSHCertificate
+}
+
+func (c *SSHCertificate) String() string {
+	return fmt.Sprintf("%s\t%s %s\t%s", c.Fingerprint(), SSHCertType(c.CertType), c.KeyId, strings.Join(c.ValidPrincipals, ","))
+}
+
+func (c *SSHCertificate) Fingerprint() Fingerprint {
+	if c.Certificate == nil {
+		return Fingerprint{}
+	}
+	return NewFingerPrint(c.Marshal())
+}
+
+// PublicKey returns the public key being certified
+func (c *SSHCertificate) PublicKey() (*PublicKey, error) {
+	cpuk, ok := c.Key.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("ssh key type %s is not supported", c.Key.Type())
+	}
+	return NewPublicKey(cpuk.CryptoPublicKey()), nil
+}
+
+// CAKey returns the public key of the CA which signed the certificate
+func (c *SSHCertificate) CAKey() (*PublicKey, error) {
+	cpuk, ok := c.SignatureKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("ssh key type %s is not supported", c.SignatureKey.Type())
+	}
+	return NewPublicKey(cpuk.CryptoPublicKey()), nil
+}
+
+// ValidAfterTime gives the start of the certificates validity
+func (c *SSHCertificate) ValidAfterTime() time.Time {
+	return sshTime(c.ValidAfter)
+}
+
+// ValidBeforeTime gives the end of the certificates validity
+func (c *SSHCertificate) ValidBeforeTime() time.Time {
+	return sshTime(c.ValidBefore)
+}
+
+// MarshalBinary marshals the certificate in the OpenSSH wire format
+func (c *SSHCertificate) MarshalBinary() (data []byte, err error) {
+	if c.Certificate == nil {
+		return nil, errors.New("no ssh certificate")
+	}
+	return c.Marshal(), nil
+}
+
+// UnmarshalBinary unmarshals the OpenSSH wire format into a certificate
+func (c *SSHCertificate) UnmarshalBinary(data []byte) error {
+	k, err := ssh.ParsePublicKey(data)
+	if err != nil {
+		return err
+	}
+	cert, ok := k.(*ssh.Certificate)
+	if !ok {
+		return fmt.Errorf("%s is not an ssh certificate", k.Type())
+	}
+	c.Certificate = cert
+	return nil
+}
+
+// MarshalText marshals the certificate as an OpenSSH certificate file line, as read by ssh and sshd.
+// The key id is used as the comment.
+func (c *SSHCertificate) MarshalText() (text []byte, err error) {
+	if c.Certificate == nil {
+		return nil, errors.New("no ssh certificate")
+	}
+	line := bytes.TrimSuffix(ssh.MarshalAuthorizedKey(c.Certificate), []byte("\n"))
+	if c.KeyId != "" && !strings.ContainsAny(c.KeyId, "\r\n") {
+		line = append(append(line, ' '), c.KeyId...)
+	}
+	return append(line, '\n'), nil
+}
+
+// UnmarshalText unmarshals the first certificate found in either an OpenSSH certificate file or ssh certificate pem.
+func (c *SSHCertificate) UnmarshalText(text []byte) error {
+	if blk, _ := pem.Decode(text); blk != nil {
+		if !strings.EqualFold(blk.Type, ResourceTypeSSHCertificate.String()) {
+			return fmt.Errorf("%s is not an ssh certificate", blk.Type)
+		}
+		return c.UnmarshalBinary(blk.Bytes)
+	}
+	certs, err := ParseSSHCertificates(text)
+	if err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		return errors.New("no ssh certificate found")
+	}
+	*c = *certs[0]
+	return nil
+}
+
+// ParseSSHCertificates parses the certificates in the given OpenSSH certificate or authorized_keys data.
+// Lines which are not certificates are ignored.
+func ParseSSHCertificates(data []byte) ([]*SSHCertificate, error) {
+	var certs []*SSHCertificate
+	scn := bufio.NewScanner(bytes.NewReader(data))
+	for scn.Scan() {
+		line := strings.TrimSpace(scn.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, err
+		}
+		if cert, ok := k.(*ssh.Certificate); ok {
+			certs = append(certs, &SSHCertificate{Certificate: cert})
+		}
+	}
+	return certs, scn.Err()
+}
+
+func NewSSHCertificateFromPem(blk *pem.Block) (*SSHCertificate, error) {
+	cert := &SSHCertificate{}
+	if err := cert.UnmarshalBinary(blk.Bytes); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// SSHCertType is the type of an ssh certificate, either a user or a host certificate
+type SSHCertType uint32
+
+func (t SSHCertType) String() string {
+	switch uint32(t) {
+	case ssh.UserCert:
+		return "user"
+	case ssh.HostCert:
+		return "host"
+	default:
+		return ""
+	}
+}
+
+func (t SSHCertType) MarshalText() (text []byte, err error) {
+	return []byte(t.String()), nil
+}
+
+func (t *SSHCertType) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "user":
+		*t = SSHCertType(ssh.UserCert)
+	case "host":
+		*t = SSHCertType(ssh.HostCert)
+	case "":
+		*t = 0
+	default:
+		return fmt.Errorf("unknown ssh certificate type %q. Use user or host", string(text))
+	}
+	return nil
+}
+
+// SSHPrincipals are the user or host names of an ssh certificate.
+// They unmarshal from either a yaml list or a comma separated string, so may be given as a single flag.
+type SSHPrincipals []string
+
+func (p *SSHPrincipals) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var names []string
+	if err := unmarshal(&names); err == nil {
+		*p = names
+		return nil
+	}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	*p = nil
+	for _, n := range strings.Split(s, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			*p = append(*p, n)
+		}
+	}
+	return nil
+}
+
+func sshTime(t uint64) time.Time {
+	if t >= uint64(1<<63-1) {
+		return time.Time{}
+	}
+	return time.Unix(int64(t), 0)
+}
@@ -0,0 +1,144 @@
+package passphrases
+
+import (
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/logging"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ENV_PP_PASSPHRASE is the environment variable read for the passphrase of encrypted keys
+const ENV_PP_PASSPHRASE = "PP_PASSPHRASE"
+
+// Provider obtains the passphrase for an encrypted private key.
+// name identifies the key, usually the file path of the key.
+// confirm is true when the passphrase is for a new key, rather than an existing one.
+type Provider interface {
+	Passphrase(name string, confirm bool) ([]byte, error)
+}
+
+// DefaultProvider is the provider used to obtain passphrases for encrypting and decrypting keys.
+// It uses the passphrases mapped to keys in the config, then the PP_PASSPHRASE environment variable,
+// then the default passphrase source of the config and finally, prompts the terminal.
+var DefaultProvider Provider = NewCachedProvider(NewDefaultProvider(config.DefaultPPConfig))
+
+// Invalidate removes the passphrase cached by the default provider for the given name, when it caches passphrases.
+func Invalidate(name string) {
+	if c, ok := DefaultProvider.(*CachedProvider); ok {
+		c.Invalidate(name)
+	}
+}
+
+// NewDefaultProvider creates the provider for the given config.
+// Invalid passphrase sources in the config are logged and ignored.
+func NewDefaultProvider(cfg *config.PPConfig) Provider {
+	mapped := MappedProvider{}
+	for pattern, src := range cfg.Passphrases {
+		p, err := ParseProvider(src)
+		if err != nil {
+			logging.Warning("passphrase for %s ignored: %v", pattern, err)
+			continue
+		}
+		mapped[pattern] = p
+	}
+	chain := ChainProvider{mapped, EnvProvider(ENV_PP_PASSPHRASE)}
+	if cfg.Passphrase != "" {
+		p, err := ParseProvider(cfg.Passphrase)
+		if err != nil {
+			logging.Warning("default passphrase ignored: %v", err)
+		} else {
+			chain = append(chain, p)
+		}
+	}
+	return append(chain, PromptProvider{})
+}
+
+// ChainProvider tries each of its providers in turn, returning the first passphrase found.
+type ChainProvider []Provider
+
+func (c ChainProvider) Passphrase(name string, confirm bool) ([]byte, error) {
+	var errs []error
+	for _, p := range c {
+		pass, err := p.Passphrase(name, confirm)
+		if err == nil {
+			return pass, nil
+		}
+		if err != ErrNoPassphrase {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return nil, fmt.Errorf("no passphrase for %s. Set the passphrase in %s or map it in the config", name, ENV_PP_PASSPHRASE)
+}
+
+// MappedProvider maps key names to the provider of their passphrase.
+// The map keys are file path patterns, matched against the full name and the base name of the key.
+type MappedProvider map[string]Provider
+
+func (m MappedProvider) Passphrase(name string, confirm bool) ([]byte, error) {
+	patterns := make([]string, 0, len(m))
+	for pattern := range m {
+		patterns = append(patterns, pattern)
+	}
+	// longest, most specific patterns first
+	sort.Slice(patterns, func(i, j int) bool {
+		return len(patterns[i]) > len(patterns[j])
+	})
+	for _, pattern := range patterns {
+		if matchName(pattern, name) {
+			return m[pattern].Passphrase(name, confirm)
+		}
+	}
+	return nil, ErrNoPassphrase
+}
+
+func matchName(pattern, name string) bool {
+	if ok, _ := filepath.Match(pattern, name); ok {
+		return true
+	}
+	if ok, _ := filepath.Match(pattern, filepath.Base(name)); ok {
+		return true
+	}
+	if rel, err := filepath.Rel(config.RootPath(), name); err == nil {
+		ok, _ := filepath.Match(pattern, rel)
+		return ok
+	}
+	return false
+}
+
+// CachedProvider remembers the passphrases obtained for each name, so each is only requested once.
+type CachedProvider struct {
+	provider Provider
+	cache    map[string][]byte
+	lock     sync.Mutex
+}
+
+func (c *CachedProvider) Passphrase(name string, confirm bool) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if pass, ok := c.cache[name]; ok {
+		return pass, nil
+	}
+	pass, err := c.provider.Passphrase(name, confirm)
+	if err != nil {
+		return nil, err
+	}
+	c.cache[name] = pass
+	return pass, nil
+}
+
+// Invalidate removes the passphrase cached for the given name, so it is requested again.
+// Call when the passphrase obtained fails to decrypt the key.
+func (c *CachedProvider) Invalidate(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.cache, name)
+}
+
+func NewCachedProvider(p Provider) *CachedProvider {
+	return &CachedProvider{provider: p, cache: map[string][]byte{}}
+}
@@ -0,0 +1,77 @@
+package passphrases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMappedProvider(t *testing.T) {
+	dir := t.TempDir()
+	passFile := filepath.Join(dir, "pass")
+	if err := os.WriteFile(passFile, []byte("filepass\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_PP_PASS", "envpass")
+	mp := MappedProvider{}
+	for pattern, src := range map[string]string{
+		"root*.key":  "file:" + passFile,
+		"*.key":      "env:TEST_PP_PASS",
+		"ci/web.key": "cmd:echo $PP_KEY_NAME",
+	} {
+		p, err := ParseProvider(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mp[pattern] = p
+	}
+	for name, expect := range map[string]string{
+		"private/root-ca.key": "filepass",
+		"private/other.key":   "envpass",
+		"ci/web.key":          "ci/web.key",
+	} {
+		pass, err := mp.Passphrase(name, false)
+		if err != nil {
+			t.Errorf("unexpected error for %s  %v", name, err)
+			continue
+		}
+		if string(pass) != expect {
+			t.Errorf("expected passphrase %q for %s, found %q", expect, name, pass)
+		}
+	}
+	if _, err := mp.Passphrase("private/other.pem", false); err != ErrNoPassphrase {
+		t.Errorf("expected no passphrase for unmapped name, found %v", err)
+	}
+	if _, err := ParseProvider("vault:root"); err == nil {
+		t.Errorf("expected unknown source to fail")
+	}
+}
+
+type countingProvider struct {
+	count int
+}
+
+func (p *countingProvider) Passphrase(name string, confirm bool) ([]byte, error) {
+	p.count++
+	return []byte(name), nil
+}
+
+func TestCachedProviderInvalidate(t *testing.T) {
+	cp := &countingProvider{}
+	c := NewCachedProvider(cp)
+	for i := 0; i < 2; i++ {
+		if _, err := c.Passphrase("key", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cp.count != 1 {
+		t.Errorf("expected passphrase to be requested once, found %d", cp.count)
+	}
+	c.Invalidate("key")
+	if _, err := c.Passphrase("key", false); err != nil {
+		t.Fatal(err)
+	}
+	if cp.count != 2 {
+		t.Errorf("expected invalidated passphrase to be requested again, found %d", cp.count)
+	}
+}
@@ -0,0 +1,156 @@
+package passphrases
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ENV_PP_KEY_NAME is set in the environment of passphrase commands, to the name of the key requiring the passphrase.
+const ENV_PP_KEY_NAME = "PP_KEY_NAME"
+
+// ErrNoPassphrase is returned by providers which do not have a passphrase for the requested key.
+var ErrNoPassphrase = errors.New("no passphrase available")
+
+// ParseProvider creates the provider for the given passphrase source.
+// Sources take the form of "<type>:<value>", where type is one of:
+// env: the name of an environment variable
+// file: the path of a file containing the passphrase
+// cmd: a command, whose output is the passphrase
+// prompt: prompts the terminal, taking no value
+func ParseProvider(source string) (Provider, error) {
+	kind, value, _ := strings.Cut(source, ":")
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "env":
+		if value == "" {
+			return nil, fmt.Errorf("passphrase source %q has no variable name", source)
+		}
+		return EnvProvider(value), nil
+	case "file":
+		if value == "" {
+			return nil, fmt.Errorf("passphrase source %q has no file path", source)
+		}
+		return FileProvider(value), nil
+	case "cmd":
+		if value == "" {
+			return nil, fmt.Errorf("passphrase source %q has no command", source)
+		}
+		return CommandProvider(value), nil
+	case "prompt":
+		return PromptProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown passphrase source %q. Use env:, file:, cmd: or prompt", source)
+	}
+}
+
+// EnvProvider provides the passphrase from the environment variable of its name.
+type EnvProvider string
+
+func (e EnvProvider) Passphrase(name string, confirm bool) ([]byte, error) {
+	s, ok := os.LookupEnv(string(e))
+	if !ok || s == "" {
+		return nil, ErrNoPassphrase
+	}
+	return []byte(s), nil
+}
+
+// FileProvider provides the passphrase from the first line of the file at its path.
+type FileProvider string
+
+func (f FileProvider) Passphrase(name string, confirm bool) ([]byte, error) {
+	path := os.ExpandEnv(string(f))
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase file for %s  %v", name, err)
+	}
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) == 0 {
+		return nil, fmt.Errorf("passphrase file %s is empty", path)
+	}
+	return line, nil
+}
+
+// CommandProvider provides the passphrase from the output of its command.
+// The command is run by the shell, with the key name in the PP_KEY_NAME environment variable.
+type CommandProvider string
+
+func (c CommandProvider) Passphrase(name string, confirm bool) ([]byte, error) {
+	cmd := exec.Command("sh", "-c", string(c))
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", ENV_PP_KEY_NAME, name))
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("passphrase command for %s failed  %v", name, err)
+	}
+	out = bytes.TrimRight(out, "\r\n")
+	if len(out) == 0 {
+		return nil, fmt.Errorf("passphrase command for %s returned no passphrase", name)
+	}
+	return out, nil
+}
+
+// PromptProvider prompts the terminal for the passphrase.
+// New keys are prompted twice, to confirm the passphrase.
+// When there is no terminal, such as when running unattended, no passphrase is available.
+type PromptProvider struct{}
+
+func (p PromptProvider) Passphrase(name string, confirm bool) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, ErrNoPassphrase
+	}
+	defer tty.Close()
+	pass, err := readHidden(tty, fmt.Sprintf("passphrase for %s: ", name))
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, fmt.Errorf("no passphrase entered for %s", name)
+	}
+	if confirm {
+		again, err := readHidden(tty, "confirm passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(pass, again) {
+			return nil, fmt.Errorf("passphrases for %s do not match", name)
+		}
+	}
+	return pass, nil
+}
+
+// readHidden reads a line from the terminal, with echo turned off
+func readHidden(tty *os.File, prompt string) ([]byte, error) {
+	if _, err := fmt.Fprint(tty, prompt); err != nil {
+		return nil, err
+	}
+	if err := stty(tty, "-echo"); err == nil {
+		defer func() {
+			_ = stty(tty, "echo")
+			fmt.Fprintln(tty)
+		}()
+	}
+	line, err := bufio.NewReader(tty).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+func stty(tty *os.File, arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = tty
+	return cmd.Run()
+}
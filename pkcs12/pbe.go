@@ -0,0 +1,320 @@
+package pkcs12
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"unicode/utf16"
+)
+
+var (
+	// legacy password based encryption of RFC 7292 appendix C
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd128BitRC2CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// Identifiers of the keys derived by the PKCS#12 KDF
+const (
+	kdfKey byte = 1
+	kdfIV  byte = 2
+	kdfMAC byte = 3
+)
+
+// pbeParams are the parameters of the legacy encryption algorithms
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decrypt decrypts data encrypted with the given password based encryption algorithm
+func decrypt(alg pkix.AlgorithmIdentifier, password string, data []byte) ([]byte, error) {
+	block, iv, err := pbeCipher(alg, password)
+	if err != nil {
+		return nil, err
+	}
+	size := block.BlockSize()
+	if len(data) == 0 || len(data)%size != 0 {
+		return nil, errors.New("encrypted data is not a multiple of the block size")
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > size || !bytes.Equal(plain[len(plain)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, ErrIncorrectPassword
+	}
+	return plain[:len(plain)-pad], nil
+}
+
+// encrypt encrypts data with the given password based encryption algorithm
+func encrypt(alg pkix.AlgorithmIdentifier, password string, data []byte) ([]byte, error) {
+	block, iv, err := pbeCipher(alg, password)
+	if err != nil {
+		return nil, err
+	}
+	size := block.BlockSize()
+	pad := size - len(data)%size
+	encrypted := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+	return encrypted, nil
+}
+
+// pbeCipher derives the cipher and iv of the password based encryption algorithm
+func pbeCipher(alg pkix.AlgorithmIdentifier, password string) (cipher.Block, []byte, error) {
+	if alg.Algorithm.Equal(oidPBES2) {
+		return pbes2Cipher(alg, password)
+	}
+	var params pbeParams
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, fmt.Errorf("invalid pbe parameters  %v", err)
+	}
+	pass := bmpString(password)
+	switch {
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+		key := pkcs12KDF(sha1.New, kdfKey, pass, params.Salt, params.Iterations, 24)
+		block, err := des.NewTripleDESCipher(key)
+		return block, pkcs12KDF(sha1.New, kdfIV, pass, params.Salt, params.Iterations, 8), err
+	case alg.Algorithm.Equal(oidPBEWithSHAAnd128BitRC2CBC), alg.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		keyLen := 16
+		if alg.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC) {
+			keyLen = 5
+		}
+		key := pkcs12KDF(sha1.New, kdfKey, pass, params.Salt, params.Iterations, keyLen)
+		block, err := newRC2Cipher(key, keyLen*8)
+		return block, pkcs12KDF(sha1.New, kdfIV, pass, params.Salt, params.Iterations, 8), err
+	default:
+		return nil, nil, fmt.Errorf("encryption algorithm %s is not supported", alg.Algorithm)
+	}
+}
+
+// pbes2Cipher derives the cipher of PBES2 encryption, RFC 8018.  The password is used as its utf8 bytes.
+func pbes2Cipher(alg pkix.AlgorithmIdentifier, password string) (cipher.Block, []byte, error) {
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, fmt.Errorf("invalid pbes2 parameters  %v", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("key derivation function %s is not supported", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, fmt.Errorf("invalid pbkdf2 parameters  %v", err)
+	}
+	prf := sha1.New
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA384):
+		prf = sha512.New384
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, nil, fmt.Errorf("pbkdf2 prf %s is not supported", kdf.PRF.Algorithm)
+	}
+
+	scheme := params.EncryptionScheme
+	var keyLen int
+	newCipher := aes.NewCipher
+	switch {
+	case scheme.Algorithm.Equal(oidAES128CBC):
+		keyLen = 16
+	case scheme.Algorithm.Equal(oidAES192CBC):
+		keyLen = 24
+	case scheme.Algorithm.Equal(oidAES256CBC):
+		keyLen = 32
+	case scheme.Algorithm.Equal(oidDESEDE3CBC):
+		keyLen = 24
+		newCipher = des.NewTripleDESCipher
+	default:
+		return nil, nil, fmt.Errorf("encryption scheme %s is not supported", scheme.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(scheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, fmt.Errorf("invalid encryption iv  %v", err)
+	}
+	key, err := pbkdf2.Key(prf, password, kdf.Salt, kdf.Iterations, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, nil, errors.New("invalid encryption iv length")
+	}
+	return block, iv, nil
+}
+
+// newPBES2Algorithm creates the algorithm of AES-256-CBC encryption, with a key from PBKDF2 with HMAC-SHA256
+func newPBES2Algorithm(iterations int) (pkix.AlgorithmIdentifier, error) {
+	salt := make([]byte, 16)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	kdf, err := asn1.Marshal(pbkdf2Params{
+		Salt:       salt,
+		Iterations: iterations,
+		PRF:        pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	ivDER, err := asn1.Marshal(iv)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdf}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivDER}},
+	})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+// newLegacyAlgorithm creates the algorithm of the legacy triple DES encryption, for older consumers
+func newLegacyAlgorithm(iterations int) (pkix.AlgorithmIdentifier, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	params, err := asn1.Marshal(pbeParams{Salt: salt, Iterations: iterations})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBEWithSHAAnd3KeyTripleDESCBC, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+// computeMAC computes the HMAC of the data, with a key derived by the PKCS#12 KDF from the bmp encoded password
+func computeMAC(h crypto.Hash, password, salt []byte, iterations int, data []byte) []byte {
+	key := pkcs12KDF(h.New, kdfMAC, password, salt, iterations, h.Size())
+	mac := hmac.New(h.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func macHash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA1):
+		return crypto.SHA1, nil
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("mac algorithm %s is not supported", oid)
+	}
+}
+
+// pkcs12KDF derives a key of the given length, as defined in RFC 7292 appendix B.2
+func pkcs12KDF(newHash func() hash.Hash, id byte, password, salt []byte, iterations, length int) []byte {
+	h := newHash()
+	v := h.BlockSize()
+	d := bytes.Repeat([]byte{id}, v)
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		n := v * ((len(b) + v - 1) / v)
+		out := make([]byte, n)
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	i := append(fill(salt), fill(password)...)
+	one := big.NewInt(1)
+	var key []byte
+	for len(key) < length {
+		h.Reset()
+		h.Write(d)
+		h.Write(i)
+		a := h.Sum(nil)
+		for r := 1; r < iterations; r++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		key = append(key, a...)
+		if len(key) >= length {
+			break
+		}
+		// I_j = (I_j + B + 1) mod 2^v, for each v byte block of I
+		b := new(big.Int).SetBytes(fill(a)[:v])
+		b.Add(b, one)
+		for j := 0; j < len(i); j += v {
+			ij := new(big.Int).SetBytes(i[j : j+v])
+			ij.Add(ij, b)
+			ijb := ij.Bytes()
+			if len(ijb) > v {
+				ijb = ijb[len(ijb)-v:]
+			}
+			block := i[j : j+v]
+			clear(block)
+			copy(block[v-len(ijb):], ijb)
+		}
+	}
+	return key[:length]
+}
+
+// bmpString encodes the password as a null terminated, big endian UTF-16 string, as used by the PKCS#12 KDF
+func bmpString(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(c>>8), byte(c))
+	}
+	return append(b, 0, 0)
+}
@@ -0,0 +1,439 @@
+package pkcs12
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// ErrIncorrectPassword is returned when the password does not match the mac or encryption of the PKCS#12
+var ErrIncorrectPassword = errors.New("pkcs12 password incorrect")
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidCRLBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 4}
+
+	oidCertTypeX509 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidCRLTypeX509  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 23, 1}
+
+	oidFriendlyName = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+)
+
+// Iterations of the key derivation used to encrypt and mac
+const Iterations = 2048
+
+// PKCS12 is the content of a PKCS#12 (PFX) file
+type PKCS12 struct {
+	// PrivateKeys is the PKCS#8 der of each private key
+	PrivateKeys  [][]byte
+	Certificates []*x509.Certificate
+	CRLs         []*x509.RevocationList
+}
+
+type pfx struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+// contentInfo Content is the [0] EXPLICIT content, its Bytes being the der of the inner content.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// safeBag Value is the [0] EXPLICIT value, its Bytes being the der of the inner value.
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue `asn1:"tag:0"`
+	Attributes []attribute   `asn1:"set,optional"`
+}
+
+type attribute struct {
+	ID     asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// certBag is also the structure of a crlBag
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"explicit,tag:0"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// IsPKCS12 checks if the given data is the der of a PKCS#12
+func IsPKCS12(data []byte) bool {
+	var p pfx
+	rest, err := asn1.Unmarshal(data, &p)
+	return err == nil && len(rest) == 0 && p.Version == 3 && p.AuthSafe.ContentType.Equal(oidData)
+}
+
+// Decode decodes the PKCS#12 der, verifying its mac and decrypting its content with the password.
+func Decode(data []byte, password string) (*PKCS12, error) {
+	var p pfx
+	if rest, err := asn1.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid pkcs12  %v", err)
+	} else if len(rest) > 0 {
+		return nil, errors.New("invalid pkcs12, trailing data")
+	}
+	if p.Version != 3 {
+		return nil, fmt.Errorf("pkcs12 version %d is not supported", p.Version)
+	}
+	if !p.AuthSafe.ContentType.Equal(oidData) {
+		return nil, errors.New("only password integrity pkcs12 is supported")
+	}
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(p.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, fmt.Errorf("invalid pkcs12 content  %v", err)
+	}
+	if len(p.MacData.Mac.Algorithm.Algorithm) > 0 {
+		if err := verifyMAC(p.MacData, password, authSafe); err != nil {
+			return nil, err
+		}
+	}
+
+	var contents []contentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		return nil, fmt.Errorf("invalid pkcs12 content  %v", err)
+	}
+	p12 := &PKCS12{}
+	for _, ci := range contents {
+		bags, err := safeContents(ci, password)
+		if err != nil {
+			return nil, err
+		}
+		for _, bag := range bags {
+			if err := p12.addBag(bag, password); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p12, nil
+}
+
+// Encode encodes the key, its certificate and the chain of its issuers as a password protected PKCS#12.
+// The key may be nil, to encode only certificates.
+// Unless legacy, the key and certificates are encrypted with AES-256-CBC, using PBES2 and the mac uses SHA-256.
+// Legacy uses triple DES and a SHA-1 mac, for older consumers which do not support PBES2.
+func Encode(key crypto.PrivateKey, certs []*x509.Certificate, password string, legacy bool) ([]byte, error) {
+	newAlgorithm := newPBES2Algorithm
+	macAlg := crypto.SHA256
+	if legacy {
+		newAlgorithm = newLegacyAlgorithm
+		macAlg = crypto.SHA1
+	}
+
+	var localKeyID []byte
+	var friendlyName string
+	if len(certs) > 0 {
+		id := sha1.Sum(certs[0].Raw)
+		localKeyID = id[:]
+		friendlyName = certs[0].Subject.CommonName
+	}
+	var certBags []safeBag
+	for i, c := range certs {
+		bag, err := newCertBag(c)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 && key != nil {
+			if bag.Attributes, err = bagAttributes(friendlyName, localKeyID); err != nil {
+				return nil, err
+			}
+		}
+		certBags = append(certBags, bag)
+	}
+	var contents []contentInfo
+	if len(certBags) > 0 {
+		ci, err := encryptedContents(certBags, password, newAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, ci)
+	}
+	if key != nil {
+		bag, err := newShroudedKeyBag(key, password, newAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		if bag.Attributes, err = bagAttributes(friendlyName, localKeyID); err != nil {
+			return nil, err
+		}
+		ci, err := dataContents([]safeBag{bag})
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, ci)
+	}
+
+	authSafe, err := asn1.Marshal(contents)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	macOID := oidSHA256
+	if legacy {
+		macOID = oidSHA1
+	}
+	authSafeContent, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pfx{
+		Version:  3,
+		AuthSafe: contentInfo{ContentType: oidData, Content: asn1.RawValue{FullBytes: explicit(authSafeContent)}},
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: macOID, Parameters: asn1.NullRawValue},
+				Digest:    computeMAC(macAlg, bmpString(password), salt, Iterations, authSafe),
+			},
+			MacSalt:    salt,
+			Iterations: Iterations,
+		},
+	})
+}
+
+// verifyMAC verifies the mac of the content.  Empty passwords are tried both with and without a null terminator.
+func verifyMAC(md macData, password string, content []byte) error {
+	h, err := macHash(md.Mac.Algorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+	passwords := [][]byte{bmpString(password)}
+	if password == "" {
+		passwords = append(passwords, nil)
+	}
+	for _, pass := range passwords {
+		if hmac.Equal(computeMAC(h, pass, md.MacSalt, md.Iterations, content), md.Mac.Digest) {
+			return nil
+		}
+	}
+	return ErrIncorrectPassword
+}
+
+// safeContents reads the bags of the content, decrypting them when encrypted
+func safeContents(ci contentInfo, password string) ([]safeBag, error) {
+	var data []byte
+	switch {
+	case ci.ContentType.Equal(oidData):
+		if _, err := asn1.Unmarshal(ci.Content.Bytes, &data); err != nil {
+			return nil, fmt.Errorf("invalid pkcs12 safe contents  %v", err)
+		}
+	case ci.ContentType.Equal(oidEncryptedData):
+		var ed encryptedData
+		if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+			return nil, fmt.Errorf("invalid pkcs12 encrypted data  %v", err)
+		}
+		encrypted, err := octets(ed.EncryptedContentInfo.EncryptedContent)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = decrypt(ed.EncryptedContentInfo.ContentEncryptionAlgorithm, password, encrypted); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("pkcs12 content type %s is not supported", ci.ContentType)
+	}
+	var bags []safeBag
+	if _, err := asn1.Unmarshal(data, &bags); err != nil {
+		return nil, fmt.Errorf("invalid pkcs12 safe contents  %v", err)
+	}
+	return bags, nil
+}
+
+// addBag adds the key, certificate or crl of the bag.  Other bag types are ignored.
+func (p *PKCS12) addBag(bag safeBag, password string) error {
+	switch {
+	case bag.ID.Equal(oidKeyBag):
+		p.PrivateKeys = append(p.PrivateKeys, bag.Value.Bytes)
+	case bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+		var epki encryptedPrivateKeyInfo
+		if _, err := asn1.Unmarshal(bag.Value.Bytes, &epki); err != nil {
+			return fmt.Errorf("invalid pkcs12 key bag  %v", err)
+		}
+		der, err := decrypt(epki.Algorithm, password, epki.EncryptedData)
+		if err != nil {
+			return err
+		}
+		p.PrivateKeys = append(p.PrivateKeys, der)
+	case bag.ID.Equal(oidCertBag):
+		var cb certBag
+		if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+			return fmt.Errorf("invalid pkcs12 certificate bag  %v", err)
+		}
+		if !cb.ID.Equal(oidCertTypeX509) {
+			return nil
+		}
+		c, err := x509.ParseCertificate(cb.Data)
+		if err != nil {
+			return err
+		}
+		p.Certificates = append(p.Certificates, c)
+	case bag.ID.Equal(oidCRLBag):
+		var cb certBag
+		if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+			return fmt.Errorf("invalid pkcs12 crl bag  %v", err)
+		}
+		if !cb.ID.Equal(oidCRLTypeX509) {
+			return nil
+		}
+		crl, err := x509.ParseRevocationList(cb.Data)
+		if err != nil {
+			return err
+		}
+		p.CRLs = append(p.CRLs, crl)
+	}
+	return nil
+}
+
+func newCertBag(c *x509.Certificate) (safeBag, error) {
+	der, err := asn1.Marshal(certBag{ID: oidCertTypeX509, Data: c.Raw})
+	if err != nil {
+		return safeBag{}, err
+	}
+	return safeBag{ID: oidCertBag, Value: asn1.RawValue{FullBytes: explicit(der)}}, nil
+}
+
+func newShroudedKeyBag(key crypto.PrivateKey, password string, newAlgorithm func(int) (pkix.AlgorithmIdentifier, error)) (safeBag, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return safeBag{}, err
+	}
+	alg, err := newAlgorithm(Iterations)
+	if err != nil {
+		return safeBag{}, err
+	}
+	encrypted, err := encrypt(alg, password, der)
+	if err != nil {
+		return safeBag{}, err
+	}
+	epki, err := asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: alg, EncryptedData: encrypted})
+	if err != nil {
+		return safeBag{}, err
+	}
+	return safeBag{ID: oidPKCS8ShroudedKeyBag, Value: asn1.RawValue{FullBytes: explicit(epki)}}, nil
+}
+
+// bagAttributes gives the friendly name and local key id, which link a key to its certificate
+func bagAttributes(friendlyName string, localKeyID []byte) ([]attribute, error) {
+	id, err := asn1.Marshal(localKeyID)
+	if err != nil {
+		return nil, err
+	}
+	attrs := []attribute{{ID: oidLocalKeyID, Values: []asn1.RawValue{{FullBytes: id}}}}
+	if friendlyName != "" {
+		// BMPString, without the null terminator of the KDF password
+		bmp := bmpString(friendlyName)
+		name, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagBMPString, Bytes: bmp[:len(bmp)-2]})
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attribute{ID: oidFriendlyName, Values: []asn1.RawValue{{FullBytes: name}}})
+	}
+	return attrs, nil
+}
+
+func dataContents(bags []safeBag) (contentInfo, error) {
+	der, err := asn1.Marshal(bags)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	content, err := asn1.Marshal(der)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return contentInfo{ContentType: oidData, Content: asn1.RawValue{FullBytes: explicit(content)}}, nil
+}
+
+func encryptedContents(bags []safeBag, password string, newAlgorithm func(int) (pkix.AlgorithmIdentifier, error)) (contentInfo, error) {
+	der, err := asn1.Marshal(bags)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	alg, err := newAlgorithm(Iterations)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	encrypted, err := encrypt(alg, password, der)
+	if err != nil {
+		return contentInfo{}, err
+	}
+	content, err := asn1.Marshal(encryptedData{
+		Version: 0,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidData,
+			ContentEncryptionAlgorithm: alg,
+			EncryptedContent:           asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: encrypted},
+		},
+	})
+	if err != nil {
+		return contentInfo{}, err
+	}
+	return contentInfo{ContentType: oidEncryptedData, Content: asn1.RawValue{FullBytes: explicit(content)}}, nil
+}
+
+// explicit wraps the der in an explicit [0] tag
+func explicit(der []byte) []byte {
+	wrapped, _ := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der})
+	return wrapped
+}
+
+// octets reads an implicit OCTET STRING, which may be constructed from several octet strings
+func octets(rv asn1.RawValue) ([]byte, error) {
+	if !rv.IsCompound {
+		return rv.Bytes, nil
+	}
+	buf := bytes.NewBuffer(nil)
+	for data := rv.Bytes; len(data) > 0; {
+		var part asn1.RawValue
+		rest, err := asn1.Unmarshal(data, &part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted content  %v", err)
+		}
+		buf.Write(part.Bytes)
+		data = rest
+	}
+	return buf.Bytes(), nil
+}
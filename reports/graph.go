@@ -0,0 +1,145 @@
+package reports
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// node fill colours, by status or kind of certificate
+const (
+	colourRevoked     = "#666666"
+	colourExpired     = "#f4a6a6"
+	colourNotYetValid = "#d9d2e9"
+	colourSelfSigned  = "#ffd966"
+	colourCA          = "#9fc5e8"
+	colourCertificate = "#ffffff"
+	colourRequest     = "#d9ead3"
+	colourCRL         = "#eeeeee"
+	colourExpiring    = "#e69138"
+)
+
+// WriteGraph writes the issuer to subject graph of the inventory, in Graphviz DOT.
+// Each certificate is a node, with an edge from every CA whose key signed it, so cross signed certificates have multiple parents.
+// Requests link to the certificates issued for their key and CRLs to the CA which signed them.
+func WriteGraph(out io.Writer, inv *Inventory) error {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("digraph pki {\n")
+	buf.WriteString("\trankdir=TB;\n")
+	buf.WriteString("\tnode [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\", fontsize=10];\n")
+	buf.WriteString("\tedge [color=\"#444444\"];\n")
+
+	var cas []*CertificateEntry
+	for _, ce := range inv.Certificates {
+		if ce.IsCA {
+			cas = append(cas, ce)
+		}
+	}
+	unknown := map[string]bool{}
+	for _, ce := range inv.Certificates {
+		writeCertificateNode(buf, ce)
+		if isSelfSigned(ce.cert) {
+			continue
+		}
+		parents := certificateIssuers(ce, cas)
+		if len(parents) == 0 {
+			id := "issuer:" + ce.Issuer
+			if !unknown[id] {
+				unknown[id] = true
+				fmt.Fprintf(buf, "\t%s [label=%s, style=\"rounded,dashed\"];\n", dotQuote(id), dotQuote(ce.Issuer+"\nissuer not found"))
+			}
+			fmt.Fprintf(buf, "\t%s -> %s [style=dashed];\n", dotQuote(id), dotQuote(ce.Fingerprint))
+			continue
+		}
+		for _, p := range parents {
+			fmt.Fprintf(buf, "\t%s -> %s;\n", dotQuote(p.Fingerprint), dotQuote(ce.Fingerprint))
+		}
+	}
+
+	for _, re := range inv.requests {
+		id := re.csr.Fingerprint().String()
+		fmt.Fprintf(buf, "\t%s [label=%s, shape=note, style=filled, fillcolor=%s];\n",
+			dotQuote(id), dotQuote(commonName(re.csr.Subject.CommonName, re.csr.Subject.String())+"\nrequest"), dotQuote(colourRequest))
+		for _, ce := range inv.Certificates {
+			if bytes.Equal(ce.cert.RawSubjectPublicKeyInfo, re.csr.RawSubjectPublicKeyInfo) {
+				fmt.Fprintf(buf, "\t%s -> %s [style=dashed, arrowhead=empty];\n", dotQuote(id), dotQuote(ce.Fingerprint))
+			}
+		}
+	}
+
+	for _, cl := range inv.crls {
+		id := cl.crl.Fingerprint().String()
+		label := fmt.Sprintf("CRL %s\n%d revoked", serialText(cl.crl.Number), len(cl.crl.RevokedCertificateEntries))
+		fmt.Fprintf(buf, "\t%s [label=%s, shape=folder, style=filled, fillcolor=%s];\n", dotQuote(id), dotQuote(label), dotQuote(colourCRL))
+		for _, ca := range cas {
+			if bytes.Equal(ca.cert.RawSubject, cl.crl.RawIssuer) && (*x509.RevocationList)(cl.crl).CheckSignatureFrom((*x509.Certificate)(ca.cert)) == nil {
+				fmt.Fprintf(buf, "\t%s -> %s [style=dotted];\n", dotQuote(ca.Fingerprint), dotQuote(id))
+			}
+		}
+	}
+	buf.WriteString("}\n")
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+func writeCertificateNode(buf *bytes.Buffer, ce *CertificateEntry) {
+	label := fmt.Sprintf("%s\n%s, expires %s", commonName(ce.cert.Subject.CommonName, ce.Subject), ce.Status, ce.NotAfter.UTC().Format("2006-01-02"))
+	attrs := []string{"label=" + dotQuote(label), "fillcolor=" + dotQuote(certificateColour(ce))}
+	switch ce.Status {
+	case StatusRevoked:
+		attrs = append(attrs, "fontcolor=white")
+	case StatusExpiring:
+		attrs = append(attrs, "color="+dotQuote(colourExpiring), "penwidth=2")
+	}
+	if ce.IsCA {
+		attrs = append(attrs, "peripheries=2")
+	}
+	fmt.Fprintf(buf, "\t%s [%s];\n", dotQuote(ce.Fingerprint), strings.Join(attrs, ", "))
+}
+
+// certificateColour gives the fill colour of the certificate, by its status, or when valid, its kind.
+func certificateColour(ce *CertificateEntry) string {
+	switch {
+	case ce.Status == StatusRevoked:
+		return colourRevoked
+	case ce.Status == StatusExpired:
+		return colourExpired
+	case ce.Status == StatusNotYetValid:
+		return colourNotYetValid
+	case isSelfSigned(ce.cert):
+		return colourSelfSigned
+	case ce.IsCA:
+		return colourCA
+	default:
+		return colourCertificate
+	}
+}
+
+// certificateIssuers finds every CA, other than the certificate itself, whose key signed the certificate.
+func certificateIssuers(ce *CertificateEntry, cas []*CertificateEntry) []*CertificateEntry {
+	var found []*CertificateEntry
+	for _, ca := range cas {
+		if ca == ce || !bytes.Equal(ca.cert.RawSubject, ce.cert.RawIssuer) {
+			continue
+		}
+		if (*x509.Certificate)(ce.cert).CheckSignatureFrom((*x509.Certificate)(ca.cert)) == nil {
+			found = append(found, ca)
+		}
+	}
+	return found
+}
+
+func commonName(cn, dn string) string {
+	if cn != "" {
+		return cn
+	}
+	return dn
+}
+
+// dotQuote quotes the string as a DOT identifier, escaping quotes and line breaks.
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
@@ -0,0 +1,125 @@
+package reports
+
+// htmlReport is a self contained html document of the inventory, with no external stylesheets or scripts.
+const htmlReport = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>PKI inventory</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0; }
+h2 { border-bottom: 1px solid #ccc; margin-top: 2em; }
+table { border-collapse: collapse; margin: 0.5em 0 1.5em 0; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+code { font-size: 0.85em; }
+.generated { color: #666; }
+.none { color: #666; font-style: italic; }
+.valid { color: #1a7f37; }
+.expiring { color: #9a6700; font-weight: bold; }
+.not-yet-valid { color: #0550ae; }
+.expired, .revoked { color: #cf222e; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>PKI inventory</h1>
+<p class="generated">Generated {{datetime .Generated}}.  Certificates expiring within {{.ExpiryDays}} days are marked as expiring.</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Certificates</th><td>{{len .Certificates}}</td></tr>
+{{- $counts := .Counts}}
+{{- range statuses}}
+<tr><th class="{{class .}}">{{.}}</th><td>{{index $counts .}}</td></tr>
+{{- end}}
+<tr><th>Certificate authorities</th><td>{{len .Hierarchy}}</td></tr>
+<tr><th>Revoked entries</th><td>{{len .Revoked}}</td></tr>
+<tr><th>Orphaned keys</th><td>{{len .OrphanedKeys}}</td></tr>
+<tr><th>Orphaned requests</th><td>{{len .OrphanedRequests}}</td></tr>
+</table>
+
+<h2>Certificate authorities</h2>
+{{- with .Hierarchy}}
+<table>
+<tr><th>Subject</th><th>Issued</th><th>Not after</th><th>Status</th><th>Path</th></tr>
+{{- range .}}
+<tr><td style="padding-left: {{.Depth}}.5em">{{if .Depth}}&#x2514; {{end}}{{.Subject}}</td><td>{{.Issued}}</td><td>{{date .NotAfter}}</td><td class="{{class .Status}}">{{.Status}}</td><td><code>{{.Path}}</code></td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="none">No CA certificates found.</p>
+{{- end}}
+
+<h2>Certificates by issuer</h2>
+{{- range .Issuers}}
+<h3>{{.Issuer}}</h3>
+<table>
+<tr><th>Subject</th><th>Serial</th><th>Not before</th><th>Not after</th><th>Status</th><th>Path</th></tr>
+{{- range .Certificates}}
+<tr><td>{{.Subject}}</td><td>{{.Serial}}</td><td>{{date .NotBefore}}</td><td>{{date .NotAfter}}</td><td class="{{class .Status}}">{{.Status}}</td><td><code>{{.Path}}</code></td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="none">No certificates found.</p>
+{{- end}}
+
+<h2>Expiry timeline</h2>
+{{- range .Timeline}}
+<h3>{{.Month}}</h3>
+<table>
+<tr><th>Expires</th><th>Days left</th><th>Subject</th><th>Issuer</th><th>Status</th></tr>
+{{- range .Certificates}}
+<tr><td>{{date .NotAfter}}</td><td>{{.DaysLeft}}</td><td>{{.Subject}}</td><td>{{.Issuer}}</td><td class="{{class .Status}}">{{.Status}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="none">No certificates found.</p>
+{{- end}}
+
+<h2>Revoked certificates</h2>
+{{- with .Revoked}}
+<table>
+<tr><th>Serial</th><th>Subject</th><th>Issuer</th><th>Revoked</th><th>Reason</th><th>CRL</th></tr>
+{{- range .}}
+<tr><td>{{.Serial}}</td><td>{{.Subject}}</td><td>{{.Issuer}}</td><td>{{date .RevokedAt}}</td><td>{{.Reason}}</td><td><code>{{.Path}}</code></td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="none">No revoked certificates found.</p>
+{{- end}}
+
+<h2>Orphaned keys</h2>
+{{- with .OrphanedKeys}}
+<table>
+<tr><th>Fingerprint</th><th>Algorithm</th><th>Path</th></tr>
+{{- range .}}
+<tr><td><code>{{.Fingerprint}}</code></td><td>{{.Algorithm}}</td><td><code>{{.Path}}</code></td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="none">No orphaned keys found.</p>
+{{- end}}
+{{- with .EncryptedKeys}}
+<p>Encrypted keys, not checked:</p>
+<ul>
+{{- range .}}
+<li><code>{{.}}</code></li>
+{{- end}}
+</ul>
+{{- end}}
+
+<h2>Orphaned requests</h2>
+{{- with .OrphanedRequests}}
+<table>
+<tr><th>Subject</th><th>Algorithm</th><th>Path</th></tr>
+{{- range .}}
+<tr><td>{{.Subject}}</td><td>{{.Algorithm}}</td><td><code>{{.Path}}</code></td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="none">No orphaned requests found.</p>
+{{- end}}
+</body>
+</html>
+`
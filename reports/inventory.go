@@ -0,0 +1,399 @@
+package reports
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/logging"
+	"github.com/eurozulu/pempal/model"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Certificate statuses, in order of concern
+const (
+	StatusValid       = "valid"
+	StatusExpiring    = "expiring"
+	StatusNotYetValid = "not yet valid"
+	StatusExpired     = "expired"
+	StatusRevoked     = "revoked"
+)
+
+// Inventory is the summary of the resources in a repository, as presented in a report.
+type Inventory struct {
+	Generated  time.Time
+	ExpiryDays int
+
+	Certificates     []*CertificateEntry
+	CAs              []*CANode
+	Issuers          []*IssuerGroup
+	Timeline         []*ExpiryMonth
+	Revoked          []*RevokedEntry
+	OrphanedKeys     []*KeyEntry
+	OrphanedRequests []*RequestEntry
+	// EncryptedKeys are the paths of keys which could not be matched, without their passphrase.
+	EncryptedKeys []string
+	// Counts are the number of certificates of each status
+	Counts map[string]int
+
+	requests []requestEntry
+	crls     []crlEntry
+}
+
+// CertificateEntry is a single certificate of the inventory.
+type CertificateEntry struct {
+	Subject     string
+	Issuer      string
+	Serial      string
+	Fingerprint string
+	NotBefore   time.Time
+	NotAfter    time.Time
+	DaysLeft    int
+	Status      string
+	IsCA        bool
+	Path        string
+
+	cert *model.Certificate
+}
+
+// CANode is a CA certificate and the CAs it has issued.
+type CANode struct {
+	*CertificateEntry
+	// Issued is the number of certificates signed by the CA, including other CAs.
+	Issued   int
+	Children []*CANode
+}
+
+// IssuerGroup is the certificates issued under the same issuer name.
+type IssuerGroup struct {
+	Issuer       string
+	Certificates []*CertificateEntry
+}
+
+// ExpiryMonth is the certificates expiring in the same month.
+type ExpiryMonth struct {
+	Month        string
+	Certificates []*CertificateEntry
+}
+
+// RevokedEntry is a certificate listed in a CRL.
+type RevokedEntry struct {
+	Serial    string
+	Issuer    string
+	RevokedAt time.Time
+	Reason    string
+	// Subject is the subject of the revoked certificate, when found in the repository.
+	Subject string
+	Path    string
+}
+
+// KeyEntry is a private key with no certificate or request.
+type KeyEntry struct {
+	Fingerprint string
+	Algorithm   string
+	Path        string
+}
+
+// RequestEntry is a certificate request with no certificate issued for its key.
+type RequestEntry struct {
+	Subject     string
+	Fingerprint string
+	Algorithm   string
+	Path        string
+}
+
+var reasonNames = []string{
+	"Unspecified", "Key Compromise", "CA Compromise", "Affiliation Changed", "Superseded",
+	"Cessation Of Operation", "Certificate Hold", "", "Remove From CRL", "Privilege Withdrawn", "AA Compromise",
+}
+
+type keyEntry struct {
+	key  *model.PrivateKey
+	path string
+}
+
+type requestEntry struct {
+	csr  *model.CertificateRequest
+	path string
+}
+
+type crlEntry struct {
+	crl  *model.RevocationList
+	path string
+}
+
+// NewInventory builds the inventory of the resources in the given files, as of the given time.
+// Certificates expiring within expiryDays of that time are marked as expiring.
+// Duplicate resources, found in more than one file, are listed once.
+func NewInventory(files []*model.PemFile, now time.Time, expiryDays int) *Inventory {
+	inv := &Inventory{Generated: now, ExpiryDays: expiryDays, Counts: map[string]int{}}
+	var keys []keyEntry
+	var csrs []requestEntry
+	var crls []crlEntry
+	seen := map[model.Fingerprint]bool{}
+	for _, pf := range files {
+		for _, blk := range pf.Blocks {
+			if model.ParseResourceType(blk.Type) == model.ResourceTypePrivateKey && model.IsEncryptedPem(blk) {
+				inv.EncryptedKeys = append(inv.EncryptedKeys, pf.Path)
+				continue
+			}
+			for _, res := range (&model.PemFile{Path: pf.Path, Blocks: []*pem.Block{blk}}).Resources() {
+				if seen[res.Fingerprint()] {
+					continue
+				}
+				seen[res.Fingerprint()] = true
+				switch v := res.(type) {
+				case *model.Certificate:
+					inv.Certificates = append(inv.Certificates, newCertificateEntry(v, pf.Path))
+				case *model.PrivateKey:
+					keys = append(keys, keyEntry{key: v, path: pf.Path})
+				case *model.CertificateRequest:
+					csrs = append(csrs, requestEntry{csr: v, path: pf.Path})
+				case *model.RevocationList:
+					crls = append(crls, crlEntry{crl: v, path: pf.Path})
+				}
+			}
+		}
+	}
+	sort.Slice(inv.Certificates, func(i, j int) bool {
+		return inv.Certificates[i].NotAfter.Before(inv.Certificates[j].NotAfter)
+	})
+
+	inv.addRevoked(crls)
+	for _, ce := range inv.Certificates {
+		ce.setStatus(now, expiryDays)
+		inv.Counts[ce.Status]++
+	}
+	inv.addHierarchy()
+	inv.addIssuers()
+	inv.addTimeline()
+	inv.addOrphans(keys, csrs)
+	inv.requests = csrs
+	inv.crls = crls
+	return inv
+}
+
+func newCertificateEntry(cert *model.Certificate, path string) *CertificateEntry {
+	return &CertificateEntry{
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		Serial:      serialText(cert.SerialNumber),
+		Fingerprint: cert.Fingerprint().String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		IsCA:        cert.IsCA,
+		Path:        path,
+		cert:        cert,
+	}
+}
+
+func (ce *CertificateEntry) setStatus(now time.Time, expiryDays int) {
+	ce.DaysLeft = int(ce.NotAfter.Sub(now).Hours() / 24)
+	switch {
+	case ce.Status == StatusRevoked:
+	case now.After(ce.NotAfter):
+		ce.Status = StatusExpired
+	case now.Before(ce.NotBefore):
+		ce.Status = StatusNotYetValid
+	case ce.DaysLeft < expiryDays:
+		ce.Status = StatusExpiring
+	default:
+		ce.Status = StatusValid
+	}
+}
+
+// addRevoked lists the entries of each CRL, marking the certificates found as revoked.
+// Only CRLs signed by one of the CAs are applied, marking the certificates that CA issued.
+// Certificates listed in more than one CRL of the same issuer are listed once.
+func (inv *Inventory) addRevoked(crls []crlEntry) {
+	listed := map[string]bool{}
+	for _, ce := range crls {
+		ca := inv.crlIssuer(ce.crl)
+		if ca == nil {
+			logging.Warning("ignoring CRL %s, its signature is not verified by any CA", ce.path)
+			continue
+		}
+		for _, rc := range ce.crl.RevokedCertificateEntries {
+			id := string(ce.crl.RawIssuer) + rc.SerialNumber.String()
+			if listed[id] {
+				continue
+			}
+			listed[id] = true
+			re := &RevokedEntry{
+				Serial:    serialText(rc.SerialNumber),
+				Issuer:    ce.crl.Issuer.String(),
+				RevokedAt: rc.RevocationTime,
+				Reason:    reasonText(rc.ReasonCode),
+				Path:      ce.path,
+			}
+			for _, c := range inv.Certificates {
+				if c.cert.SerialNumber.Cmp(rc.SerialNumber) != 0 || !bytes.Equal(c.cert.RawIssuer, ce.crl.RawIssuer) {
+					continue
+				}
+				if (*x509.Certificate)(c.cert).CheckSignatureFrom(ca) != nil {
+					continue
+				}
+				c.Status = StatusRevoked
+				re.Subject = c.Subject
+			}
+			inv.Revoked = append(inv.Revoked, re)
+		}
+	}
+	sort.SliceStable(inv.Revoked, func(i, j int) bool {
+		return inv.Revoked[i].RevokedAt.Before(inv.Revoked[j].RevokedAt)
+	})
+}
+
+// crlIssuer finds the CA certificate whose key signed the CRL, or nil if none is found.
+func (inv *Inventory) crlIssuer(crl *model.RevocationList) *x509.Certificate {
+	for _, c := range inv.Certificates {
+		ca := (*x509.Certificate)(c.cert)
+		if !c.IsCA || !bytes.Equal(ca.RawSubject, crl.RawIssuer) {
+			continue
+		}
+		if (*x509.RevocationList)(crl).CheckSignatureFrom(ca) == nil {
+			return ca
+		}
+	}
+	return nil
+}
+
+// addHierarchy builds the tree of CAs, from each root, or CA whose issuer is not found.
+func (inv *Inventory) addHierarchy() {
+	var nodes []*CANode
+	for _, ce := range inv.Certificates {
+		if ce.IsCA {
+			nodes = append(nodes, &CANode{CertificateEntry: ce})
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Subject < nodes[j].Subject
+	})
+	parents := map[*CANode]*CANode{}
+	for _, n := range nodes {
+		if p := issuerNode(n.cert, nodes); p != nil && !isAncestor(n, p, parents) {
+			parents[n] = p
+			p.Children = append(p.Children, n)
+			continue
+		}
+		inv.CAs = append(inv.CAs, n)
+	}
+	for _, ce := range inv.Certificates {
+		if p := issuerNode(ce.cert, nodes); p != nil {
+			p.Issued++
+		}
+	}
+}
+
+// issuerNode finds the CA which signed the given certificate.  Self signed certificates have no issuer node.
+func issuerNode(cert *model.Certificate, nodes []*CANode) *CANode {
+	if isSelfSigned(cert) {
+		return nil
+	}
+	for _, n := range nodes {
+		if n.cert == cert || !bytes.Equal(n.cert.RawSubject, cert.RawIssuer) {
+			continue
+		}
+		if (*x509.Certificate)(cert).CheckSignatureFrom((*x509.Certificate)(n.cert)) == nil {
+			return n
+		}
+	}
+	return nil
+}
+
+func isSelfSigned(cert *model.Certificate) bool {
+	if !bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+		return false
+	}
+	return (*x509.Certificate)(cert).CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// isAncestor checks if n is already a parent of p, to prevent cross signed loops.
+func isAncestor(n, p *CANode, parents map[*CANode]*CANode) bool {
+	for ; p != nil; p = parents[p] {
+		if p == n {
+			return true
+		}
+	}
+	return false
+}
+
+func (inv *Inventory) addIssuers() {
+	groups := map[string]*IssuerGroup{}
+	for _, ce := range inv.Certificates {
+		g, ok := groups[ce.Issuer]
+		if !ok {
+			g = &IssuerGroup{Issuer: ce.Issuer}
+			groups[ce.Issuer] = g
+			inv.Issuers = append(inv.Issuers, g)
+		}
+		g.Certificates = append(g.Certificates, ce)
+	}
+	sort.Slice(inv.Issuers, func(i, j int) bool {
+		return inv.Issuers[i].Issuer < inv.Issuers[j].Issuer
+	})
+}
+
+// addTimeline groups the certificates, already in order of expiry, by the month they expire
+func (inv *Inventory) addTimeline() {
+	var month *ExpiryMonth
+	for _, ce := range inv.Certificates {
+		m := ce.NotAfter.UTC().Format("2006-01")
+		if month == nil || month.Month != m {
+			month = &ExpiryMonth{Month: m}
+			inv.Timeline = append(inv.Timeline, month)
+		}
+		month.Certificates = append(month.Certificates, ce)
+	}
+}
+
+// addOrphans lists the keys with no certificate or request, and the requests with no certificate.
+func (inv *Inventory) addOrphans(keys []keyEntry, csrs []requestEntry) {
+	certKeys := map[model.Fingerprint]bool{}
+	for _, ce := range inv.Certificates {
+		certKeys[model.NewPublicKey(ce.cert.PublicKey).Fingerprint()] = true
+	}
+	csrKeys := map[model.Fingerprint]bool{}
+	for _, re := range csrs {
+		puk := model.NewPublicKey(re.csr.PublicKey)
+		csrKeys[puk.Fingerprint()] = true
+		if certKeys[puk.Fingerprint()] {
+			continue
+		}
+		inv.OrphanedRequests = append(inv.OrphanedRequests, &RequestEntry{
+			Subject:     re.csr.Subject.String(),
+			Fingerprint: re.csr.Fingerprint().String(),
+			Algorithm:   puk.PublicKeyAlgorithm().String(),
+			Path:        re.path,
+		})
+	}
+	for _, ke := range keys {
+		if ke.key.IsExternal() {
+			continue
+		}
+		puk := ke.key.Public()
+		if certKeys[puk.Fingerprint()] || csrKeys[puk.Fingerprint()] {
+			continue
+		}
+		inv.OrphanedKeys = append(inv.OrphanedKeys, &KeyEntry{
+			Fingerprint: ke.key.Fingerprint().String(),
+			Algorithm:   ke.key.PublicKeyAlgorithm().String(),
+			Path:        ke.path,
+		})
+	}
+}
+
+func reasonText(code int) string {
+	if code < 0 || code >= len(reasonNames) || reasonNames[code] == "" {
+		return "Unknown"
+	}
+	return reasonNames[code]
+}
+
+func serialText(n *big.Int) string {
+	if n == nil {
+		return ""
+	}
+	return n.String()
+}
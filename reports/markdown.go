@@ -0,0 +1,100 @@
+package reports
+
+// markdownReport is a markdown document of the inventory.
+const markdownReport = `# PKI inventory
+
+Generated {{datetime .Generated}}.  Certificates expiring within {{.ExpiryDays}} days are marked as expiring.
+
+## Summary
+
+| | |
+|---|---|
+| Certificates | {{len .Certificates}} |
+{{- $counts := .Counts}}
+{{- range statuses}}
+| {{.}} | {{index $counts .}} |
+{{- end}}
+| Certificate authorities | {{len .Hierarchy}} |
+| Revoked entries | {{len .Revoked}} |
+| Orphaned keys | {{len .OrphanedKeys}} |
+| Orphaned requests | {{len .OrphanedRequests}} |
+
+## Certificate authorities
+{{with .Hierarchy}}
+{{- range .}}
+{{indent .Depth}}- **{{md .Subject}}**  {{.Status}}, expires {{date .NotAfter}}, {{.Issued}} issued  ` + "`{{.Path}}`" + `
+{{- end}}
+{{- else}}
+_No CA certificates found._
+{{- end}}
+
+## Certificates by issuer
+{{- range .Issuers}}
+
+### {{md .Issuer}}
+
+| Subject | Serial | Not before | Not after | Status | Path |
+|---|---|---|---|---|---|
+{{- range .Certificates}}
+| {{md .Subject}} | {{.Serial}} | {{date .NotBefore}} | {{date .NotAfter}} | {{.Status}} | ` + "`{{.Path}}`" + ` |
+{{- end}}
+{{- else}}
+
+_No certificates found._
+{{- end}}
+
+## Expiry timeline
+{{- range .Timeline}}
+
+### {{.Month}}
+
+| Expires | Days left | Subject | Issuer | Status |
+|---|---|---|---|---|
+{{- range .Certificates}}
+| {{date .NotAfter}} | {{.DaysLeft}} | {{md .Subject}} | {{md .Issuer}} | {{.Status}} |
+{{- end}}
+{{- else}}
+
+_No certificates found._
+{{- end}}
+
+## Revoked certificates
+{{with .Revoked}}
+| Serial | Subject | Issuer | Revoked | Reason | CRL |
+|---|---|---|---|---|---|
+{{- range .}}
+| {{.Serial}} | {{md .Subject}} | {{md .Issuer}} | {{date .RevokedAt}} | {{.Reason}} | ` + "`{{.Path}}`" + ` |
+{{- end}}
+{{- else}}
+_No revoked certificates found._
+{{- end}}
+
+## Orphaned keys
+{{with .OrphanedKeys}}
+| Fingerprint | Algorithm | Path |
+|---|---|---|
+{{- range .}}
+| ` + "`{{.Fingerprint}}`" + ` | {{.Algorithm}} | ` + "`{{.Path}}`" + ` |
+{{- end}}
+{{- else}}
+_No orphaned keys found._
+{{- end}}
+{{- with .EncryptedKeys}}
+
+Encrypted keys, not checked:
+{{range .}}
+- ` + "`{{.}}`" + `
+{{- end}}
+{{- end}}
+
+## Orphaned requests
+{{with .OrphanedRequests}}
+| Subject | Algorithm | Path |
+|---|---|---|
+{{- range .}}
+| {{md .Subject}} | {{.Algorithm}} | ` + "`{{.Path}}`" + ` |
+{{- end}}
+{{- else}}
+_No orphaned requests found._
+{{- end}}
+`
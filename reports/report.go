@@ -0,0 +1,83 @@
+package reports
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatDOT      = "dot"
+)
+
+// HierarchyLine is a CA of the hierarchy, at its depth from the root.
+type HierarchyLine struct {
+	*CANode
+	Depth int
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "<", "&lt;", ">", "&gt;")
+
+var reportFuncs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format("2006-01-02")
+	},
+	"datetime": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05 MST")
+	},
+	"class": func(status string) string {
+		return strings.ReplaceAll(status, " ", "-")
+	},
+	"md": markdownEscaper.Replace,
+	"indent": func(depth int) string {
+		return strings.Repeat("  ", depth)
+	},
+	"statuses": func() []string {
+		return []string{StatusValid, StatusExpiring, StatusNotYetValid, StatusExpired, StatusRevoked}
+	},
+}
+
+// Hierarchy gives the CAs in tree order, each following its issuer.
+func (inv *Inventory) Hierarchy() []*HierarchyLine {
+	var lines []*HierarchyLine
+	var walk func(nodes []*CANode, depth int)
+	walk = func(nodes []*CANode, depth int) {
+		for _, n := range nodes {
+			lines = append(lines, &HierarchyLine{CANode: n, Depth: depth})
+			walk(n.Children, depth+1)
+		}
+	}
+	walk(inv.CAs, 0)
+	return lines
+}
+
+// WriteReport writes the inventory as a report in the given format, html, markdown or dot.
+func WriteReport(out io.Writer, format string, inv *Inventory) error {
+	switch strings.ToLower(format) {
+	case FormatHTML, "htm":
+		t, err := htmltemplate.New("report").Funcs(reportFuncs).Parse(htmlReport)
+		if err != nil {
+			return err
+		}
+		return t.Execute(out, inv)
+	case FormatMarkdown, "md":
+		t, err := template.New("report").Funcs(reportFuncs).Parse(markdownReport)
+		if err != nil {
+			return err
+		}
+		return t.Execute(out, inv)
+	case FormatDOT, "gv":
+		return WriteGraph(out, inv)
+	default:
+		return fmt.Errorf("%q is not a known report format.  Use html, markdown or dot", format)
+	}
+}
@@ -0,0 +1,204 @@
+package reports
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/model"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewInventory(t *testing.T) {
+	now := time.Now()
+	rootKey, root := testCertificate(t, "root", true, 1, now.AddDate(1, 0, 0), nil, nil)
+	interKey, inter := testCertificate(t, "inter", true, 2, now.AddDate(1, 0, 0), root, rootKey)
+	_, leaf := testCertificate(t, "leaf | one", false, 3, now.AddDate(0, 0, 10), inter, interKey)
+	_, revoked := testCertificate(t, "revoked", false, 4, now.AddDate(1, 0, 0), inter, interKey)
+	_, expired := testCertificate(t, "expired", false, 5, now.AddDate(0, 0, -1), inter, interKey)
+
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now,
+		NextUpdate: now.AddDate(0, 0, 7),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: revoked.SerialNumber, RevocationTime: now, ReasonCode: 1},
+		},
+	}, inter, interKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a CRL of the same issuer name, signed by another key, is not applied
+	forgedKey, forgedCA := testCertificate(t, "inter", true, 2, now.AddDate(1, 0, 0), nil, nil)
+	forged, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(2),
+		ThisUpdate: now,
+		NextUpdate: now.AddDate(0, 0, 7),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: leaf.SerialNumber, RevocationTime: now},
+		},
+	}, forgedCA, forgedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphanKey := testKey(t)
+	keyDer, err := x509.MarshalPKCS8PrivateKey(orphanKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "pending"}}, testKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []*model.PemFile{
+		testPemFile("certs/root.pem", model.ResourceTypeCertificate, root.Raw),
+		testPemFile("certs/inter.pem", model.ResourceTypeCertificate, inter.Raw),
+		testPemFile("certs/leaf.pem", model.ResourceTypeCertificate, leaf.Raw),
+		testPemFile("certs/revoked.pem", model.ResourceTypeCertificate, revoked.Raw),
+		testPemFile("certs/expired.pem", model.ResourceTypeCertificate, expired.Raw),
+		testPemFile("certs/copy.pem", model.ResourceTypeCertificate, leaf.Raw),
+		testPemFile("revoked/inter.crl", model.ResourceTypeRevokationList, crl),
+		testPemFile("revoked/forged.crl", model.ResourceTypeRevokationList, forged),
+		testPemFile("private/orphan.key", model.ResourceTypePrivateKey, keyDer),
+		testPemFile("requests/pending.csr", model.ResourceTypeCertificateRequest, csr),
+	}
+	inv := NewInventory(files, now, 30)
+
+	if len(inv.Certificates) != 5 {
+		t.Fatalf("expected 5 certificates, found %d", len(inv.Certificates))
+	}
+	if len(inv.CAs) != 1 || inv.CAs[0].Subject != "CN=root" || len(inv.CAs[0].Children) != 1 {
+		t.Fatalf("expected root CA with one child CA")
+	}
+	if in := inv.CAs[0].Children[0]; in.Subject != "CN=inter" || in.Issued != 3 {
+		t.Fatalf("expected inter CA to have issued 3 certificates, found %d", in.Issued)
+	}
+	for status, count := range map[string]int{StatusValid: 2, StatusExpiring: 1, StatusExpired: 1, StatusRevoked: 1} {
+		if inv.Counts[status] != count {
+			t.Errorf("expected %d %s certificates, found %d", count, status, inv.Counts[status])
+		}
+	}
+	if len(inv.Issuers) != 2 {
+		t.Errorf("expected 2 issuers, found %d", len(inv.Issuers))
+	}
+	if inv.Timeline[0].Certificates[0].Subject != "CN=expired" {
+		t.Errorf("expected timeline to start with the expired certificate")
+	}
+	if len(inv.Revoked) != 1 || inv.Revoked[0].Subject != "CN=revoked" || inv.Revoked[0].Reason != "Key Compromise" {
+		t.Errorf("expected revoked entry for CN=revoked")
+	}
+	if len(inv.OrphanedKeys) != 1 || inv.OrphanedKeys[0].Path != "private/orphan.key" {
+		t.Errorf("expected orphaned key private/orphan.key")
+	}
+	if len(inv.OrphanedRequests) != 1 || inv.OrphanedRequests[0].Subject != "CN=pending" {
+		t.Errorf("expected orphaned request CN=pending")
+	}
+
+	for _, format := range []string{FormatHTML, FormatMarkdown} {
+		buf := bytes.NewBuffer(nil)
+		if err := WriteReport(buf, format, inv); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		escaped := `CN=leaf \| one`
+		if format == FormatHTML {
+			escaped = "CN=leaf | one"
+		}
+		if !strings.Contains(buf.String(), escaped) {
+			t.Errorf("%s: expected report to contain %q", format, escaped)
+		}
+	}
+	if err := WriteReport(bytes.NewBuffer(nil), "pdf", inv); err == nil {
+		t.Errorf("expected unknown format to fail")
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	now := time.Now()
+	rootKey, root := testCertificate(t, "root", true, 1, now.AddDate(1, 0, 0), nil, nil)
+	oldKey, oldRoot := testCertificate(t, "old root", true, 2, now.AddDate(0, 0, -1), nil, nil)
+	interKey, inter := testCertificate(t, "inter", true, 3, now.AddDate(1, 0, 0), root, rootKey)
+	tmpl := *inter
+	tmpl.SerialNumber = big.NewInt(4)
+	crossDer, err := x509.CreateCertificate(rand.Reader, &tmpl, oldRoot, interKey.Public(), oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cross, err := x509.ParseCertificate(crossDer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, leaf := testCertificate(t, "leaf \"one\"", false, 5, now.AddDate(1, 0, 0), inter, interKey)
+
+	inv := NewInventory([]*model.PemFile{
+		testPemFile("root.pem", model.ResourceTypeCertificate, root.Raw),
+		testPemFile("old.pem", model.ResourceTypeCertificate, oldRoot.Raw),
+		testPemFile("inter.pem", model.ResourceTypeCertificate, inter.Raw),
+		testPemFile("cross.pem", model.ResourceTypeCertificate, cross.Raw),
+		testPemFile("leaf.pem", model.ResourceTypeCertificate, leaf.Raw),
+	}, now, 30)
+	buf := bytes.NewBuffer(nil)
+	if err := WriteReport(buf, FormatDOT, inv); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	fp := func(c *x509.Certificate) string {
+		return `"` + model.NewFingerPrint(c.Raw).String() + `"`
+	}
+	for _, edge := range [][2]*x509.Certificate{{root, inter}, {oldRoot, cross}, {inter, leaf}, {cross, leaf}} {
+		if e := fp(edge[0]) + " -> " + fp(edge[1]); !strings.Contains(dot, e) {
+			t.Errorf("expected edge %s", e)
+		}
+	}
+	if !strings.Contains(dot, `leaf \"one\"`) {
+		t.Errorf("expected quotes in label to be escaped")
+	}
+	if !strings.Contains(dot, fp(oldRoot)+` [label="old root\nexpired`) || !strings.Contains(dot, colourExpired) {
+		t.Errorf("expected expired root node")
+	}
+}
+
+func testKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testCertificate(t *testing.T, cn string, isCA bool, serial int64, notAfter time.Time, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key := testKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             notAfter.AddDate(-2, 0, 0),
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if isCA {
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	if issuer == nil {
+		issuer, issuerKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, key.Public(), issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+func testPemFile(path string, rt model.ResourceType, der []byte) *model.PemFile {
+	return &model.PemFile{Path: path, Blocks: []*pem.Block{{Type: rt.String(), Bytes: der}}}
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"github.com/eurozulu/pempal/agent"
+	"github.com/eurozulu/pempal/logging"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/resourcefiles"
+	"time"
+)
+
+// AddToAgent adds the keys found in the path to the given agent.
+// Encrypted keys are decrypted, using the default passphrase provider, before being added.
+// Each key is also identified by the fingerprint of its pem, so encrypted keys can be matched to the
+// agents keys without decrypting them.
+func (kz Keys) AddToAgent(c agent.Client, timeout time.Duration) ([]*agent.KeyInfo, error) {
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+	var added []*agent.KeyInfo
+	for pf := range resourcefiles.PemFiles(kz).FindByType(ctx, model.ResourceTypePrivateKey) {
+		for _, blk := range pf.Blocks {
+			prk, err := parseKey(pf.Path, blk)
+			if err != nil {
+				logging.Warning("key in %s not added  %v", pf.Path, err)
+				continue
+			}
+			info, err := c.Add(prk, []string{model.NewFingerPrint(blk.Bytes).String()}, timeout)
+			if err != nil {
+				return added, fmt.Errorf("failed to add key in %s  %v", pf.Path, err)
+			}
+			added = append(added, info)
+		}
+	}
+	return added, nil
+}
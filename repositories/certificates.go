@@ -1,11 +1,15 @@
 package repositories
 
 import (
+	"bytes"
 	"context"
+	"crypto/x509"
 	"fmt"
 	"github.com/eurozulu/pempal/model"
 	"github.com/eurozulu/pempal/resourcefiles"
 	"math/big"
+	"slices"
+	"time"
 )
 
 type Certificates string
@@ -124,3 +128,84 @@ func (certs Certificates) Find(ctx context.Context, filter CertificateFilter) <-
 	}()
 	return ch
 }
+
+// Chain gives the issuer certificates of the given certificate, from its issuer up to the root.
+// Each issuer is a CA whose subject and key issued the certificate before it.
+// The chain ends at a self signed certificate or when no issuer is found.
+func (certs Certificates) Chain(cert *model.Certificate) []*model.Certificate {
+	cas := certs.ByCA()
+	var chain []*model.Certificate
+	for c := cert; !isSelfSigned(c); {
+		issuer := findIssuer(c, cas, chain)
+		if issuer == nil {
+			break
+		}
+		chain = append(chain, issuer)
+		c = issuer
+	}
+	return chain
+}
+
+// findIssuer finds the ca which signed the given certificate, preferring one currently valid.
+// CAs already in the chain are ignored, to prevent cross signed loops.
+func findIssuer(cert *model.Certificate, cas, chain []*model.Certificate) *model.Certificate {
+	var found *model.Certificate
+	now := time.Now()
+	for _, ca := range cas {
+		if !bytes.Equal(ca.RawSubject, cert.RawIssuer) || slices.ContainsFunc(chain, func(c *model.Certificate) bool {
+			return bytes.Equal(c.Raw, ca.Raw)
+		}) {
+			continue
+		}
+		if err := (*x509.Certificate)(cert).CheckSignatureFrom((*x509.Certificate)(ca)); err != nil {
+			continue
+		}
+		if now.After(ca.NotBefore) && now.Before(ca.NotAfter) {
+			return ca
+		}
+		if found == nil {
+			found = ca
+		}
+	}
+	return found
+}
+
+func isSelfSigned(cert *model.Certificate) bool {
+	if !bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+		return false
+	}
+	return (*x509.Certificate)(cert).CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// Verify validates the given certificate chains to a root certificate in the repository.
+// Roots are the self signed CAs of the repository.  Its other CAs, along with the given intermediates, may form the chain.
+// Returns the verified chain, starting with the given certificate.
+func (certs Certificates) Verify(cert *model.Certificate, intermediates ...*model.Certificate) ([]*model.Certificate, error) {
+	roots := x509.NewCertPool()
+	inters := x509.NewCertPool()
+	for _, ca := range certs.ByCA() {
+		if isSelfSigned(ca) {
+			roots.AddCert((*x509.Certificate)(ca))
+		} else {
+			inters.AddCert((*x509.Certificate)(ca))
+		}
+	}
+	for _, c := range intermediates {
+		if !isSelfSigned(c) {
+			inters.AddCert((*x509.Certificate)(c))
+		}
+	}
+	chains, err := (*x509.Certificate)(cert).Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: inters,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]*model.Certificate, len(chains[0]))
+	for i, c := range chains[0] {
+		chain[i] = (*model.Certificate)(c)
+	}
+	return chain, nil
+}
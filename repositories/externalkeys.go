@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/logging"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/signers"
+	"slices"
+	"sync"
+)
+
+var externalKeys []*model.PrivateKey
+var externalKeysOnce sync.Once
+
+// ExternalKeys returns the keys of the external signers in the config.
+// Each signer is run once, to obtain its public key.  Signers which fail are logged and ignored.
+func ExternalKeys() []*model.PrivateKey {
+	externalKeysOnce.Do(func() {
+		names := make([]string, 0, len(config.DefaultPPConfig.Signers))
+		for name := range config.DefaultPPConfig.Signers {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			s, err := signers.NewExternalSigner(name, config.DefaultPPConfig.Signers[name])
+			if err != nil {
+				logging.Warning("external signer %s ignored  %v", name, err)
+				continue
+			}
+			externalKeys = append(externalKeys, model.NewPrivateKey(s))
+		}
+	})
+	return externalKeys
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/resourcefiles"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Normalise rewrites the key files which contain keys encoded as anything other than PKCS#8,
+// such as PKCS#1, SEC1 or OpenSSH keys.  Encrypted keys are re-encrypted with the same passphrase.
+// Any other pems in the files are left unchanged.
+// Returns the paths of the files rewritten, or those which would be, when dryRun is true.
+func (kz Keys) Normalise(ctx context.Context, dryRun bool) ([]string, error) {
+	var paths []string
+	for pf := range resourcefiles.PemFiles(kz).FindByType(ctx, model.ResourceTypePrivateKey) {
+		if !slices.ContainsFunc(pf.Blocks, isNotNormalised) {
+			continue
+		}
+		paths = append(paths, pf.Path)
+		if dryRun {
+			continue
+		}
+		if err := normaliseKeyFile(pf.Path); err != nil {
+			return paths, err
+		}
+	}
+	return paths, nil
+}
+
+func isNotNormalised(blk *pem.Block) bool {
+	return !model.IsNormalisedPem(blk)
+}
+
+func normaliseKeyFile(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	blks, err := resourcefiles.FormatAsPems(data)
+	if err != nil {
+		return err
+	}
+	var out []byte
+	for _, blk := range blks {
+		if model.ParseResourceType(blk.Type) == model.ResourceTypePrivateKey && isNotNormalised(blk) {
+			k, err := readKey(path, blk)
+			if err != nil {
+				return err
+			}
+			text, err := k.MarshalText()
+			if err != nil {
+				return err
+			}
+			out = append(out, text...)
+			continue
+		}
+		out = append(out, pem.EncodeToMemory(blk)...)
+	}
+	return replaceFile(path, out, fi.Mode().Perm())
+}
+
+// replaceFile writes the data to a temporary file in the same directory, renaming it over the file at the path,
+// so the original file remains intact should the write fail.
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
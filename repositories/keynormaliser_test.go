@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/model"
+	"os"
+	"testing"
+)
+
+func TestNormalise(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pem files are found relative to the working directory
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("sec1.pem", pem.EncodeToMemory(&pem.Block{Type: model.PemTypeECPrivateKey, Bytes: der}), 0640); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := Keys(".").Normalise(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != "sec1.pem" {
+		t.Fatalf("expected sec1.pem to be normalised, found %v", paths)
+	}
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the key file, found %d files", len(entries))
+	}
+	fi, err := os.Stat("sec1.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Errorf("expected file mode to be retained, found %s", fi.Mode().Perm())
+	}
+	data, err := os.ReadFile("sec1.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, _ := pem.Decode(data)
+	if blk == nil || !model.IsNormalisedPem(blk) {
+		t.Fatalf("expected a PKCS#8 key")
+	}
+	prk, err := x509.ParsePKCS8PrivateKey(blk.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.Equal(prk) {
+		t.Errorf("expected the same key")
+	}
+}
@@ -2,21 +2,41 @@ package repositories
 
 import (
 	"context"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"github.com/eurozulu/pempal/agent"
+	"github.com/eurozulu/pempal/config"
 	"github.com/eurozulu/pempal/logging"
 	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
 	"github.com/eurozulu/pempal/resourcefiles"
+	"golang.org/x/crypto/ssh"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 )
 
 type Keys string
 
 type keyFilter func(key *model.PrivateKey) bool
 
+// publicKeyFilter selects keys by the fingerprint of their public key.
+// Encrypted keys are selected before being decrypted, so only the keys selected are decrypted.
+type publicKeyFilter func(fp model.Fingerprint) bool
+
 func (kz Keys) ByPublicKey(puk *model.PublicKey) (*model.PrivateKey, error) {
-	ps := puk.Fingerprint().String()
-	return kz.FindFirst(func(k *model.PrivateKey) bool {
-		return k.Public().Fingerprint().Equals(ps)
-	})
+	ps := puk.Fingerprint()
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+	found, ok := <-kz.find(ctx, func(fp model.Fingerprint) bool {
+		return fp == ps
+	}, nil)
+	if !ok {
+		return nil, fmt.Errorf("no keys found")
+	}
+	return found, nil
 }
 
 func (kz Keys) ByFingerPrint(fingerprint string) (*model.PrivateKey, error) {
@@ -31,9 +51,15 @@ func (kz Keys) MatchByFingerPrint(fingerprint string) []*model.PrivateKey {
 	})
 }
 func (kz Keys) MatchByPublicKeyFingerPrint(fingerprint string) []*model.PrivateKey {
-	return kz.FindAll(func(key *model.PrivateKey) bool {
-		return key.Public().Fingerprint().Match(fingerprint)
-	})
+	ctx, cnl := context.WithCancel(context.Background())
+	defer cnl()
+	var found []*model.PrivateKey
+	for key := range kz.find(ctx, func(fp model.Fingerprint) bool {
+		return fp.Match(fingerprint)
+	}, nil) {
+		found = append(found, key)
+	}
+	return found
 }
 
 func (kz Keys) MatchByAnyFingerPrint(fingerprint string) []*model.PrivateKey {
@@ -64,18 +90,31 @@ func (kz Keys) FindAll(filter keyFilter) []*model.PrivateKey {
 	return found
 }
 
+// Find finds the keys matching the given filter.
+// Encrypted keys can not be filtered without decrypting them, so only those already decrypted or held by the key agent are found.
+// Use ByPublicKey or MatchByPublicKeyFingerPrint to find encrypted keys.
 func (kz Keys) Find(ctx context.Context, filter keyFilter) <-chan *model.PrivateKey {
+	return kz.find(ctx, nil, filter)
+}
+
+func (kz Keys) find(ctx context.Context, pukFilter publicKeyFilter, filter keyFilter) <-chan *model.PrivateKey {
 	found := make(chan *model.PrivateKey)
 	go func() {
 		defer close(found)
 		keyFiles := resourcefiles.PemFiles(kz).FindByType(ctx, model.ResourceTypePrivateKey)
 		for pf := range keyFiles {
 			for _, blk := range pf.Blocks {
-				k, err := model.NewPrivateKeyFromPem(blk)
+				k, err := selectKey(pf.Path, blk, pukFilter)
 				if err != nil {
 					logging.Warning("could not parse pem in %s %v", pf.Path, err)
 					continue
 				}
+				if k == nil {
+					continue
+				}
+				if pukFilter != nil && !pukFilter(k.Public().Fingerprint()) {
+					continue
+				}
 				if filter != nil && !filter(k) {
 					continue
 				}
@@ -85,6 +124,185 @@ func (kz Keys) Find(ctx context.Context, filter keyFilter) <-chan *model.Private
 				}
 			}
 		}
+		if !kz.includesKeyPath() {
+			return
+		}
+		for _, k := range ExternalKeys() {
+			if pukFilter != nil && !pukFilter(k.Public().Fingerprint()) {
+				continue
+			}
+			if filter != nil && !filter(k) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+			case found <- k:
+			}
+		}
 	}()
 	return found
 }
+
+// includesKeyPath checks if any of the paths searched are, or contain, the key path of the config.
+// External signer keys are only searched along with the key path.
+func (kz Keys) includesKeyPath() bool {
+	keyPath, err := filepath.Abs(config.KeyPath())
+	if err != nil {
+		return false
+	}
+	for _, p := range filepath.SplitList(string(kz)) {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(abs, keyPath)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// decryptedKeys caches the keys already decrypted, to save decrypting the same key on each search.
+var decryptedKeys = map[model.Fingerprint]*model.PrivateKey{}
+var decryptedKeysLock sync.Mutex
+
+// selectKey reads the private key in the given pem, when it may be selected by the public key filter.
+// Unencrypted keys are always read.  Encrypted keys already decrypted or held by the key agent are read without a passphrase.
+// Other encrypted keys are only decrypted when the filter selects their public key, found without decrypting them.
+// Returns nil when the key is not selected.
+func selectKey(path string, blk *pem.Block, pukFilter publicKeyFilter) (*model.PrivateKey, error) {
+	if !model.IsEncryptedPem(blk) {
+		return model.NewPrivateKeyFromPem(blk)
+	}
+	if k := heldKey(model.NewFingerPrint(blk.Bytes)); k != nil {
+		return k, nil
+	}
+	if pukFilter == nil {
+		logging.Debug("encrypted key in %s not searched", path)
+		return nil, nil
+	}
+	fp, ok := encryptedPublicKeyFingerprint(path, blk)
+	if !ok {
+		logging.Debug("encrypted key in %s not searched, its public key is unknown. Add a %s file of its public key", path, publicKeyFileExtension)
+		return nil, nil
+	}
+	if !pukFilter(fp) {
+		return nil, nil
+	}
+	k, err := readKey(path, blk)
+	if err != nil {
+		return nil, err
+	}
+	if k.Public().Fingerprint() != fp {
+		return nil, fmt.Errorf("decrypted key does not match its public key %s", fp)
+	}
+	return k, nil
+}
+
+// readKey reads the private key in the given pem.  If the key is encrypted and held by the key agent, the agents key is used.
+// Otherwise its passphrase is obtained from the default passphrase provider, using the path as the name of the key.
+func readKey(path string, blk *pem.Block) (*model.PrivateKey, error) {
+	if !model.IsEncryptedPem(blk) {
+		return model.NewPrivateKeyFromPem(blk)
+	}
+	id := model.NewFingerPrint(blk.Bytes)
+	if k := heldKey(id); k != nil {
+		return k, nil
+	}
+	k, err := parseKey(path, blk)
+	if err != nil {
+		return nil, err
+	}
+	decryptedKeysLock.Lock()
+	defer decryptedKeysLock.Unlock()
+	decryptedKeys[id] = k
+	return k, nil
+}
+
+// parseKey parses the private key in the given pem, without using the key agent.
+// Encrypted keys are decrypted with the passphrase obtained from the default passphrase provider, using the path as the name of the key.
+func parseKey(path string, blk *pem.Block) (*model.PrivateKey, error) {
+	if !model.IsEncryptedPem(blk) {
+		return model.NewPrivateKeyFromPem(blk)
+	}
+	pass, err := passphrases.DefaultProvider.Passphrase(path, false)
+	if err != nil {
+		return nil, err
+	}
+	k, err := model.NewPrivateKeyFromEncryptedPem(blk, pass)
+	if err != nil {
+		passphrases.Invalidate(path)
+		return nil, err
+	}
+	return k, nil
+}
+
+// heldKey finds the encrypted key with the given pem fingerprint, already decrypted or held by the key agent.
+// The lock is not held while asking the agent, so other searches are not held up by its socket.
+func heldKey(id model.Fingerprint) *model.PrivateKey {
+	decryptedKeysLock.Lock()
+	k, ok := decryptedKeys[id]
+	decryptedKeysLock.Unlock()
+	if ok {
+		return k
+	}
+	if k = agentKey(id); k == nil {
+		return nil
+	}
+	decryptedKeysLock.Lock()
+	defer decryptedKeysLock.Unlock()
+	decryptedKeys[id] = k
+	return k
+}
+
+// publicKeyFileExtension is the extension of the file holding the public key of an encrypted key, alongside the key file.
+const publicKeyFileExtension = ".pub"
+
+// encryptedPublicKeyFingerprint finds the public key fingerprint of the encrypted key in the given pem, without decrypting it.
+// The public key is read from a .pub file alongside the key file, or from the key itself, for OpenSSH keys.
+// Failing those, the file name is used when it is a fingerprint, as keys are named by their public key when saved.
+// Returns false if the public key can not be found.
+func encryptedPublicKeyFingerprint(path string, blk *pem.Block) (model.Fingerprint, bool) {
+	for _, p := range []string{
+		path + publicKeyFileExtension,
+		strings.TrimSuffix(path, filepath.Ext(path)) + publicKeyFileExtension,
+	} {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		puk := &model.PublicKey{}
+		if err := puk.UnmarshalText(data); err != nil {
+			logging.Warning("public key file %s could not be read  %v", p, err)
+			continue
+		}
+		return puk.Fingerprint(), true
+	}
+	if strings.EqualFold(blk.Type, model.PemTypeOpenSSHPrivateKey) {
+		var missing *ssh.PassphraseMissingError
+		if _, err := ssh.ParseRawPrivateKey(pem.EncodeToMemory(blk)); errors.As(err, &missing) && missing.PublicKey != nil {
+			if ck, ok := missing.PublicKey.(ssh.CryptoPublicKey); ok {
+				return model.NewPublicKey(ck.CryptoPublicKey()).Fingerprint(), true
+			}
+		}
+	}
+	if fp, err := model.ParseFingerPrint(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))); err == nil {
+		return fp, true
+	}
+	return model.Fingerprint{}, false
+}
+
+// agentKey finds the key with the given pem fingerprint in the key agent, if one is running.
+func agentKey(id model.Fingerprint) *model.PrivateKey {
+	c := agent.DefaultClient()
+	if c == "" {
+		return nil
+	}
+	k, err := c.Find(id.String())
+	if err != nil {
+		logging.Warning("key agent failed  %v", err)
+		return nil
+	}
+	return k
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testProvider []string
+
+func (p *testProvider) Passphrase(name string, confirm bool) ([]byte, error) {
+	*p = append(*p, name)
+	return []byte("secret"), nil
+}
+
+func TestKeysByPublicKeyEncrypted(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pem files are found relative to the working directory
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	dir := "."
+	var keys []*model.PrivateKey
+	for i := 0; i < 3; i++ {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prk := model.NewPrivateKey(k)
+		prk.SetPassphrase([]byte("secret"))
+		data, err := prk.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, prk.Public().Fingerprint().String()+".pem")
+		if i == 2 {
+			// not named by its fingerprint, so requires its public key alongside it
+			path = filepath.Join(dir, "other.pem")
+			pub, err := prk.Public().MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "other.pub"), pub, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, prk)
+	}
+	requested := &testProvider{}
+	defer func(p passphrases.Provider) {
+		passphrases.DefaultProvider = p
+	}(passphrases.DefaultProvider)
+	passphrases.DefaultProvider = requested
+
+	for _, i := range []int{1, 2} {
+		*requested = nil
+		k, err := Keys(dir).ByPublicKey(keys[i].Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.Public().Fingerprint() != keys[i].Public().Fingerprint() {
+			t.Errorf("expected key %d to be found", i)
+		}
+		if len(*requested) != 1 {
+			t.Errorf("expected one passphrase request for key %d, found %v", i, *requested)
+		}
+	}
+	*requested = nil
+	if len(Keys(dir).FindAll(nil)) != 2 {
+		t.Errorf("expected only the decrypted keys to be found without a public key")
+	}
+	if len(*requested) != 0 {
+		t.Errorf("expected no passphrase requests without a public key, found %v", *requested)
+	}
+}
+
+func TestKeysIncludesKeyPath(t *testing.T) {
+	keyPath := config.KeyPath()
+	for path, expect := range map[string]bool{
+		keyPath:                       true,
+		filepath.Dir(keyPath):         true,
+		filepath.Join(keyPath, "sub"): false,
+		"certs":                       false,
+		"certs" + string(filepath.ListSeparator) + keyPath: true,
+		"": false,
+	} {
+		if found := Keys(path).includesKeyPath(); found != expect {
+			t.Errorf("%q: expected %v, found %v", path, expect, found)
+		}
+	}
+}
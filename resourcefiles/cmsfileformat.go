@@ -0,0 +1,42 @@
+package resourcefiles
+
+import (
+	"encoding/pem"
+	"github.com/eurozulu/pempal/cms"
+	"slices"
+)
+
+// CMSFileFormat reads the certificates and CRLs held in CMS signed data, such as .p7s, .p7m and .p7b files.
+// Signed data may be either der or CMS/PKCS7 pems.  Other pems in the same file are passed through.
+type CMSFileFormat struct{}
+
+func (c CMSFileFormat) Format(data []byte) ([]*pem.Block, error) {
+	p7 := PKCS7Parser{}
+	blks, _ := PemFileFormat{}.Format(data)
+	if len(blks) == 0 {
+		if !p7.CanParse(data) {
+			return nil, nil
+		}
+		return p7.ParsePems(data)
+	}
+	if !slices.ContainsFunc(blks, cms.IsCMSPem) {
+		return nil, nil
+	}
+	var found []*pem.Block
+	for _, blk := range blks {
+		if !cms.IsCMSPem(blk) {
+			found = append(found, blk)
+			continue
+		}
+		// other cms content, such as enveloped data, has no certificates
+		if !p7.CanParse(blk.Bytes) {
+			continue
+		}
+		pems, err := p7.ParsePems(blk.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, pems...)
+	}
+	return found, nil
+}
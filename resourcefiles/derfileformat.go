@@ -42,6 +42,18 @@ func (d Derfileformat) Format(data []byte) ([]*pem.Block, error) {
 		}}, nil
 
 	}
+	if _, err := x509.ParsePKCS1PrivateKey(data); err == nil {
+		return []*pem.Block{{
+			Type:  model.PemTypeRSAPrivateKey,
+			Bytes: data,
+		}}, nil
+	}
+	if _, err := x509.ParseECPrivateKey(data); err == nil {
+		return []*pem.Block{{
+			Type:  model.PemTypeECPrivateKey,
+			Bytes: data,
+		}}, nil
+	}
 	if _, err := x509.ParseCertificateRequest(data); err == nil {
 		return []*pem.Block{{
 			Type:  model.ResourceTypeCertificateRequest.String(),
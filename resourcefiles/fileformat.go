@@ -2,7 +2,9 @@ package resourcefiles
 
 import (
 	"encoding/pem"
+	"github.com/eurozulu/pempal/jks"
 	"github.com/eurozulu/pempal/logging"
+	"github.com/eurozulu/pempal/pkcs12"
 )
 
 type FileFormat interface {
@@ -10,7 +12,12 @@ type FileFormat interface {
 }
 
 var knownPemFormats = []FileFormat{
+	&CMSFileFormat{},
 	&PemFileFormat{},
+	&SSHFileFormat{},
+	&JWKFileFormat{},
+	&PKCS12FileFormat{},
+	&JKSFileFormat{},
 	&Derfileformat{},
 }
 
@@ -30,3 +37,14 @@ func FormatAsPems(data []byte) ([]*pem.Block, error) {
 	}
 	return blocks, nil
 }
+
+// FormatFileAsPems formats the data of the named file, naming the file when a passphrase is required to read it.
+func FormatFileAsPems(path string, data []byte) ([]*pem.Block, error) {
+	if pkcs12.IsPKCS12(data) {
+		return PKCS12FileFormat{Name: path}.Format(data)
+	}
+	if jks.IsKeyStore(data) {
+		return JKSFileFormat{Name: path}.Format(data)
+	}
+	return FormatAsPems(data)
+}
@@ -0,0 +1,80 @@
+package resourcefiles
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/jks"
+	"github.com/eurozulu/pempal/logging"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
+	"slices"
+)
+
+// JKSFileFormat reads the keys and certificates of Java JKS and JCEKS keystores and truststores.
+// Stores are read without their password, so the certificates are read without verifying the store integrity.
+// Only when the store holds private keys, is the passphrase for Name requested, to decrypt them.
+// Without a passphrase, or when it fails to decrypt the keys, only the certificates are read.
+// Once the keys are decrypted, the store integrity is verified with the passphrase, warning when it does not match.
+type JKSFileFormat struct {
+	// Name identifies the file when requesting its passphrase, usually its path.
+	Name string
+}
+
+func (j JKSFileFormat) Format(data []byte) ([]*pem.Block, error) {
+	if !jks.IsKeyStore(data) {
+		return nil, nil
+	}
+	name := j.Name
+	if name == "" {
+		name = "keystore"
+	}
+	ks, err := jks.Decode(data, "")
+	if err != nil {
+		return nil, err
+	}
+	var password string
+	if len(ks.PrivateKeys) > 0 {
+		if pass, err := passphrases.DefaultProvider.Passphrase(name, false); err != nil {
+			logging.Warning("reading certificates of %s only  %v", name, err)
+		} else {
+			password = string(pass)
+		}
+	}
+
+	var blks []*pem.Block
+	var certs []*x509.Certificate
+	addCerts := func(cz ...*x509.Certificate) {
+		for _, c := range cz {
+			if !slices.ContainsFunc(certs, func(e *x509.Certificate) bool { return bytes.Equal(e.Raw, c.Raw) }) {
+				certs = append(certs, c)
+			}
+		}
+	}
+	for _, entry := range ks.PrivateKeys {
+		addCerts(entry.Certificates...)
+		if password == "" {
+			continue
+		}
+		der, err := entry.Decrypt(password)
+		if err != nil {
+			logging.Warning("reading certificates of %s only, failed to decrypt key %s  %v", name, entry.Alias, err)
+			passphrases.Invalidate(name)
+			password = ""
+			continue
+		}
+		blks = append(blks, &pem.Block{Type: model.ResourceTypePrivateKey.String(), Bytes: der})
+	}
+	if password != "" {
+		if err := jks.VerifyIntegrity(data, password); err != nil {
+			logging.Warning("integrity of %s does not match its password, the store may have been modified  %v", name, err)
+		}
+	}
+	for _, entry := range ks.TrustedCertificates {
+		addCerts(entry.Certificate)
+	}
+	for _, c := range certs {
+		blks = append(blks, &pem.Block{Type: model.ResourceTypeCertificate.String(), Bytes: c.Raw})
+	}
+	return blks, nil
+}
@@ -38,7 +38,7 @@ func (p PemFiles) Find(ctx context.Context, filter ...PemFileFilter) <-chan *mod
 				logging.Warning("failed to open %s  %v", pPAth, err)
 				continue
 			}
-			blks, err := FormatAsPems(data)
+			blks, err := FormatFileAsPems(pPAth, data)
 			if err != nil {
 				logging.Error("failed to parse %s  %v", pPAth, err)
 				continue
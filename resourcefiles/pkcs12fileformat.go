@@ -0,0 +1,52 @@
+package resourcefiles
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
+	"github.com/eurozulu/pempal/pkcs12"
+)
+
+// PKCS12FileFormat reads the keys, certificates and CRLs of PKCS#12 (PFX) files, such as .p12 and .pfx files.
+// An empty password is tried first, then the passphrase for Name is requested.
+type PKCS12FileFormat struct {
+	// Name identifies the file when requesting its passphrase, usually its path.
+	Name string
+}
+
+func (p PKCS12FileFormat) Format(data []byte) ([]*pem.Block, error) {
+	if !pkcs12.IsPKCS12(data) {
+		return nil, nil
+	}
+	p12, err := pkcs12.Decode(data, "")
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		name := p.Name
+		if name == "" {
+			name = "pkcs12"
+		}
+		pass, perr := passphrases.DefaultProvider.Passphrase(name, false)
+		if perr != nil {
+			return nil, fmt.Errorf("no passphrase for %s  %v", name, perr)
+		}
+		p12, err = pkcs12.Decode(data, string(pass))
+		if err != nil {
+			passphrases.Invalidate(name)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var blks []*pem.Block
+	for _, der := range p12.PrivateKeys {
+		blks = append(blks, &pem.Block{Type: model.ResourceTypePrivateKey.String(), Bytes: der})
+	}
+	for _, c := range p12.Certificates {
+		blks = append(blks, &pem.Block{Type: model.ResourceTypeCertificate.String(), Bytes: c.Raw})
+	}
+	for _, crl := range p12.CRLs {
+		blks = append(blks, &pem.Block{Type: model.ResourceTypeRevokationList.String(), Bytes: crl.Raw})
+	}
+	return blks, nil
+}
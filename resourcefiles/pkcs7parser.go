@@ -1,41 +1,33 @@
 package resourcefiles
 
-//
-//import (
-//	"encoding/pem"
-//	"fmt"
-//	"go.mozilla.org/pkcs7"
-//	"os"
-//)
-//
-//type PKCS7Parser struct {
-//}
-//
-//func (P PKCS7Parser) CanParse(data []byte) bool {
-//	if _, err := pkcs7.Parse(data); err == nil {
-//		return true
-//	}
-//	return false
-//}
-//
-//func (P PKCS7Parser) ParsePems(data []byte) []pem.Block {
-//	p7, err := pkcs7.Parse(data)
-//	if err != nil {
-//		fmt.Fprintf(os.Stderr, "Error parsing PKCS7 file: %v\n", err)
-//		return nil
-//	}
-//	var blocks []pem.Block
-//	for _, c := range p7.Certificates {
-//		blocks = append(blocks, pem.Block{
-//			PublicKeyAlgorithm:  "CERTIFICATE",
-//			Bytes: c.Raw,
-//		})
-//	}
-//	for _, c := range p7.CRLs {
-//		blocks = append(blocks, pem.Block{
-//			PublicKeyAlgorithm:  "X509 CRL",
-//			Bytes: c.TBSCertList.Raw,
-//		})
-//	}
-//	return blocks
-//}
+import (
+	"encoding/pem"
+	"github.com/eurozulu/pempal/cms"
+	"github.com/eurozulu/pempal/model"
+)
+
+// PKCS7Parser reads the certificates and CRLs of PKCS#7 signed data.
+// This includes the degenerate signed data, with no content or signers, of .p7b and .p7c certificate bundles.
+type PKCS7Parser struct{}
+
+// CanParse checks if the der is signed data.  Other CMS content, such as enveloped data, can not be parsed.
+func (p PKCS7Parser) CanParse(der []byte) bool {
+	ct, _, err := cms.ParseContentInfo(der)
+	return err == nil && ct.Equal(cms.OIDSignedData)
+}
+
+// ParsePems gives the certificates and CRLs of the signed data der, as pems.
+func (p PKCS7Parser) ParsePems(der []byte) ([]*pem.Block, error) {
+	sd, err := cms.ParseSignedData(der)
+	if err != nil {
+		return nil, err
+	}
+	var blks []*pem.Block
+	for _, c := range sd.Certificates {
+		blks = append(blks, &pem.Block{Type: model.ResourceTypeCertificate.String(), Bytes: c.Raw})
+	}
+	for _, crl := range sd.CRLs {
+		blks = append(blks, &pem.Block{Type: model.ResourceTypeRevokationList.String(), Bytes: crl.Raw})
+	}
+	return blks, nil
+}
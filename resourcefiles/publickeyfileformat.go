@@ -0,0 +1,59 @@
+package resourcefiles
+
+import (
+	"bytes"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/model"
+)
+
+// SSHFileFormat reads OpenSSH authorized_keys, public key and certificate files, as public key and ssh certificate pems.
+type SSHFileFormat struct{}
+
+func (s SSHFileFormat) Format(data []byte) ([]*pem.Block, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("ssh-")) &&
+		!bytes.HasPrefix(bytes.TrimSpace(data), []byte("ecdsa-")) {
+		return nil, nil
+	}
+	pukz, err := model.ParseAuthorizedKeys(data)
+	if err != nil {
+		return nil, err
+	}
+	blks, err := publicKeyPems(pukz)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := model.ParseSSHCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, cert := range certs {
+		blks = append(blks, &pem.Block{Type: model.ResourceTypeSSHCertificate.String(), Bytes: cert.Marshal()})
+	}
+	return blks, nil
+}
+
+// JWKFileFormat reads JSON Web Keys and JWK sets, as public key pems.
+type JWKFileFormat struct{}
+
+func (j JWKFileFormat) Format(data []byte) ([]*pem.Block, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return nil, nil
+	}
+	pukz, err := model.ParseJWKs(data)
+	if err != nil {
+		return nil, nil
+	}
+	return publicKeyPems(pukz)
+}
+
+func publicKeyPems(pukz []*model.PublicKey) ([]*pem.Block, error) {
+	blks := make([]*pem.Block, len(pukz))
+	for i, puk := range pukz {
+		der, err := puk.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		blks[i] = &pem.Block{Type: model.ResourceTypePublicKey.String(), Bytes: der}
+	}
+	return blks, nil
+}
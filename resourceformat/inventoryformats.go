@@ -0,0 +1,169 @@
+package resourceformat
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// inventoryColumns are the names of the inventory record fields, in column order.
+var inventoryColumns = []string{
+	"type", "fingerprint", "subject", "issuer", "serial", "not-before", "not-after", "key-algorithm", "sans", "path",
+}
+
+// inventoryRecord is the flattened summary of a single resource, as exported by the csv and jsonl formats.
+// Fields not relevant to a resource type are left empty.
+type inventoryRecord struct {
+	Type         string   `json:"type"`
+	Fingerprint  string   `json:"fingerprint"`
+	Subject      string   `json:"subject"`
+	Issuer       string   `json:"issuer"`
+	Serial       string   `json:"serial"`
+	NotBefore    string   `json:"not-before"`
+	NotAfter     string   `json:"not-after"`
+	KeyAlgorithm string   `json:"key-algorithm"`
+	SANs         []string `json:"sans"`
+	Path         string   `json:"path"`
+}
+
+func (r inventoryRecord) columns() []string {
+	return []string{
+		r.Type, r.Fingerprint, r.Subject, r.Issuer, r.Serial, r.NotBefore, r.NotAfter, r.KeyAlgorithm,
+		strings.Join(r.SANs, ";"), r.Path,
+	}
+}
+
+// CSVFormat writes one comma separated record per resource, headed by the column names.
+type CSVFormat struct{}
+
+func (f CSVFormat) Header(out io.Writer) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(inventoryColumns); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (f CSVFormat) Format(out io.Writer, p *model.PemFile) error {
+	w := csv.NewWriter(out)
+	for _, r := range p.Resources() {
+		if err := w.Write(newInventoryRecord(r, p.Path).columns()); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// JSONLinesFormat writes one json object per resource, each on a single line.
+type JSONLinesFormat struct{}
+
+func (f JSONLinesFormat) Format(out io.Writer, p *model.PemFile) error {
+	enc := json.NewEncoder(out)
+	for _, r := range p.Resources() {
+		if err := enc.Encode(newInventoryRecord(r, p.Path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newInventoryRecord(r model.PemResource, path string) inventoryRecord {
+	rec := inventoryRecord{
+		Type:        r.ResourceType().String(),
+		Fingerprint: r.Fingerprint().String(),
+		SANs:        []string{},
+		Path:        path,
+	}
+	switch v := r.(type) {
+	case *model.Certificate:
+		rec.Subject = v.Subject.String()
+		rec.Issuer = v.Issuer.String()
+		rec.Serial = serialText(v.SerialNumber)
+		rec.NotBefore = inventoryTime(v.NotBefore)
+		rec.NotAfter = inventoryTime(v.NotAfter)
+		rec.KeyAlgorithm = keyAlgorithmText(v.PublicKey)
+		rec.SANs = subjectAltNames(v.DNSNames, v.EmailAddresses, v.IPAddresses, v.URIs)
+	case *model.CertificateRequest:
+		rec.Subject = v.Subject.String()
+		rec.KeyAlgorithm = keyAlgorithmText(v.PublicKey)
+		rec.SANs = subjectAltNames(v.DNSNames, v.EmailAddresses, v.IPAddresses, v.URIs)
+	case *model.RevocationList:
+		rec.Issuer = v.Issuer.String()
+		rec.Serial = serialText(v.Number)
+		rec.NotBefore = inventoryTime(v.ThisUpdate)
+		rec.NotAfter = inventoryTime(v.NextUpdate)
+	case *model.PrivateKey:
+		if signer, ok := v.Private().(crypto.Signer); ok {
+			rec.KeyAlgorithm = keyAlgorithmText(signer.Public())
+		} else {
+			rec.KeyAlgorithm = v.PublicKeyAlgorithm().String()
+		}
+	case *model.PublicKey:
+		rec.KeyAlgorithm = keyAlgorithmText(v.Public())
+	case *model.SSHCertificate:
+		rec.Subject = v.KeyId
+		if ca, err := v.CAKey(); err == nil {
+			rec.Issuer = ca.Fingerprint().String()
+		}
+		rec.Serial = fmt.Sprintf("%d", v.Serial)
+		rec.NotBefore = inventoryTime(v.ValidAfterTime())
+		rec.NotAfter = inventoryTime(v.ValidBeforeTime())
+		if puk, err := v.PublicKey(); err == nil {
+			rec.KeyAlgorithm = keyAlgorithmText(puk.Public())
+		}
+		rec.SANs = append(rec.SANs, v.ValidPrincipals...)
+	}
+	return rec
+}
+
+// keyAlgorithmText gives the algorithm of the key with its size or curve, e.g. RSA-2048 or ECDSA-P256
+func keyAlgorithmText(puk crypto.PublicKey) string {
+	alg := model.NewPublicKey(puk).PublicKeyAlgorithm().String()
+	if k, ok := puk.(*ecdsa.PublicKey); ok {
+		if c, err := model.NewCurve(k.Curve); err == nil {
+			return fmt.Sprintf("%s-%s", alg, c)
+		}
+		return alg
+	}
+	if bits := publicKeyBits(puk); bits > 0 && alg != "Ed25519" {
+		return fmt.Sprintf("%s-%d", alg, bits)
+	}
+	return alg
+}
+
+func subjectAltNames(dns, emails []string, ips []net.IP, uris []*url.URL) []string {
+	sans := append(append([]string{}, dns...), emails...)
+	for _, ip := range ips {
+		sans = append(sans, ip.String())
+	}
+	for _, u := range uris {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+func serialText(n *big.Int) string {
+	if n == nil {
+		return ""
+	}
+	return n.String()
+}
+
+// inventoryTime gives the time in RFC3339 UTC, or empty when not set
+func inventoryTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
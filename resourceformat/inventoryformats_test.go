@@ -0,0 +1,57 @@
+package resourceformat
+
+import (
+	"bytes"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/model"
+	"testing"
+)
+
+const (
+	inventoryCSV = `type,fingerprint,subject,issuer,serial,not-before,not-after,key-algorithm,sans,path
+CERTIFICATE,aac86a7492e1a878447c7c1c9812d2cfd743d12b,"CN=pempal text test,O=Pempal,C=GB","CN=pempal text test,O=Pempal,C=GB",4660,2025-01-01T00:00:00Z,2035-01-01T00:00:00Z,ECDSA-P256,pempal.example;ca@pempal.example;192.0.2.1;https://pempal.example/ca,certs/ca.pem
+CERTIFICATE REQUEST,89d71ddee14176c1547b9abd16058f43d040b2d8,"CN=www.pempal.example,O=Pempal",,,,,ECDSA-P256,www.pempal.example,certs/ca.pem
+X509 CRL,3b292bfc7c7033853780ad5e0668a30993237ce7,,"CN=pempal text test,O=Pempal,C=GB",,2025-06-01T00:00:00Z,2025-07-01T00:00:00Z,,,certs/ca.pem
+PRIVATE KEY,288ac2b6de8598aa0f1a28d38f43db583feedc56,,,,,,ECDSA-P256,,certs/ca.pem
+`
+	inventoryJSONL = `{"type":"CERTIFICATE","fingerprint":"aac86a7492e1a878447c7c1c9812d2cfd743d12b","subject":"CN=pempal text test,O=Pempal,C=GB","issuer":"CN=pempal text test,O=Pempal,C=GB","serial":"4660","not-before":"2025-01-01T00:00:00Z","not-after":"2035-01-01T00:00:00Z","key-algorithm":"ECDSA-P256","sans":["pempal.example","ca@pempal.example","192.0.2.1","https://pempal.example/ca"],"path":"certs/ca.pem"}
+{"type":"CERTIFICATE REQUEST","fingerprint":"89d71ddee14176c1547b9abd16058f43d040b2d8","subject":"CN=www.pempal.example,O=Pempal","issuer":"","serial":"","not-before":"","not-after":"","key-algorithm":"ECDSA-P256","sans":["www.pempal.example"],"path":"certs/ca.pem"}
+{"type":"X509 CRL","fingerprint":"3b292bfc7c7033853780ad5e0668a30993237ce7","subject":"","issuer":"CN=pempal text test,O=Pempal,C=GB","serial":"","not-before":"2025-06-01T00:00:00Z","not-after":"2025-07-01T00:00:00Z","key-algorithm":"","sans":[],"path":"certs/ca.pem"}
+{"type":"PRIVATE KEY","fingerprint":"288ac2b6de8598aa0f1a28d38f43db583feedc56","subject":"","issuer":"","serial":"","not-before":"","not-after":"","key-algorithm":"ECDSA-P256","sans":[],"path":"certs/ca.pem"}
+`
+)
+
+func TestInventoryFormats(t *testing.T) {
+	p := &model.PemFile{Path: "certs/ca.pem"}
+	for _, s := range []string{textCertPEM, textReqPEM, textCrlPEM, textKeyPEM} {
+		blk, _ := pem.Decode([]byte(s))
+		p.Blocks = append(p.Blocks, blk)
+	}
+	for _, tc := range []struct {
+		format string
+		expect string
+	}{
+		{"csv", inventoryCSV},
+		{"jsonl", inventoryJSONL},
+	} {
+		format, err := NewResourceFormat(tc.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a format may be reused, so each run must output the same header
+		for run := 0; run < 2; run++ {
+			buf := bytes.NewBuffer(nil)
+			if hf, ok := format.(HeaderFormat); ok {
+				if err := hf.Header(buf); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := format.Format(buf, p); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tc.expect {
+				t.Errorf("%s: unexpected output\n%s\nexpected\n%s", tc.format, buf.String(), tc.expect)
+			}
+		}
+	}
+}
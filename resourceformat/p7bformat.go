@@ -0,0 +1,54 @@
+package resourceformat
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/cms"
+	"github.com/eurozulu/pempal/model"
+	"io"
+)
+
+// P7BFormat bundles every certificate and CRL into a single PKCS#7 certificates only pem, as used by .p7b files.
+// Resources found in more than one file are bundled once.
+type P7BFormat struct {
+	certs []*x509.Certificate
+	crls  []*x509.RevocationList
+	seen  map[model.Fingerprint]bool
+}
+
+func (f *P7BFormat) Format(out io.Writer, p *model.PemFile) error {
+	if f.seen == nil {
+		f.seen = map[model.Fingerprint]bool{}
+	}
+	for _, res := range p.Resources() {
+		fp := res.Fingerprint()
+		if f.seen[fp] {
+			continue
+		}
+		switch v := res.(type) {
+		case *model.Certificate:
+			f.certs = append(f.certs, (*x509.Certificate)(v))
+			f.seen[fp] = true
+		case *model.RevocationList:
+			f.crls = append(f.crls, (*x509.RevocationList)(v))
+			f.seen[fp] = true
+		}
+	}
+	return nil
+}
+
+func (f *P7BFormat) Flush(out io.Writer) error {
+	defer func() {
+		f.certs = nil
+		f.crls = nil
+		f.seen = nil
+	}()
+	if len(f.certs) == 0 && len(f.crls) == 0 {
+		return nil
+	}
+	der, err := cms.EncodeCertificates(f.certs, f.crls)
+	if err != nil {
+		return err
+	}
+	return pem.Encode(out, &pem.Block{Type: cms.PemTypePKCS7, Bytes: der})
+}
@@ -0,0 +1,83 @@
+package resourceformat
+
+import (
+	"bytes"
+	"encoding/pem"
+	"github.com/eurozulu/pempal/cms"
+	"github.com/eurozulu/pempal/model"
+	"testing"
+)
+
+func TestP7BFormat(t *testing.T) {
+	pemFile := func(path string, pems ...string) *model.PemFile {
+		p := &model.PemFile{Path: path}
+		for _, s := range pems {
+			blk, _ := pem.Decode([]byte(s))
+			p.Blocks = append(p.Blocks, blk)
+		}
+		return p
+	}
+	// the certificate is in both files, the crl only in the second
+	files := []*model.PemFile{
+		pemFile("certs/ca.pem", textCertPEM),
+		pemFile("bundle.pem", textCertPEM, textCrlPEM, textKeyPEM),
+	}
+
+	// an unflushed format does not affect the formats of later views
+	unflushed, err := NewResourceFormat("p7b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unflushed.Format(bytes.NewBuffer(nil), files[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	format, err := NewResourceFormat("p7b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf, ok := format.(BundleFormat)
+	if !ok {
+		t.Fatalf("expected p7b to be a bundle format")
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := bf.Flush(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected a new format to have nothing to flush")
+	}
+	for _, p := range files {
+		if err := bf.Format(buf, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output until flushed")
+	}
+	if err := bf.Flush(buf); err != nil {
+		t.Fatal(err)
+	}
+	sd, err := cms.ReadSignedData(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sd.Certificates) != 1 || len(sd.CRLs) != 1 {
+		t.Errorf("expected 1 certificate and 1 crl, found %d and %d", len(sd.Certificates), len(sd.CRLs))
+	}
+
+	// a flushed format starts a new bundle
+	buf.Reset()
+	if err := bf.Format(buf, files[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := bf.Flush(buf); err != nil {
+		t.Fatal(err)
+	}
+	if sd, err = cms.ReadSignedData(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sd.Certificates) != 1 || len(sd.CRLs) != 1 {
+		t.Errorf("expected 1 certificate and 1 crl after flushing, found %d and %d", len(sd.Certificates), len(sd.CRLs))
+	}
+}
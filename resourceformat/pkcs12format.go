@@ -0,0 +1,87 @@
+package resourceformat
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"github.com/eurozulu/pempal/config"
+	"github.com/eurozulu/pempal/model"
+	"github.com/eurozulu/pempal/passphrases"
+	"github.com/eurozulu/pempal/pkcs12"
+	"github.com/eurozulu/pempal/repositories"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// PKCS12Format outputs the first certificate of a file, its private key and the chain of its issuers, as a password protected PKCS#12 (PFX).
+// Only a single file with a certificate can be output as a pfx. Files without a certificate are not output.
+// The key is read from the file or, when not in the file, found in the key repository.
+// The passphrase is requested for the file path with a .pfx extension.
+// Legacy uses triple DES and a SHA-1 mac, for older Windows and Java consumers.
+type PKCS12Format struct {
+	Legacy bool
+}
+
+// Formats checks if the file has a certificate
+func (f PKCS12Format) Formats(p *model.PemFile) bool {
+	for _, res := range p.Resources() {
+		if _, ok := res.(*model.Certificate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (f PKCS12Format) Format(out io.Writer, p *model.PemFile) error {
+	var cert *model.Certificate
+	var keys []*model.PrivateKey
+	for _, res := range p.Resources() {
+		switch v := res.(type) {
+		case *model.Certificate:
+			if cert == nil {
+				cert = v
+			}
+		case *model.PrivateKey:
+			keys = append(keys, v)
+		}
+	}
+	if cert == nil {
+		return nil
+	}
+	key, err := certificateKey(cert, keys)
+	if err != nil {
+		return err
+	}
+	certs := []*x509.Certificate{(*x509.Certificate)(cert)}
+	for _, c := range repositories.Certificates(config.SearchPath()).Chain(cert) {
+		certs = append(certs, (*x509.Certificate)(c))
+	}
+
+	name := strings.TrimSuffix(p.Path, filepath.Ext(p.Path)) + ".pfx"
+	pass, err := passphrases.DefaultProvider.Passphrase(name, true)
+	if err != nil {
+		return err
+	}
+	data, err := pkcs12.Encode(key, certs, string(pass), f.Legacy)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// certificateKey finds the private key of the certificate, from the given keys or the key repository.
+func certificateKey(cert *model.Certificate, keys []*model.PrivateKey) (crypto.PrivateKey, error) {
+	puk := model.NewPublicKey(cert.PublicKey)
+	for _, k := range keys {
+		if !k.IsEncrypted() && k.Public().Fingerprint() == puk.Fingerprint() {
+			return k.Private(), nil
+		}
+	}
+	prk, err := repositories.Keys(config.KeyPath()).ByPublicKey(puk)
+	if err != nil {
+		return nil, fmt.Errorf("no private key found for certificate %s  %v", cert.Subject.CommonName, err)
+	}
+	return prk.Private(), nil
+}
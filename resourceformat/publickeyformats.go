@@ -0,0 +1,118 @@
+package resourceformat
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"io"
+)
+
+// SSHFormat outputs the public key of each resource as an OpenSSH authorized_keys line.
+// Each line is commented with the resource summary.
+type SSHFormat struct{}
+
+func (s SSHFormat) Format(out io.Writer, p *model.PemFile) error {
+	for _, res := range p.Resources() {
+		puk, err := model.PublicKeyOf(res)
+		if err != nil {
+			continue
+		}
+		line, err := puk.MarshalAuthorizedKey(resourceComment(res))
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JWKFormat outputs the public key of each resource as a JSON Web Key.
+// Files with multiple public keys are output as a JWK set.
+type JWKFormat struct{}
+
+func (j JWKFormat) Format(out io.Writer, p *model.PemFile) error {
+	var jwks []*model.JWK
+	for _, res := range p.Resources() {
+		puk, err := model.PublicKeyOf(res)
+		if err != nil {
+			continue
+		}
+		jwk, err := model.NewJWK(puk)
+		if err != nil {
+			return err
+		}
+		if cert, ok := res.(*model.Certificate); ok {
+			jwk.X5c = []string{base64.StdEncoding.EncodeToString(cert.Raw)}
+		}
+		jwks = append(jwks, jwk)
+	}
+	if len(jwks) == 0 {
+		return nil
+	}
+	var v interface{} = &model.JWKSet{Keys: jwks}
+	if len(jwks) == 1 {
+		v = jwks[0]
+	}
+	return json.NewEncoder(out).Encode(v)
+}
+
+// PKIXFormat outputs the public key of each resource as PKIX public key pems.
+type PKIXFormat struct{}
+
+func (k PKIXFormat) Format(out io.Writer, p *model.PemFile) error {
+	for _, res := range p.Resources() {
+		puk, err := model.PublicKeyOf(res)
+		if err != nil {
+			continue
+		}
+		der, err := puk.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := pem.Encode(out, &pem.Block{Type: model.ResourceTypePublicKey.String(), Bytes: der}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PKIXDERFormat outputs the public key of a single resource as PKIX der.
+type PKIXDERFormat struct{}
+
+func (k PKIXDERFormat) Format(out io.Writer, p *model.PemFile) error {
+	var pukz []*model.PublicKey
+	for _, res := range p.Resources() {
+		if puk, err := model.PublicKeyOf(res); err == nil {
+			pukz = append(pukz, puk)
+		}
+	}
+	if len(pukz) == 0 {
+		return nil
+	}
+	if len(pukz) > 1 {
+		return fmt.Errorf("multiple public keys in %s.  pkix-der can only format a single key", p.Path)
+	}
+	der, err := pukz[0].MarshalBinary()
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(der)
+	return err
+}
+
+func resourceComment(res model.PemResource) string {
+	switch r := res.(type) {
+	case *model.Certificate:
+		return r.Subject.CommonName
+	case *model.CertificateRequest:
+		return r.Subject.CommonName
+	case *model.SSHCertificate:
+		return r.KeyId
+	default:
+		return ""
+	}
+}
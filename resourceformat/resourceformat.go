@@ -4,37 +4,99 @@ import (
 	"fmt"
 	"github.com/eurozulu/pempal/model"
 	"io"
+	"slices"
+	"sort"
 	"strings"
 )
 
 const DefaultFormat = "yaml"
 
-var views = map[string]ResourceFormat{
-	"list": &ListFormat{},
-	"pem":  &PemFormat{},
-	"der":  &DERFormat{},
-	"yaml": &YamlFormat{},
-	"json": &JsonFormat{},
+// views constructs the format of each name, so formats which collect state, such as bundles, are not shared between views.
+var views = map[string]func() ResourceFormat{
+	"list": func() ResourceFormat { return &ListFormat{} },
+	"pem":  func() ResourceFormat { return &PemFormat{} },
+	"der":  func() ResourceFormat { return &DERFormat{} },
+	"yaml": func() ResourceFormat { return &YamlFormat{} },
+	"json": func() ResourceFormat { return &JsonFormat{} },
+	"text": func() ResourceFormat { return &TextFormat{} },
+
+	"csv":   func() ResourceFormat { return &CSVFormat{} },
+	"jsonl": func() ResourceFormat { return &JSONLinesFormat{} },
+
+	"ssh":      func() ResourceFormat { return &SSHFormat{} },
+	"jwk":      func() ResourceFormat { return &JWKFormat{} },
+	"pkix":     func() ResourceFormat { return &PKIXFormat{} },
+	"pkix-der": func() ResourceFormat { return &PKIXDERFormat{} },
+
+	"pfx":        func() ResourceFormat { return &PKCS12Format{} },
+	"pfx-legacy": func() ResourceFormat { return &PKCS12Format{Legacy: true} },
+	"p7b":        func() ResourceFormat { return &P7BFormat{} },
 }
 
+// baseFormats are the original formats, preferred when an abbreviated format matches more than one.
+var baseFormats = []string{"list", "pem", "der", "yaml", "json"}
+
 type ResourceFormat interface {
 	Format(out io.Writer, p *model.PemFile) error
 }
 
+// SingleFileFormat formats the resources of a single file, such as a pfx, so can only be given one file it formats.
+type SingleFileFormat interface {
+	ResourceFormat
+	// Formats checks if the given file has any resources the format would format.
+	Formats(p *model.PemFile) bool
+}
+
+// HeaderFormat heads its output, such as with column names, ahead of the first file it formats.
+type HeaderFormat interface {
+	ResourceFormat
+	Header(out io.Writer) error
+}
+
+// BundleFormat collects the resources of every file it formats, writing them as a single bundle when flushed.
+type BundleFormat interface {
+	ResourceFormat
+	Flush(out io.Writer) error
+}
+
 func NewResourceFormat(format string) (ResourceFormat, error) {
+	name, err := formatName(format)
+	if err != nil {
+		return nil, err
+	}
+	return views[name](), nil
+}
+
+// formatName gives the name of the view format, matching the start of the given format when not the full name.
+func formatName(format string) (string, error) {
 	if format == "" {
 		format = DefaultFormat
 	}
 	format = strings.ToLower(format)
-	if rv, ok := views[format]; ok {
-		return rv, nil
+	if _, ok := views[format]; ok {
+		return format, nil
 	}
-	for k, v := range views {
+	var names []string
+	for k := range views {
 		if strings.HasPrefix(k, format) {
-			return v, nil
+			names = append(names, k)
+		}
+	}
+	if len(names) > 1 {
+		// abbreviations of the original formats keep their meaning, as formats have since been added
+		if i := slices.IndexFunc(baseFormats, func(k string) bool { return strings.HasPrefix(k, format) }); i >= 0 {
+			names = baseFormats[i : i+1]
 		}
 	}
-	return nil, fmt.Errorf("%q is not a known resource view format", format)
+	switch len(names) {
+	case 0:
+		return "", fmt.Errorf("%q is not a known resource view format", format)
+	case 1:
+		return names[0], nil
+	default:
+		sort.Strings(names)
+		return "", fmt.Errorf("%q is an ambiguous format, matching %s", format, strings.Join(names, ", "))
+	}
 }
 
 func FormatResources(format string, out io.Writer, p *model.PemFile) error {
@@ -0,0 +1,36 @@
+package resourceformat
+
+import "testing"
+
+func TestFormatName(t *testing.T) {
+	for format, expect := range map[string]string{
+		"":      DefaultFormat,
+		"p":     "pem",
+		"j":     "json",
+		"JSON":  "json",
+		"js":    "json",
+		"jsonl": "jsonl",
+		"jw":    "jwk",
+		"pfx":   "pfx",
+		"pfx-":  "pfx-legacy",
+		"pkix":  "pkix",
+		"pkix-": "pkix-der",
+		"t":     "text",
+		"c":     "csv",
+		"s":     "ssh",
+	} {
+		name, err := formatName(format)
+		if err != nil {
+			t.Errorf("%q: %v", format, err)
+			continue
+		}
+		if name != expect {
+			t.Errorf("%q: expected format %s, found %s", format, expect, name)
+		}
+	}
+	for _, format := range []string{"pf", "pk", "x"} {
+		if _, err := formatName(format); err == nil {
+			t.Errorf("%q: expected an ambiguous or unknown format to fail", format)
+		}
+	}
+}
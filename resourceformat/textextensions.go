@@ -0,0 +1,520 @@
+package resourceformat
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+)
+
+// extensionText decodes the value of an extension into the lines of text describing it
+type extensionText func(value []byte) ([]string, error)
+
+type extensionDecoder struct {
+	name   string
+	decode extensionText
+}
+
+var extensionDecoders = map[string]extensionDecoder{
+	"2.5.29.14":               {"X509v3 Subject Key Identifier", keyIdentifierText},
+	"2.5.29.15":               {"X509v3 Key Usage", keyUsageText},
+	"2.5.29.17":               {"X509v3 Subject Alternative Name", generalNamesText},
+	"2.5.29.18":               {"X509v3 Issuer Alternative Name", generalNamesText},
+	"2.5.29.19":               {"X509v3 Basic Constraints", basicConstraintsText},
+	"2.5.29.20":               {"X509v3 CRL Number", integerText},
+	"2.5.29.21":               {"X509v3 CRL Reason Code", reasonCodeText},
+	"2.5.29.24":               {"Invalidity Date", invalidityDateText},
+	"2.5.29.27":               {"X509v3 Delta CRL Indicator", integerText},
+	"2.5.29.30":               {"X509v3 Name Constraints", nameConstraintsText},
+	"2.5.29.31":               {"X509v3 CRL Distribution Points", crlDistributionPointsText},
+	"2.5.29.32":               {"X509v3 Certificate Policies", policiesText},
+	"2.5.29.35":               {"X509v3 Authority Key Identifier", authorityKeyIdentifierText},
+	"2.5.29.36":               {"X509v3 Policy Constraints", policyConstraintsText},
+	"2.5.29.37":               {"X509v3 Extended Key Usage", extKeyUsageText},
+	"2.5.29.54":               {"X509v3 Inhibit Any Policy", integerText},
+	"1.3.6.1.5.5.7.1.1":       {"Authority Information Access", authorityInfoAccessText},
+	"1.3.6.1.5.5.7.48.1.5":    {"OCSP No Check", nil},
+	"1.3.6.1.4.1.11129.2.4.2": {"CT Precertificate SCTs", nil},
+}
+
+// extensions writes each extension, its name and critical flag followed by its decoded value.
+// Unknown extensions, or those which fail to decode, are written as a hex dump of their value.
+func (tw *textWriter) extensions(indent int, exts []pkix.Extension) {
+	for _, ext := range exts {
+		dec, ok := extensionDecoders[ext.Id.String()]
+		name := dec.name
+		if !ok {
+			name = ext.Id.String()
+		}
+		if ext.Critical {
+			tw.line(indent, "%s: critical", name)
+		} else {
+			tw.line(indent, "%s: ", name)
+		}
+		var lines []string
+		var err error
+		if dec.decode != nil {
+			lines, err = dec.decode(ext.Value)
+		}
+		if dec.decode == nil || err != nil {
+			tw.hexDump(indent+1, ext.Value)
+			continue
+		}
+		for _, l := range lines {
+			tw.line(indent+1, "%s", l)
+		}
+	}
+}
+
+// hexDump writes the bytes with their offset, as hex and printable characters, 16 bytes to a line
+func (tw *textWriter) hexDump(indent int, b []byte) {
+	for i := 0; i < len(b); i += 16 {
+		end := min(i+16, len(b))
+		hx := hexString(b[i:end], " ")
+		printable := []byte(string(b[i:end]))
+		for j, c := range printable {
+			if c < 0x20 || c > 0x7e {
+				printable[j] = '.'
+			}
+		}
+		tw.line(indent, "%04x - %-47s   %s", i, hx, printable)
+	}
+}
+
+func keyIdentifierText(value []byte) ([]string, error) {
+	var id []byte
+	if err := unmarshalAll(value, &id); err != nil {
+		return nil, err
+	}
+	return []string{strings.ToUpper(hexString(id, ":"))}, nil
+}
+
+var keyUsageTextNames = []string{
+	"Digital Signature", "Non Repudiation", "Key Encipherment", "Data Encipherment", "Key Agreement",
+	"Certificate Sign", "CRL Sign", "Encipher Only", "Decipher Only",
+}
+
+func keyUsageText(value []byte) ([]string, error) {
+	var bits asn1.BitString
+	if err := unmarshalAll(value, &bits); err != nil {
+		return nil, err
+	}
+	var names []string
+	for i, name := range keyUsageTextNames {
+		if bits.At(i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return []string{strings.Join(names, ", ")}, nil
+}
+
+var extKeyUsageTextNames = map[string]string{
+	"1.3.6.1.5.5.7.3.1":      "TLS Web Server Authentication",
+	"1.3.6.1.5.5.7.3.2":      "TLS Web Client Authentication",
+	"1.3.6.1.5.5.7.3.3":      "Code Signing",
+	"1.3.6.1.5.5.7.3.4":      "E-mail Protection",
+	"1.3.6.1.5.5.7.3.5":      "IPSec End System",
+	"1.3.6.1.5.5.7.3.6":      "IPSec Tunnel",
+	"1.3.6.1.5.5.7.3.7":      "IPSec User",
+	"1.3.6.1.5.5.7.3.8":      "Time Stamping",
+	"1.3.6.1.5.5.7.3.9":      "OCSP Signing",
+	"2.5.29.37.0":            "Any Extended Key Usage",
+	"1.3.6.1.4.1.311.10.3.3": "Microsoft Server Gated Crypto",
+	"1.3.6.1.4.1.311.20.2.2": "Microsoft Smartcard Login",
+	"2.16.840.1.113730.4.1":  "Netscape Server Gated Crypto",
+}
+
+func extKeyUsageText(value []byte) ([]string, error) {
+	var oids []asn1.ObjectIdentifier
+	if err := unmarshalAll(value, &oids); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(oids))
+	for i, oid := range oids {
+		if name, ok := extKeyUsageTextNames[oid.String()]; ok {
+			names[i] = name
+		} else {
+			names[i] = oid.String()
+		}
+	}
+	return []string{strings.Join(names, ", ")}, nil
+}
+
+func basicConstraintsText(value []byte) ([]string, error) {
+	var bc struct {
+		IsCA       bool `asn1:"optional"`
+		MaxPathLen int  `asn1:"optional,default:-1"`
+	}
+	if err := unmarshalAll(value, &bc); err != nil {
+		return nil, err
+	}
+	s := "CA:FALSE"
+	if bc.IsCA {
+		s = "CA:TRUE"
+	}
+	if bc.MaxPathLen >= 0 {
+		s = fmt.Sprintf("%s, pathlen:%d", s, bc.MaxPathLen)
+	}
+	return []string{s}, nil
+}
+
+func integerText(value []byte) ([]string, error) {
+	var n *big.Int
+	if err := unmarshalAll(value, &n); err != nil {
+		return nil, err
+	}
+	return []string{n.String()}, nil
+}
+
+var reasonCodeNames = []string{
+	"Unspecified", "Key Compromise", "CA Compromise", "Affiliation Changed", "Superseded",
+	"Cessation Of Operation", "Certificate Hold", "", "Remove From CRL", "Privilege Withdrawn", "AA Compromise",
+}
+
+func reasonCodeText(value []byte) ([]string, error) {
+	var reason asn1.Enumerated
+	if err := unmarshalAll(value, &reason); err != nil {
+		return nil, err
+	}
+	if int(reason) < 0 || int(reason) >= len(reasonCodeNames) || reasonCodeNames[reason] == "" {
+		return []string{fmt.Sprintf("Unknown reason %d", reason)}, nil
+	}
+	return []string{reasonCodeNames[reason]}, nil
+}
+
+func invalidityDateText(value []byte) ([]string, error) {
+	var t time.Time
+	if err := unmarshalAll(value, &t); err != nil {
+		return nil, err
+	}
+	return []string{timeText(t)}, nil
+}
+
+func authorityKeyIdentifierText(value []byte) ([]string, error) {
+	var aki struct {
+		KeyID  []byte        `asn1:"optional,tag:0"`
+		Issuer asn1.RawValue `asn1:"optional,tag:1"`
+		Serial *big.Int      `asn1:"optional,tag:2"`
+	}
+	if err := unmarshalAll(value, &aki); err != nil {
+		return nil, err
+	}
+	var lines []string
+	if len(aki.KeyID) > 0 {
+		lines = append(lines, strings.ToUpper(hexString(aki.KeyID, ":")))
+	}
+	if len(aki.Issuer.Bytes) > 0 {
+		names, err := generalNames(aki.Issuer.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, names...)
+	}
+	if aki.Serial != nil {
+		lines = append(lines, "serial:"+strings.ToUpper(hexString(aki.Serial.Bytes(), ":")))
+	}
+	return lines, nil
+}
+
+func generalNamesText(value []byte) ([]string, error) {
+	var seq asn1.RawValue
+	if err := unmarshalAll(value, &seq); err != nil {
+		return nil, err
+	}
+	names, err := generalNames(seq.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return []string{strings.Join(names, ", ")}, nil
+}
+
+// generalNames formats each of the concatenated GeneralNames
+func generalNames(data []byte) ([]string, error) {
+	var names []string
+	for len(data) > 0 {
+		var rv asn1.RawValue
+		rest, err := asn1.Unmarshal(data, &rv)
+		if err != nil {
+			return nil, err
+		}
+		name, err := generalName(rv)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		data = rest
+	}
+	return names, nil
+}
+
+func generalName(rv asn1.RawValue) (string, error) {
+	if rv.Class != asn1.ClassContextSpecific {
+		return "", errors.New("invalid general name")
+	}
+	switch rv.Tag {
+	case 0:
+		return "othername:<unsupported>", nil
+	case 1:
+		return "email:" + string(rv.Bytes), nil
+	case 2:
+		return "DNS:" + string(rv.Bytes), nil
+	case 3:
+		return "X400Name:<unsupported>", nil
+	case 4:
+		return "DirName:" + slashNameText(rv.Bytes), nil
+	case 5:
+		return "EdiPartyName:<unsupported>", nil
+	case 6:
+		return "URI:" + string(rv.Bytes), nil
+	case 7:
+		if len(rv.Bytes) != net.IPv4len && len(rv.Bytes) != net.IPv6len {
+			return "IP Address:<invalid>", nil
+		}
+		return "IP Address:" + ipText(rv.Bytes), nil
+	case 8:
+		var oid asn1.ObjectIdentifier
+		der, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagOID, Bytes: rv.Bytes})
+		if err != nil {
+			return "", err
+		}
+		if _, err := asn1.Unmarshal(der, &oid); err != nil {
+			return "", err
+		}
+		return "Registered ID:" + oid.String(), nil
+	default:
+		return "", fmt.Errorf("unknown general name tag %d", rv.Tag)
+	}
+}
+
+func nameConstraintsText(value []byte) ([]string, error) {
+	var nc struct {
+		Permitted asn1.RawValue `asn1:"optional,tag:0"`
+		Excluded  asn1.RawValue `asn1:"optional,tag:1"`
+	}
+	if err := unmarshalAll(value, &nc); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, subtrees := range []struct {
+		name string
+		data []byte
+	}{{"Permitted", nc.Permitted.Bytes}, {"Excluded", nc.Excluded.Bytes}} {
+		if len(subtrees.data) == 0 {
+			continue
+		}
+		lines = append(lines, subtrees.name+":")
+		for data := subtrees.data; len(data) > 0; {
+			var subtree struct {
+				Base asn1.RawValue
+				Min  int `asn1:"optional,tag:0"`
+				Max  int `asn1:"optional,tag:1"`
+			}
+			rest, err := asn1.Unmarshal(data, &subtree)
+			if err != nil {
+				return nil, err
+			}
+			name, err := constraintName(subtree.Base)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, "  "+name)
+			data = rest
+		}
+	}
+	return lines, nil
+}
+
+// constraintName formats a general name of a name constraint, where ip addresses are an address and mask
+func constraintName(rv asn1.RawValue) (string, error) {
+	if rv.Class != asn1.ClassContextSpecific || rv.Tag != 7 {
+		return generalName(rv)
+	}
+	l := len(rv.Bytes) / 2
+	if l != net.IPv4len && l != net.IPv6len {
+		return "IP:<invalid>", nil
+	}
+	return fmt.Sprintf("IP:%s/%s", ipText(rv.Bytes[:l]), ipText(rv.Bytes[l:])), nil
+}
+
+// ipText formats IPv6 addresses in full, without compressing zeros, as openssl does
+func ipText(ip []byte) string {
+	if len(ip) != net.IPv6len {
+		return net.IP(ip).String()
+	}
+	groups := make([]string, 8)
+	for i := range groups {
+		groups[i] = fmt.Sprintf("%x", uint16(ip[2*i])<<8|uint16(ip[2*i+1]))
+	}
+	return strings.Join(groups, ":")
+}
+
+func crlDistributionPointsText(value []byte) ([]string, error) {
+	var points []struct {
+		DistributionPoint asn1.RawValue  `asn1:"optional,tag:0"`
+		Reasons           asn1.BitString `asn1:"optional,tag:1"`
+		CRLIssuer         asn1.RawValue  `asn1:"optional,tag:2"`
+	}
+	if err := unmarshalAll(value, &points); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, dp := range points {
+		if len(dp.DistributionPoint.Bytes) > 0 {
+			var dpName asn1.RawValue
+			if _, err := asn1.Unmarshal(dp.DistributionPoint.Bytes, &dpName); err != nil {
+				return nil, err
+			}
+			switch dpName.Tag {
+			case 0:
+				names, err := generalNames(dpName.Bytes)
+				if err != nil {
+					return nil, err
+				}
+				lines = append(lines, "Full Name:")
+				for _, n := range names {
+					lines = append(lines, "  "+n)
+				}
+			case 1:
+				lines = append(lines, "Relative Name:")
+				lines = append(lines, "  <unsupported>")
+			}
+		}
+		if len(dp.CRLIssuer.Bytes) > 0 {
+			names, err := generalNames(dp.CRLIssuer.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, "CRL Issuer:")
+			for _, n := range names {
+				lines = append(lines, "  "+n)
+			}
+		}
+	}
+	return lines, nil
+}
+
+var (
+	oidCPS        = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 2, 1}
+	oidUserNotice = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 2, 2}
+)
+
+func policiesText(value []byte) ([]string, error) {
+	var policies []struct {
+		Policy     asn1.ObjectIdentifier
+		Qualifiers []struct {
+			ID        asn1.ObjectIdentifier
+			Qualifier asn1.RawValue
+		} `asn1:"optional"`
+	}
+	if err := unmarshalAll(value, &policies); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, p := range policies {
+		if p.Policy.String() == "2.5.29.32.0" {
+			lines = append(lines, "Policy: X509v3 Any Policy")
+		} else {
+			lines = append(lines, "Policy: "+p.Policy.String())
+		}
+		for _, q := range p.Qualifiers {
+			switch {
+			case q.ID.Equal(oidCPS):
+				lines = append(lines, "  CPS: "+string(q.Qualifier.Bytes))
+			case q.ID.Equal(oidUserNotice):
+				var notice struct {
+					NoticeRef    asn1.RawValue `asn1:"optional"`
+					ExplicitText asn1.RawValue `asn1:"optional"`
+				}
+				if _, err := asn1.Unmarshal(q.Qualifier.FullBytes, &notice); err != nil {
+					return nil, err
+				}
+				lines = append(lines, "  User Notice:")
+				// a single element is the explicit text, as the notice ref is a sequence
+				text := notice.ExplicitText
+				if len(text.FullBytes) == 0 && notice.NoticeRef.Tag != asn1.TagSequence {
+					text = notice.NoticeRef
+				}
+				if len(text.Bytes) > 0 {
+					lines = append(lines, "    Explicit Text: "+displayText(text))
+				}
+			default:
+				lines = append(lines, "  Unknown Qualifier: "+q.ID.String())
+			}
+		}
+	}
+	return lines, nil
+}
+
+// displayText decodes a DisplayText string, decoding BMPStrings from UTF-16
+func displayText(rv asn1.RawValue) string {
+	if rv.Tag != asn1.TagBMPString {
+		return string(rv.Bytes)
+	}
+	runes := make([]rune, 0, len(rv.Bytes)/2)
+	for i := 0; i+1 < len(rv.Bytes); i += 2 {
+		runes = append(runes, rune(rv.Bytes[i])<<8|rune(rv.Bytes[i+1]))
+	}
+	return string(runes)
+}
+
+func policyConstraintsText(value []byte) ([]string, error) {
+	var pc struct {
+		RequireExplicitPolicy int `asn1:"optional,tag:0,default:-1"`
+		InhibitPolicyMapping  int `asn1:"optional,tag:1,default:-1"`
+	}
+	if err := unmarshalAll(value, &pc); err != nil {
+		return nil, err
+	}
+	var lines []string
+	if pc.RequireExplicitPolicy >= 0 {
+		lines = append(lines, fmt.Sprintf("Require Explicit Policy:%d", pc.RequireExplicitPolicy))
+	}
+	if pc.InhibitPolicyMapping >= 0 {
+		lines = append(lines, fmt.Sprintf("Inhibit Policy Mapping:%d", pc.InhibitPolicyMapping))
+	}
+	return lines, nil
+}
+
+var accessMethodNames = map[string]string{
+	"1.3.6.1.5.5.7.48.1": "OCSP",
+	"1.3.6.1.5.5.7.48.2": "CA Issuers",
+}
+
+func authorityInfoAccessText(value []byte) ([]string, error) {
+	var descs []struct {
+		Method   asn1.ObjectIdentifier
+		Location asn1.RawValue
+	}
+	if err := unmarshalAll(value, &descs); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, d := range descs {
+		method, ok := accessMethodNames[d.Method.String()]
+		if !ok {
+			method = d.Method.String()
+		}
+		name, err := generalName(d.Location)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, fmt.Sprintf("%s - %s", method, name))
+	}
+	return lines, nil
+}
+
+// unmarshalAll unmarshals the der into v, failing when trailing data remains
+func unmarshalAll(der []byte, v interface{}) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("trailing data")
+	}
+	return nil
+}
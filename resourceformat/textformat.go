@@ -0,0 +1,359 @@
+package resourceformat
+
+import (
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// TextFormat outputs certificates, requests, CRLs and keys as human readable text, in the style of 'openssl x509 -text'.
+// Known extensions are decoded, unknown extensions are output as a hex dump.
+// Private key values are not output, only the key size and its public key.
+type TextFormat struct{}
+
+func (t TextFormat) Format(out io.Writer, p *model.PemFile) error {
+	tw := &textWriter{out: out}
+	for i, blk := range p.Blocks {
+		if i > 0 {
+			tw.line(0, "")
+		}
+		if strings.Contains(blk.Type, "ENCRYPTED") || blk.Headers["Proc-Type"] != "" {
+			tw.line(0, "Private-Key: (encrypted)")
+			continue
+		}
+		resz := (&model.PemFile{Path: p.Path, Blocks: []*pem.Block{blk}}).Resources()
+		if len(resz) == 0 {
+			continue
+		}
+		switch v := resz[0].(type) {
+		case *model.Certificate:
+			tw.certificate((*x509.Certificate)(v))
+		case *model.CertificateRequest:
+			tw.request((*x509.CertificateRequest)(v))
+		case *model.RevocationList:
+			tw.revocationList((*x509.RevocationList)(v))
+		case *model.PrivateKey:
+			tw.privateKey(v)
+		case *model.PublicKey:
+			tw.publicKey(0, v.Public())
+		default:
+			tw.line(0, "%s: %s", v.ResourceType(), v.String())
+		}
+	}
+	return tw.err
+}
+
+// textWriter writes indented lines, four spaces for each level, retaining the first error
+type textWriter struct {
+	out io.Writer
+	err error
+}
+
+func (tw *textWriter) line(indent int, format string, args ...interface{}) {
+	if tw.err != nil {
+		return
+	}
+	_, tw.err = fmt.Fprintf(tw.out, "%s%s\n", strings.Repeat("    ", indent), fmt.Sprintf(format, args...))
+}
+
+// hex writes the bytes as colon separated hex, perLine bytes on each line
+func (tw *textWriter) hex(indent int, b []byte, perLine int) {
+	for i := 0; i < len(b); i += perLine {
+		end := min(i+perLine, len(b))
+		s := hexString(b[i:end], ":")
+		if end < len(b) {
+			s += ":"
+		}
+		tw.line(indent, "%s", s)
+	}
+}
+
+func (tw *textWriter) certificate(c *x509.Certificate) {
+	tw.line(0, "Certificate:")
+	tw.line(1, "Data:")
+	tw.line(2, "Version: %d (0x%x)", c.Version, c.Version-1)
+	tw.serialNumber(2, c.SerialNumber)
+	tw.line(2, "Signature Algorithm: %s", signatureAlgorithmName(c.SignatureAlgorithm))
+	tw.line(2, "Issuer: %s", nameText(c.RawIssuer))
+	tw.line(2, "Validity")
+	tw.line(3, "Not Before: %s", timeText(c.NotBefore))
+	tw.line(3, "Not After : %s", timeText(c.NotAfter))
+	tw.line(2, "Subject: %s", nameText(c.RawSubject))
+	tw.line(2, "Subject Public Key Info:")
+	tw.publicKey(3, c.PublicKey)
+	if len(c.Extensions) > 0 {
+		tw.line(2, "X509v3 extensions:")
+		tw.extensions(3, c.Extensions)
+	}
+	tw.signature(c.SignatureAlgorithm, c.Signature)
+}
+
+func (tw *textWriter) request(r *x509.CertificateRequest) {
+	tw.line(0, "Certificate Request:")
+	tw.line(1, "Data:")
+	tw.line(2, "Version: %d (0x%x)", r.Version+1, r.Version)
+	tw.line(2, "Subject: %s", nameText(r.RawSubject))
+	tw.line(2, "Subject Public Key Info:")
+	tw.publicKey(3, r.PublicKey)
+	tw.line(2, "Attributes:")
+	if len(r.Extensions) == 0 {
+		tw.line(3, "(none)")
+	} else {
+		tw.line(3, "Requested Extensions:")
+		tw.extensions(4, r.Extensions)
+	}
+	tw.signature(r.SignatureAlgorithm, r.Signature)
+}
+
+func (tw *textWriter) revocationList(crl *x509.RevocationList) {
+	tw.line(0, "Certificate Revocation List (CRL):")
+	if len(crl.Extensions) > 0 {
+		tw.line(2, "Version 2 (0x1)")
+	} else {
+		tw.line(2, "Version 1 (0x0)")
+	}
+	tw.line(2, "Signature Algorithm: %s", signatureAlgorithmName(crl.SignatureAlgorithm))
+	tw.line(2, "Issuer: %s", nameText(crl.RawIssuer))
+	tw.line(2, "Last Update: %s", timeText(crl.ThisUpdate))
+	if crl.NextUpdate.IsZero() {
+		tw.line(2, "Next Update: NONE")
+	} else {
+		tw.line(2, "Next Update: %s", timeText(crl.NextUpdate))
+	}
+	if len(crl.Extensions) > 0 {
+		tw.line(2, "CRL extensions:")
+		tw.extensions(3, crl.Extensions)
+	}
+	if len(crl.RevokedCertificateEntries) == 0 {
+		tw.line(0, "No Revoked Certificates.")
+	} else {
+		tw.line(0, "Revoked Certificates:")
+	}
+	for _, e := range crl.RevokedCertificateEntries {
+		tw.line(1, "Serial Number: %s", strings.ToUpper(hexString(e.SerialNumber.Bytes(), "")))
+		tw.line(2, "Revocation Date: %s", timeText(e.RevocationTime))
+		if len(e.Extensions) > 0 {
+			tw.line(2, "CRL entry extensions:")
+			tw.extensions(3, e.Extensions)
+		}
+	}
+	tw.signature(crl.SignatureAlgorithm, crl.Signature)
+}
+
+func (tw *textWriter) privateKey(k *model.PrivateKey) {
+	signer, ok := k.Private().(crypto.Signer)
+	if !ok {
+		tw.line(0, "Private-Key: %s", k.PublicKeyAlgorithm())
+		return
+	}
+	puk := signer.Public()
+	if bits := publicKeyBits(puk); bits > 0 {
+		tw.line(0, "Private-Key: (%d bit)", bits)
+	} else {
+		tw.line(0, "Private-Key:")
+	}
+	tw.publicKey(0, puk)
+}
+
+func (tw *textWriter) serialNumber(indent int, n *big.Int) {
+	if n.Sign() >= 0 && n.IsInt64() {
+		tw.line(indent, "Serial Number: %d (0x%x)", n, n)
+		return
+	}
+	tw.line(indent, "Serial Number:")
+	s := hexString(n.Bytes(), ":")
+	if n.Sign() < 0 {
+		s = "(Negative)" + s
+	}
+	tw.line(indent+1, "%s", s)
+}
+
+func (tw *textWriter) signature(sa x509.SignatureAlgorithm, sig []byte) {
+	tw.line(1, "Signature Algorithm: %s", signatureAlgorithmName(sa))
+	tw.line(1, "Signature Value:")
+	tw.hex(2, sig, 18)
+}
+
+func (tw *textWriter) publicKey(indent int, puk interface{}) {
+	switch k := puk.(type) {
+	case *rsa.PublicKey:
+		tw.line(indent, "Public Key Algorithm: rsaEncryption")
+		tw.line(indent+1, "Public-Key: (%d bit)", k.N.BitLen())
+		tw.line(indent+1, "Modulus:")
+		tw.hex(indent+2, unsignedBytes(k.N), 15)
+		tw.line(indent+1, "Exponent: %d (0x%x)", k.E, k.E)
+	case *ecdsa.PublicKey:
+		tw.line(indent, "Public Key Algorithm: id-ecPublicKey")
+		tw.line(indent+1, "Public-Key: (%d bit)", k.Curve.Params().BitSize)
+		tw.line(indent+1, "pub:")
+		if ek, err := k.ECDH(); err == nil {
+			tw.hex(indent+2, ek.Bytes(), 15)
+		}
+		oid, nist := curveNames(k.Curve)
+		tw.line(indent+1, "ASN1 OID: %s", oid)
+		if nist != "" {
+			tw.line(indent+1, "NIST CURVE: %s", nist)
+		}
+	case ed25519.PublicKey:
+		tw.line(indent, "Public Key Algorithm: ED25519")
+		tw.line(indent+1, "ED25519 Public-Key:")
+		tw.line(indent+1, "pub:")
+		tw.hex(indent+2, k, 15)
+	case *dsa.PublicKey:
+		tw.line(indent, "Public Key Algorithm: dsaEncryption")
+		tw.line(indent+1, "Public-Key: (%d bit)", k.P.BitLen())
+		for _, v := range []struct {
+			name string
+			n    *big.Int
+		}{{"pub", k.Y}, {"P", k.P}, {"Q", k.Q}, {"G", k.G}} {
+			tw.line(indent+1, "%s:", v.name)
+			tw.hex(indent+2, unsignedBytes(v.n), 15)
+		}
+	default:
+		tw.line(indent, "Public Key Algorithm: %T", puk)
+	}
+}
+
+func publicKeyBits(puk interface{}) int {
+	switch k := puk.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	case *dsa.PublicKey:
+		return k.P.BitLen()
+	default:
+		return 0
+	}
+}
+
+// unsignedBytes gives the big endian bytes of a positive integer, with a leading zero when its high bit is set
+func unsignedBytes(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+func curveNames(c elliptic.Curve) (oid, nist string) {
+	switch c {
+	case elliptic.P224():
+		return "secp224r1", "P-224"
+	case elliptic.P256():
+		return "prime256v1", "P-256"
+	case elliptic.P384():
+		return "secp384r1", "P-384"
+	case elliptic.P521():
+		return "secp521r1", "P-521"
+	default:
+		return c.Params().Name, ""
+	}
+}
+
+func hexString(b []byte, sep string) string {
+	s := make([]string, len(b))
+	for i, v := range b {
+		s[i] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(s, sep)
+}
+
+// timeText formats the time in GMT, as openssl does
+func timeText(t time.Time) string {
+	return t.UTC().Format("Jan _2 15:04:05 2006") + " GMT"
+}
+
+var signatureAlgorithmNames = map[x509.SignatureAlgorithm]string{
+	x509.MD5WithRSA:       "md5WithRSAEncryption",
+	x509.SHA1WithRSA:      "sha1WithRSAEncryption",
+	x509.SHA256WithRSA:    "sha256WithRSAEncryption",
+	x509.SHA384WithRSA:    "sha384WithRSAEncryption",
+	x509.SHA512WithRSA:    "sha512WithRSAEncryption",
+	x509.SHA256WithRSAPSS: "rsassaPss",
+	x509.SHA384WithRSAPSS: "rsassaPss",
+	x509.SHA512WithRSAPSS: "rsassaPss",
+	x509.DSAWithSHA1:      "dsaWithSHA1",
+	x509.DSAWithSHA256:    "dsa_with_SHA256",
+	x509.ECDSAWithSHA1:    "ecdsa-with-SHA1",
+	x509.ECDSAWithSHA256:  "ecdsa-with-SHA256",
+	x509.ECDSAWithSHA384:  "ecdsa-with-SHA384",
+	x509.ECDSAWithSHA512:  "ecdsa-with-SHA512",
+	x509.PureEd25519:      "ED25519",
+}
+
+func signatureAlgorithmName(sa x509.SignatureAlgorithm) string {
+	if name, ok := signatureAlgorithmNames[sa]; ok {
+		return name
+	}
+	return sa.String()
+}
+
+var attributeNames = map[string]string{
+	"2.5.4.3":                    "CN",
+	"2.5.4.4":                    "SN",
+	"2.5.4.5":                    "serialNumber",
+	"2.5.4.6":                    "C",
+	"2.5.4.7":                    "L",
+	"2.5.4.8":                    "ST",
+	"2.5.4.9":                    "street",
+	"2.5.4.10":                   "O",
+	"2.5.4.11":                   "OU",
+	"2.5.4.12":                   "title",
+	"2.5.4.17":                   "postalCode",
+	"2.5.4.42":                   "GN",
+	"1.2.840.113549.1.9.1":       "emailAddress",
+	"0.9.2342.19200300.100.1.1":  "UID",
+	"0.9.2342.19200300.100.1.25": "DC",
+}
+
+// nameText formats the der of a name in the order it is encoded, as 'C = GB, O = Acme, CN = Name'
+func nameText(der []byte) string {
+	parts, err := nameParts(der, " = ")
+	if err != nil {
+		return fmt.Sprintf("<invalid name %v>", err)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// slashNameText formats the der of a name as '/C=GB/O=Acme/CN=Name', as openssl formats directory names
+func slashNameText(der []byte) string {
+	parts, err := nameParts(der, "=")
+	if err != nil {
+		return fmt.Sprintf("<invalid name %v>", err)
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+func nameParts(der []byte, sep string) ([]string, error) {
+	var rdns pkix.RDNSequence
+	if _, err := asn1.Unmarshal(der, &rdns); err != nil {
+		return nil, err
+	}
+	var parts []string
+	for _, rdn := range rdns {
+		for _, atv := range rdn {
+			name, ok := attributeNames[atv.Type.String()]
+			if !ok {
+				name = atv.Type.String()
+			}
+			parts = append(parts, fmt.Sprintf("%s%s%v", name, sep, atv.Value))
+		}
+	}
+	return parts, nil
+}
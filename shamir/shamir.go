@@ -0,0 +1,128 @@
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Split splits the secret into n shares, any threshold of which can be combined to recreate the secret.
+// Each share is the x coordinate of the share, as its first byte, followed by the y value of each secret byte.
+// Shares are computed over GF(256), so n must be no more than 255.
+func Split(secret []byte, n, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret is empty")
+	}
+	if threshold < 2 {
+		return nil, errors.New("threshold must be at least 2")
+	}
+	if n < threshold {
+		return nil, fmt.Errorf("share count %d must be at least the threshold %d", n, threshold)
+	}
+	if n > 255 {
+		return nil, errors.New("share count must be no more than 255")
+	}
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+	coefficients := make([]byte, threshold)
+	for i, b := range secret {
+		// random polynomial of degree threshold-1, with the secret byte as its constant
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		coefficients[0] = b
+		for _, share := range shares {
+			share[i+1] = evaluate(coefficients, share[0])
+		}
+	}
+	return shares, nil
+}
+
+// Combine recreates the secret from the given shares.
+// The number of shares must meet the threshold used to split the secret, otherwise the result is incorrect.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least two shares are required")
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errors.New("invalid share")
+	}
+	xs := make([]byte, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("shares are of different lengths")
+		}
+		if share[0] == 0 {
+			return nil, errors.New("invalid share with x of zero")
+		}
+		for _, x := range xs[:i] {
+			if x == share[0] {
+				return nil, fmt.Errorf("share %d is given more than once", x)
+			}
+		}
+		xs[i] = share[0]
+	}
+	secret := make([]byte, size-1)
+	for i := range secret {
+		// lagrange interpolation at x = 0
+		var s byte
+		for j, share := range shares {
+			num, den := byte(1), byte(1)
+			for k, x := range xs {
+				if k == j {
+					continue
+				}
+				num = mul(num, x)
+				den = mul(den, x^xs[j])
+			}
+			s ^= mul(share[i+1], div(num, den))
+		}
+		secret[i] = s
+	}
+	return secret, nil
+}
+
+// evaluate the polynomial at x, using horner's method
+func evaluate(coefficients []byte, x byte) byte {
+	var y byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coefficients[i]
+	}
+	return y
+}
+
+var expTable, logTable [256]byte
+
+func init() {
+	// generator 3 over the AES polynomial x^8 + x^4 + x^3 + x + 1
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		logTable[x] = byte(i)
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	expTable[255] = expTable[0]
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+255-int(logTable[b]))%255]
+}
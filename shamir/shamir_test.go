@@ -0,0 +1,37 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("a secret root key, split between operators")
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, set := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var sub [][]byte
+		for _, i := range set {
+			sub = append(sub, shares[i])
+		}
+		found, err := Combine(sub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(found, secret) {
+			t.Errorf("shares %v did not recreate the secret", set)
+		}
+	}
+	found, err := Combine(shares[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(found, secret) {
+		t.Errorf("expected shares below threshold to not recreate the secret")
+	}
+	if _, err := Split(secret, 2, 3); err == nil {
+		t.Errorf("expected threshold above share count to fail")
+	}
+}
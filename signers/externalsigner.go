@@ -0,0 +1,92 @@
+package signers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// ExternalSigner is a private key held by an external signer process, such as a hardware token or other tooling.
+// The signer is a command, run for each request, which speaks the json over stdio protocol.
+type ExternalSigner struct {
+	Name    string
+	Command string
+	public  crypto.PublicKey
+}
+
+func (s ExternalSigner) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Fingerprint of an external signer is the fingerprint of its public key, as the private key is never available.
+func (s ExternalSigner) Fingerprint() model.Fingerprint {
+	return model.NewPublicKey(s.public).Fingerprint()
+}
+
+func (s ExternalSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	req := &Request{
+		Op:     OpSign,
+		Digest: digest,
+		Hash:   uint(opts.HashFunc()),
+	}
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		req.PSS = true
+		req.PSSSaltLength = pss.SaltLength
+	}
+	resp, err := s.send(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Signature) == 0 {
+		return nil, fmt.Errorf("signer %s returned no signature", s.Name)
+	}
+	return resp.Signature, nil
+}
+
+func (s ExternalSigner) send(req *Request) (*Response, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("sh", "-c", s.Command)
+	cmd.Stdin = bytes.NewReader(append(data, '\n'))
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("signer %s failed  %v", s.Name, err)
+	}
+	line, _, _ := bytes.Cut(out, []byte("\n"))
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("signer %s returned an invalid response  %v", s.Name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("signer %s  %s", s.Name, resp.Error)
+	}
+	return &resp, nil
+}
+
+// NewExternalSigner creates a new signer for the given command, requesting its public key from it.
+func NewExternalSigner(name, command string) (*ExternalSigner, error) {
+	if command == "" {
+		return nil, errors.New("signer has no command")
+	}
+	s := &ExternalSigner{Name: name, Command: command}
+	resp, err := s.send(&Request{Op: OpPublicKey})
+	if err != nil {
+		return nil, err
+	}
+	puk := &model.PublicKey{}
+	if err := puk.UnmarshalBinary(resp.PublicKey); err != nil {
+		return nil, fmt.Errorf("signer %s returned an invalid public key  %v", name, err)
+	}
+	s.public = puk.Public()
+	return s, nil
+}
@@ -0,0 +1,85 @@
+package signers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const envTestSignerKey = "PP_TEST_SIGNER_KEY"
+
+// TestMain runs the test binary as a file signer when envTestSignerKey is set
+func TestMain(m *testing.M) {
+	if path := os.Getenv(envTestSignerKey); path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			prk := &model.PrivateKey{}
+			if err = prk.UnmarshalText(data); err == nil {
+				err = ServeFileSigner(prk, os.Stdin, os.Stdout)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestExternalSigner(t *testing.T) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prk := model.NewPrivateKey(k)
+	data, err := prk.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "signer.key")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	command := fmt.Sprintf("%s=%q %q", envTestSignerKey, path, os.Args[0])
+	s, err := NewExternalSigner("test", command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !model.NewPublicKey(s.Public()).Fingerprint().Equals(prk.Public().Fingerprint().String()) {
+		t.Fatalf("expected signer public key to match the signers key")
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "external"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, s.Public(), model.NewPrivateKey(s).Signer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Errorf("expected external signature to verify  %v", err)
+	}
+	if _, err := model.NewPrivateKey(s).MarshalText(); err == nil {
+		t.Errorf("expected external key to not marshal")
+	}
+}
@@ -0,0 +1,57 @@
+package signers
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"io"
+)
+
+// ServeFileSigner serves the external signer protocol using the given key, reading requests from in and
+// writing responses to out, until in is closed.
+// It is the reference implementation of an external signer.
+func ServeFileSigner(prk *model.PrivateKey, in io.Reader, out io.Writer) error {
+	scn := bufio.NewScanner(in)
+	scn.Buffer(nil, 1024*1024)
+	enc := json.NewEncoder(out)
+	for scn.Scan() {
+		var req Request
+		var resp *Response
+		if err := json.Unmarshal(scn.Bytes(), &req); err != nil {
+			resp = &Response{Error: fmt.Sprintf("invalid request  %v", err)}
+		} else {
+			resp = handleFileRequest(prk, &req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scn.Err()
+}
+
+func handleFileRequest(prk *model.PrivateKey, req *Request) *Response {
+	switch req.Op {
+	case OpPublicKey:
+		der, err := prk.Public().MarshalBinary()
+		if err != nil {
+			return &Response{Error: err.Error()}
+		}
+		return &Response{PublicKey: der}
+	case OpSign:
+		var opts crypto.SignerOpts = crypto.Hash(req.Hash)
+		if req.PSS {
+			opts = &rsa.PSSOptions{SaltLength: req.PSSSaltLength, Hash: crypto.Hash(req.Hash)}
+		}
+		sig, err := prk.Signer().Sign(rand.Reader, req.Digest, opts)
+		if err != nil {
+			return &Response{Error: err.Error()}
+		}
+		return &Response{Signature: sig}
+	default:
+		return &Response{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	}
+}
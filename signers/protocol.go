@@ -0,0 +1,31 @@
+package signers
+
+// Operations of the external signer protocol.
+// Each request is a single line of json written to the signers stdin, to which it responds with
+// a single line of json on its stdout.
+const (
+	// OpPublicKey requests the public key of the signer
+	OpPublicKey = "public-key"
+	// OpSign requests the signer signs the given digest
+	OpSign = "sign"
+)
+
+// Request is a request sent to an external signer
+type Request struct {
+	Op string `json:"op"`
+	// Digest is the digest to sign, hashed with Hash. Ed25519 keys sign the complete message, with a Hash of zero.
+	Digest []byte `json:"digest,omitempty"`
+	// Hash is the crypto.Hash used to hash the digest
+	Hash uint `json:"hash,omitempty"`
+	// PSS, when true, signs RSA digests with PSS, using the PSSSaltLength
+	PSS           bool `json:"pss,omitempty"`
+	PSSSaltLength int  `json:"pss-salt-length,omitempty"`
+}
+
+// Response is an external signers response to a request.
+// Errors are returned in Error, the PublicKey as PKIX der and the Signature as the raw signature bytes.
+type Response struct {
+	Error     string `json:"error,omitempty"`
+	PublicKey []byte `json:"public-key,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+}
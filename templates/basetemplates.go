@@ -12,12 +12,14 @@ import (
 // - certificate
 // - csr
 // - crl
+// - sshcert
 
 var baseTemplates = map[string]Template{
 	"privatekey":  &PrivateKeyTemplate{},
 	"certificate": &CertificateTemplate{},
 	"csr":         &CertificateRequestTemplate{},
 	"crl":         &RevocationListTemplate{},
+	"sshcert":     &SSHCertificateTemplate{},
 }
 
 var baseAliases = map[string]string{
@@ -32,6 +34,8 @@ var baseAliases = map[string]string{
 	"r":           "crl",
 	"revoke":      "crl",
 	"revokation":  "crl",
+	"ssh":         "sshcert",
+	"ssh-cert":    "sshcert",
 }
 
 // BaseTemplateNames lists all the names of the base templates.
@@ -62,8 +62,8 @@ func (c *CertificateRequestTemplate) ApplyTo(csr *model.CertificateRequest) {
 		csr.SignatureAlgorithm = x509.SignatureAlgorithm(c.SignatureAlgorithm)
 	}
 	if c.PublicKey != nil {
-		csr.PublicKey = c.PublicKey
-		csr.PublicKeyAlgorithm = x509.PublicKeyAlgorithm(model.NewPublicKey(c.PublicKey).PublicKeyAlgorithm())
+		csr.PublicKey = c.PublicKey.Public()
+		csr.PublicKeyAlgorithm = x509.PublicKeyAlgorithm(c.PublicKey.PublicKeyAlgorithm())
 	}
 	if x509.PublicKeyAlgorithm(c.PublicKeyAlgorithm) != x509.UnknownPublicKeyAlgorithm {
 		csr.PublicKeyAlgorithm = x509.PublicKeyAlgorithm(c.PublicKeyAlgorithm)
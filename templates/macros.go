@@ -0,0 +1,111 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/eurozulu/pempal/model"
+	"gopkg.in/yaml.v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const noMacroValue = "<no value>"
+
+// macroAction matches the contents of a single macro, between {{ and }}
+var macroAction = regexp.MustCompile(`{{(.*?)}}`)
+
+// macroProperty matches a dot notation property name, such as .subject.common-name
+var macroProperty = regexp.MustCompile(`(^|[\s({])\.([A-Za-z][\w-]*(?:\.[A-Za-z][\w-]*)*)`)
+
+// nameProperties are the template properties holding distinguished names.
+// Macros may refer to their fields, e.g. .subject.common-name
+var nameProperties = []string{"subject", "issuer"}
+
+// ExpandMacros expands the macros in the given text, using the properties of the given template.
+// Macros are enclosed in double curly brackets and refer to properties using dot notation: {{ .subject.common-name }}
+// The given values are added to the template properties, replacing any properties of the same name.
+func ExpandMacros(text string, t Template, values map[string]interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	props, err := macroProperties(t)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range values {
+		props[k] = v
+	}
+	tmp, err := template.New("macro").Parse(indexProperties(text))
+	if err != nil {
+		return "", fmt.Errorf("invalid macro in %q  %v", text, err)
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := tmp.Execute(buf, props); err != nil {
+		return "", fmt.Errorf("failed to expand macro in %q  %v", text, err)
+	}
+	s := buf.String()
+	if strings.Contains(s, noMacroValue) {
+		return "", fmt.Errorf("macro in %q refers to a property with no value", text)
+	}
+	return s, nil
+}
+
+// indexProperties replaces the dot notation properties in macros with index functions, as property names
+// may contain dashes, which are not valid in template field names.
+func indexProperties(text string) string {
+	return macroAction.ReplaceAllStringFunc(text, func(action string) string {
+		return macroProperty.ReplaceAllStringFunc(action, func(prop string) string {
+			m := macroProperty.FindStringSubmatch(prop)
+			names := strings.Split(m[2], ".")
+			for i, n := range names {
+				names[i] = strconv.Quote(n)
+			}
+			return fmt.Sprintf("%s(index . %s)", m[1], strings.Join(names, " "))
+		})
+	})
+}
+
+func macroProperties(t Template) (map[string]interface{}, error) {
+	props := map[string]interface{}{}
+	if t == nil {
+		return props, nil
+	}
+	data := []byte(t.String())
+	if tf, ok := t.(*model.TemplateFile); ok {
+		data = tf.CleanData()
+	}
+	if err := yaml.Unmarshal(data, &props); err != nil {
+		return nil, err
+	}
+	for _, name := range nameProperties {
+		s, ok := props[name].(string)
+		if !ok || s == "" {
+			continue
+		}
+		dn, err := model.ParseDistinguishedName(s)
+		if err != nil {
+			continue
+		}
+		props[name] = nameMacroProperties(*dn)
+	}
+	return props, nil
+}
+
+func nameMacroProperties(dn model.DistinguishedName) map[string]interface{} {
+	return map[string]interface{}{
+		"common-name":         dn.CommonName,
+		"serial-number":       dn.SerialNumber,
+		"country":             strings.Join(dn.Country, ","),
+		"organisation":        strings.Join(dn.Organization, ","),
+		"organization":        strings.Join(dn.Organization, ","),
+		"organisational-unit": strings.Join(dn.OrganizationalUnit, ","),
+		"organizational-unit": strings.Join(dn.OrganizationalUnit, ","),
+		"locality":            strings.Join(dn.Locality, ","),
+		"province":            strings.Join(dn.Province, ","),
+		"street-address":      strings.Join(dn.StreetAddress, ","),
+		"postal-code":         strings.Join(dn.PostalCode, ","),
+		"name":                dn.String(),
+	}
+}
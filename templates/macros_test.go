@@ -0,0 +1,52 @@
+package templates
+
+import (
+	"github.com/eurozulu/pempal/model"
+	"testing"
+)
+
+func TestExpandMacros(t *testing.T) {
+	cert := &CertificateTemplate{
+		Subject: model.DistinguishedName{CommonName: "server.acme.com", Organization: []string{"Acme"}},
+		Issuer:  model.DistinguishedName{CommonName: "acme ca"},
+	}
+	file := &model.TemplateFile{Path: "server.yaml", Data: []byte("#extends ca\nsubject:\n  common-name: file.acme.com\nkey-id: file-id\n")}
+	values := map[string]interface{}{"fingerprint": "3f2a", "resource-type": "certificate"}
+	for _, tc := range []struct {
+		name   string
+		text   string
+		t      Template
+		expect string
+	}{
+		{"no macros", "certs/out.pem", cert, "certs/out.pem"},
+		{"dashed name", "certs/{{ .subject.common-name }}.pem", cert, "certs/server.acme.com.pem"},
+		{"issuer name", "{{.issuer.common-name}}/{{ .subject.organisation }}", cert, "acme ca/Acme"},
+		{"values", "{{ .resource-type }}/{{ .fingerprint }}", cert, "certificate/3f2a"},
+		{"function", "{{ printf \"%s-%s\" .subject.common-name .fingerprint }}", cert, "server.acme.com-3f2a"},
+		{"no template", "{{ .fingerprint }}.key", nil, "3f2a.key"},
+		{"template file", "{{ .subject.common-name }}-{{ .key-id }}", file, "file.acme.com-file-id"},
+	} {
+		s, err := ExpandMacros(tc.text, tc.t, values)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if s != tc.expect {
+			t.Errorf("%s: expected %q, found %q", tc.name, tc.expect, s)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		text string
+	}{
+		{"missing property", "{{ .subject.locality-name }}"},
+		{"missing template property", "{{ .serial-number }}"},
+		{"unclosed", "{{ .subject.common-name "},
+		{"unknown function", "{{ upper .fingerprint }}"},
+	} {
+		if s, err := ExpandMacros(tc.text, cert, values); err == nil {
+			t.Errorf("%s: expected error, found %q", tc.name, s)
+		}
+	}
+}
@@ -9,6 +9,7 @@ type PrivateKeyTemplate struct {
 	KeyAlgoritum  model.PublicKeyAlgorithm `yaml:"public-key-algorithm"`
 	RSAKeyLength  int                      `yaml:"key-length,omitempty"`
 	ECDSAKeyCurve model.EllipticCurve      `yaml:"key-curve,omitempty"`
+	IsEncrypted   bool                     `yaml:"is-encrypted,omitempty"`
 }
 
 func (t PrivateKeyTemplate) String() string {
@@ -24,5 +25,6 @@ func NewPrivateKeyTemplate(r *model.PrivateKey) *PrivateKeyTemplate {
 		KeyAlgoritum:  r.PublicKeyAlgorithm(),
 		RSAKeyLength:  r.RSAKeyLength(),
 		ECDSAKeyCurve: r.ECDSACurve(),
+		IsEncrypted:   r.IsEncrypted(),
 	}
 }
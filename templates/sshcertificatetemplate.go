@@ -0,0 +1,54 @@
+package templates
+
+import (
+	"github.com/eurozulu/pempal/model"
+	"gopkg.in/yaml.v2"
+)
+
+type SSHCertificateTemplate struct {
+	// CertType is either 'user' or 'host'
+	CertType model.SSHCertType `yaml:"cert-type"`
+	KeyId    string            `yaml:"key-id"`
+	// Serial is the serial number of the certificate.  When zero, a random serial is used.
+	Serial uint64 `yaml:"serial,omitempty"`
+	// Principals are the user names or host names the certificate is valid for.
+	Principals  model.SSHPrincipals `yaml:"principals"`
+	ValidAfter  model.TimeDTO       `yaml:"valid-after"`
+	ValidBefore model.TimeDTO       `yaml:"valid-before"`
+
+	CriticalOptions map[string]string `yaml:"critical-options,omitempty"`
+	Extensions      map[string]string `yaml:"extensions,omitempty"`
+
+	// PublicKey is the key being certified
+	PublicKey *model.PublicKey `yaml:"public-key"`
+	// CAKey is the fingerprint of the key, in the key path, which signs the certificate.
+	CAKey string `yaml:"ca-key"`
+}
+
+func (t SSHCertificateTemplate) String() string {
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func NewSSHCertificateTemplate(cert *model.SSHCertificate) *SSHCertificateTemplate {
+	t := &SSHCertificateTemplate{
+		CertType:        model.SSHCertType(cert.CertType),
+		KeyId:           cert.KeyId,
+		Serial:          cert.Serial,
+		Principals:      cert.ValidPrincipals,
+		ValidAfter:      model.TimeDTO(cert.ValidAfterTime()),
+		ValidBefore:     model.TimeDTO(cert.ValidBeforeTime()),
+		CriticalOptions: cert.CriticalOptions,
+		Extensions:      cert.Extensions,
+	}
+	if puk, err := cert.PublicKey(); err == nil {
+		t.PublicKey = puk
+	}
+	if ca, err := cert.CAKey(); err == nil {
+		t.CAKey = ca.Fingerprint().String()
+	}
+	return t
+}
@@ -77,6 +77,8 @@ func TemplateOfResource(res model.PemResource) (Template, error) {
 		return NewCertificateRequestTemplate(r), nil
 	case *model.RevocationList:
 		return NewRevocationListTemplate(r), nil
+	case *model.SSHCertificate:
+		return NewSSHCertificateTemplate(r), nil
 	default:
 		return nil, fmt.Errorf("failed to create template as unknown resource type: %T", r.ResourceType())
 	}